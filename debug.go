@@ -0,0 +1,247 @@
+package ginplus
+
+import (
+	"crypto/subtle"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"path"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultDebugPath = "/debug"
+
+type (
+	// Debug 运行时诊断配置
+	Debug struct {
+		// Path 路由前缀, 默认为/debug
+		Path string
+		// AllowIPs 允许访问的IP或CIDR, 按连接的对端地址匹配, 与Token满足其一即可访问, 都为空时拒绝所有访问;
+		// 本机地址也需要显式添加, 例如127.0.0.1和::1
+		AllowIPs []string
+		// Token 访问令牌, 只通过Authorization: Bearer <token>传递, 避免令牌出现在访问日志和代理日志中
+		Token string
+	}
+
+	// debugBuildInfo 构建信息
+	debugBuildInfo struct {
+		GoVersion string            `json:"go_version"`
+		GOOS      string            `json:"goos"`
+		GOARCH    string            `json:"goarch"`
+		Path      string            `json:"path,omitempty"`
+		Version   string            `json:"version,omitempty"`
+		Settings  map[string]string `json:"settings,omitempty"`
+		Deps      map[string]string `json:"deps,omitempty"`
+	}
+
+	// debugEngineConfig 生效的引擎配置, 不包含账号和令牌等敏感信息
+	debugEngineConfig struct {
+		Addr             string            `json:"addr"`
+		BasePath         string            `json:"base_path"`
+		GenApiEnable     bool              `json:"gen_api_enable"`
+		TLS              bool              `json:"tls"`
+		MTLS             bool              `json:"mtls"`
+		H2C              bool              `json:"h2c"`
+		UnixSockets      []string          `json:"unix_sockets,omitempty"`
+		SystemdListeners bool              `json:"systemd_listeners"`
+		AdminAddr        string            `json:"admin_addr,omitempty"`
+		Upgrader         bool              `json:"upgrader"`
+		MetricsPath      string            `json:"metrics_path,omitempty"`
+		MetricsAddr      string            `json:"metrics_addr,omitempty"`
+		Ping             bool              `json:"ping"`
+		HealthPaths      []string          `json:"health_paths,omitempty"`
+		Graphql          bool              `json:"graphql"`
+		GraphqlPath      string            `json:"graphql_path,omitempty"`
+		ShutdownTimeout  string            `json:"shutdown_timeout"`
+		PreStopDelay     string            `json:"pre_stop_delay"`
+		Middlewares      int               `json:"middlewares"`
+		Controllers      int               `json:"controllers"`
+		Routes           []debugRouteInfo  `json:"routes"`
+		AdminRoutes      []debugRouteInfo  `json:"admin_routes,omitempty"`
+		Env              map[string]string `json:"env,omitempty"`
+	}
+
+	// debugRouteInfo 路由信息
+	debugRouteInfo struct {
+		Method  string `json:"method"`
+		Path    string `json:"path"`
+		Handler string `json:"handler"`
+	}
+)
+
+// guard 访问控制, IP在白名单中或令牌正确时放行, 都未设置时拒绝所有访问,
+// IP取连接的对端地址而不是ClientIP, 避免通过X-Forwarded-For伪造白名单或本机地址
+func (d *Debug) guard() gin.HandlerFunc {
+	nets := parseIPNets(d.AllowIPs)
+	token := []byte(d.Token)
+	return func(c *gin.Context) {
+		if ipAllowed(nets, c.RemoteIP()) {
+			c.Next()
+			return
+		}
+		if len(token) == 0 {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		auth := c.GetHeader("Authorization")
+		got, ok := strings.CutPrefix(auth, "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), token) != 1 {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Next()
+	}
+}
+
+// pprofHandler pprof处理函数, gin不支持通配路由和静态路由共存, 因此统一分发
+func pprofHandler(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("name"), "/")
+	switch name {
+	case "cmdline":
+		pprof.Cmdline(c.Writer, c.Request)
+	case "profile":
+		pprof.Profile(c.Writer, c.Request)
+	case "symbol":
+		pprof.Symbol(c.Writer, c.Request)
+	case "trace":
+		pprof.Trace(c.Writer, c.Request)
+	case "":
+		pprof.Index(c.Writer, c.Request)
+	default:
+		// pprof.Index只识别/debug/pprof/前缀, 挂载在其他路径时直接按名称查找
+		pprof.Handler(name).ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+// goroutinesHandler 输出所有goroutine的调用栈
+func goroutinesHandler(c *gin.Context) {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", buf)
+}
+
+// buildInfoHandler 输出构建信息
+func buildInfoHandler(c *gin.Context) {
+	info := debugBuildInfo{
+		GoVersion: runtime.Version(),
+		GOOS:      runtime.GOOS,
+		GOARCH:    runtime.GOARCH,
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		info.Path = bi.Main.Path
+		info.Version = bi.Main.Version
+		info.Settings = make(map[string]string, len(bi.Settings))
+		for _, setting := range bi.Settings {
+			info.Settings[setting.Key] = setting.Value
+		}
+		info.Deps = make(map[string]string, len(bi.Deps))
+		for _, dep := range bi.Deps {
+			info.Deps[dep.Path] = dep.Version
+		}
+	}
+	c.JSON(http.StatusOK, info)
+}
+
+func debugRoutes(routes gin.RoutesInfo) []debugRouteInfo {
+	list := make([]debugRouteInfo, 0, len(routes))
+	for _, route := range routes {
+		list = append(list, debugRouteInfo{Method: route.Method, Path: route.Path, Handler: route.Handler})
+	}
+	return list
+}
+
+// effectiveConfig 当前生效的引擎配置
+func (l *GinEngine) effectiveConfig() debugEngineConfig {
+	cfg := debugEngineConfig{
+		BasePath:         l.basePath,
+		GenApiEnable:     l.genApiEnable,
+		TLS:              l.tlsConfig != nil,
+		MTLS:             l.tlsConfig != nil && l.tlsConfig.ClientCAFile != "",
+		H2C:              l.h2c,
+		SystemdListeners: l.systemdListeners,
+		Upgrader:         l.upgrader != nil,
+		Ping:             l.ping != nil,
+		Graphql:          l.graphqlConfig.Enable,
+		GraphqlPath:      l.graphqlConfig.HandlePath,
+		ShutdownTimeout:  l.shutdownTimeout.String(),
+		PreStopDelay:     l.preStopDelay.String(),
+		Middlewares:      len(l.middlewares),
+		Controllers:      len(l.controllers),
+		Routes:           debugRoutes(l.Engine.Routes()),
+		Env:              map[string]string{"gin_mode": gin.Mode()},
+	}
+	cfg.Addr = l.addr
+	if l.server != nil {
+		cfg.Addr = l.server.Addr
+	}
+	for _, socket := range l.unixSockets {
+		cfg.UnixSockets = append(cfg.UnixSockets, socket.path)
+	}
+	if l.admin != nil {
+		cfg.AdminAddr = l.admin.addr
+		cfg.AdminRoutes = debugRoutes(l.admin.engine.Routes())
+	}
+	if l.metrics != nil {
+		cfg.MetricsPath = l.metrics.Path
+		cfg.MetricsAddr = l.metrics.Addr
+	}
+	if l.health != nil {
+		cfg.HealthPaths = []string{l.health.LivenessPath, l.health.ReadinessPath, l.health.StartupPath}
+	}
+	return cfg
+}
+
+func registerDebug(instance *GinEngine, d *Debug) {
+	if d == nil {
+		return
+	}
+	if d.Path == "" {
+		d.Path = defaultDebugPath
+	}
+
+	if len(d.AllowIPs) == 0 && d.Token == "" {
+		Logger().Sugar().Warnf("[GIN-PLUS] [WARNING] Debug endpoints at %s deny all requests, set AllowIPs or Token to enable them", d.Path)
+	}
+	r := instance.Admin().Group(d.Path, d.guard())
+	r.GET("/pprof/*name", pprofHandler)
+	r.POST("/pprof/symbol", pprofHandler)
+	r.GET("/vars", gin.WrapH(expvar.Handler()))
+	r.GET("/goroutines", goroutinesHandler)
+	r.GET("/trace", gin.WrapF(pprof.Trace))
+	r.GET("/buildinfo", buildInfoHandler)
+	r.GET("/config", func(c *gin.Context) {
+		c.JSON(http.StatusOK, instance.effectiveConfig())
+	})
+	Logger().Sugar().Infof("[GIN-PLUS] [INFO] Debug endpoints mounted at %s", path.Join(d.Path, "*"))
+}
+
+// RegisterDebug 注册运行时诊断接口, 包括pprof、expvar、goroutine、runtime/trace、构建信息和生效的配置,
+// 设置了管理端口时挂载在管理端口上, 所有接口都经过访问控制, 需要设置Debug.AllowIPs或Debug.Token才能访问
+func (l *GinEngine) RegisterDebug(debug ...*Debug) *GinEngine {
+	if len(debug) > 0 {
+		l.debug = debug[0]
+	}
+	if l.debug == nil {
+		l.debug = &Debug{}
+	}
+	registerDebug(l, l.debug)
+	return l
+}
+
+// WithDebug 自定义运行时诊断配置
+func WithDebug(debug *Debug) OptionFun {
+	return func(g *GinEngine) {
+		g.debug = debug
+	}
+}
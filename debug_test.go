@@ -0,0 +1,102 @@
+package ginplus
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGinEngine_RegisterDebug(t *testing.T) {
+	instance := New(gin.New(), WithAddr(":9999"), WithAdminAddr(":9998"))
+	instance.RegisterDebug(&Debug{AllowIPs: []string{"192.0.2.0/24"}, Token: "secret"})
+
+	tests := []struct {
+		name       string
+		path       string
+		remoteAddr string
+		forwarded  string
+		token      string
+		status     int
+		contains   string
+	}{
+		{name: "denied", path: "/debug/vars", remoteAddr: "198.51.100.1:1234", status: http.StatusUnauthorized},
+		{name: "wrong token", path: "/debug/vars", remoteAddr: "198.51.100.1:1234", token: "wrong", status: http.StatusUnauthorized},
+		{name: "forwarded for allowlist", path: "/debug/vars", remoteAddr: "198.51.100.1:1234", forwarded: "192.0.2.1", status: http.StatusUnauthorized},
+		{name: "token", path: "/debug/vars", remoteAddr: "198.51.100.1:1234", token: "secret", status: http.StatusOK, contains: "memstats"},
+		{name: "allowlist", path: "/debug/goroutines", remoteAddr: "192.0.2.1:1234", status: http.StatusOK, contains: "goroutine"},
+		{name: "pprof index", path: "/debug/pprof/", remoteAddr: "192.0.2.1:1234", status: http.StatusOK, contains: "heap"},
+		{name: "pprof profile", path: "/debug/pprof/heap?debug=1", remoteAddr: "192.0.2.1:1234", status: http.StatusOK, contains: "heap profile"},
+		{name: "buildinfo", path: "/debug/buildinfo", remoteAddr: "192.0.2.1:1234", status: http.StatusOK, contains: "go_version"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			instance.Admin().ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("want status %d, got %d", tt.status, w.Code)
+			}
+			if !strings.Contains(w.Body.String(), tt.contains) {
+				t.Errorf("want body containing %q", tt.contains)
+			}
+		})
+	}
+
+	// 令牌只能通过Authorization请求头传递
+	req := httptest.NewRequest(http.MethodGet, "/debug/config?token=secret", nil)
+	req.RemoteAddr = "198.51.100.1:1234"
+	w := httptest.NewRecorder()
+	instance.Admin().ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("want token query parameter rejected, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/debug/config", nil)
+	req.RemoteAddr = "198.51.100.1:1234"
+	req.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	instance.Admin().ServeHTTP(w, req)
+	var cfg debugEngineConfig
+	if err := json.Unmarshal(w.Body.Bytes(), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Addr != ":9999" || cfg.AdminAddr != ":9998" || len(cfg.AdminRoutes) == 0 {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+
+	// 未设置白名单和令牌时拒绝所有访问, 包括本机
+	local := New(gin.New()).RegisterDebug()
+	for _, remoteAddr := range []string{"127.0.0.1:1234", "192.0.2.1:1234"} {
+		req := httptest.NewRequest(http.MethodGet, "/debug/buildinfo", nil)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		local.ServeHTTP(w, req)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s: want status %d, got %d", remoteAddr, http.StatusForbidden, w.Code)
+		}
+	}
+
+	// 显式允许本机访问, X-Forwarded-For不能伪造本机地址
+	loopback := New(gin.New()).RegisterDebug(&Debug{AllowIPs: []string{"127.0.0.1", "::1"}})
+	for remoteAddr, status := range map[string]int{"127.0.0.1:1234": http.StatusOK, "192.0.2.1:1234": http.StatusForbidden} {
+		req := httptest.NewRequest(http.MethodGet, "/debug/buildinfo", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", "127.0.0.1")
+		w := httptest.NewRecorder()
+		loopback.ServeHTTP(w, req)
+		if w.Code != status {
+			t.Errorf("%s: want status %d, got %d", remoteAddr, status, w.Code)
+		}
+	}
+}
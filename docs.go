@@ -0,0 +1,305 @@
+package ginplus
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+
+	"github.com/aide-cloud/gin-plus/rapidoc"
+	"github.com/aide-cloud/gin-plus/redoc"
+	"github.com/aide-cloud/gin-plus/scalar"
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+const defaultDocsPath = "/docs"
+
+type (
+	// Docs 文档页面配置, 所有渲染器使用同一份生成的openapi文档
+	Docs struct {
+		// Path 首页路径, 默认为/docs, 渲染器挂载在{Path}/{Name}/
+		Path string
+		// SpecPath openapi文档路径, 默认为{Path}/openapi.yaml
+		SpecPath string
+		// SpecJSONPath json格式的openapi文档路径, 默认为{Path}/openapi.json
+		SpecJSONPath string
+		// Title 首页标题, 默认为ApiConfig.Title
+		Title string
+		// Renderers 渲染器, 为空时只使用SwaggerUIRenderer
+		Renderers []DocsRenderer
+	}
+
+	// DocsSpec 渲染器使用的openapi文档地址
+	DocsSpec struct {
+		// URL yaml格式的文档地址
+		URL string
+		// JSONURL json格式的文档地址
+		JSONURL string
+	}
+
+	// DocsRenderer 文档渲染器
+	DocsRenderer interface {
+		// Name 渲染器名称, 同时作为路径
+		Name() string
+		// Title 首页展示的名称
+		Title() string
+		// Handler 返回挂载在prefix下的页面
+		Handler(prefix string, spec DocsSpec) (http.Handler, error)
+	}
+
+	// staticDocsRenderer 由静态资源和index.html模板组成的渲染器
+	staticDocsRenderer struct {
+		name   string
+		title  string
+		assets fs.FS
+		// 必须存在的官方脚本, 为空时不检查
+		bundle string
+	}
+
+	// swaggerUIRenderer swagger ui渲染器
+	swaggerUIRenderer struct {
+		config *SwaggerUI
+	}
+)
+
+// NewDocsRenderer 使用静态资源创建渲染器, assets根目录需要包含index.html模板,
+// 模板中可以使用{{.Title}}、{{.SpecURL}}和{{.SpecJSONURL}}, 其余文件按原路径提供
+func NewDocsRenderer(name, title string, assets fs.FS) DocsRenderer {
+	return &staticDocsRenderer{name: name, title: title, assets: assets}
+}
+
+// SwaggerUIRenderer swagger ui渲染器, 忽略config中的路径配置
+func SwaggerUIRenderer(config ...*SwaggerUI) DocsRenderer {
+	r := &swaggerUIRenderer{config: &SwaggerUI{}}
+	if len(config) > 0 && config[0] != nil {
+		r.config = config[0]
+	}
+	return r
+}
+
+// ReDocRenderer ReDoc官方脚本渲染器, 脚本需要先在redoc包中执行go generate下载, 未下载时注册返回错误
+func ReDocRenderer() DocsRenderer {
+	return embeddedDocsRenderer("redoc", "ReDoc", redoc.Dist, redoc.Bundle)
+}
+
+// ScalarRenderer Scalar API Reference官方脚本渲染器, 脚本需要先在scalar包中执行go generate下载, 未下载时注册返回错误
+func ScalarRenderer() DocsRenderer {
+	return embeddedDocsRenderer("scalar", "Scalar", scalar.Dist, scalar.Bundle)
+}
+
+// RapiDocRenderer RapiDoc官方脚本渲染器, 脚本需要先在rapidoc包中执行go generate下载, 未下载时注册返回错误
+func RapiDocRenderer() DocsRenderer {
+	return embeddedDocsRenderer("rapidoc", "RapiDoc", rapidoc.Dist, rapidoc.Bundle)
+}
+
+func embeddedDocsRenderer(name, title string, dist fs.FS, bundle string) DocsRenderer {
+	assets, _ := fs.Sub(dist, "dist")
+	return &staticDocsRenderer{name: name, title: title, assets: assets, bundle: bundle}
+}
+
+func (r *staticDocsRenderer) Name() string {
+	return r.name
+}
+
+func (r *staticDocsRenderer) Title() string {
+	return r.title
+}
+
+func (r *staticDocsRenderer) Handler(prefix string, spec DocsSpec) (http.Handler, error) {
+	if r.bundle != "" {
+		if _, err := fs.Stat(r.assets, r.bundle); err != nil {
+			return nil, fmt.Errorf("official script %s is not vendored, run go generate ./%s/...", r.bundle, r.name)
+		}
+	}
+	tmpl, err := template.ParseFS(r.assets, "index.html")
+	if err != nil {
+		return nil, err
+	}
+	var index bytes.Buffer
+	if err := tmpl.Execute(&index, map[string]string{"Title": r.title, "SpecURL": spec.URL, "SpecJSONURL": spec.JSONURL}); err != nil {
+		return nil, err
+	}
+
+	fileServer := http.StripPrefix(prefix, http.FileServer(http.FS(r.assets)))
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// http.FileServer会把index.html重定向到目录, 首页直接返回渲染后的模板
+		if p := req.URL.Path; p == prefix+"/" || p == prefix+"/index.html" {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write(index.Bytes())
+			return
+		}
+		fileServer.ServeHTTP(w, req)
+	}), nil
+}
+
+func (r *swaggerUIRenderer) Name() string {
+	return "swagger-ui"
+}
+
+func (r *swaggerUIRenderer) Title() string {
+	return "Swagger UI"
+}
+
+func (r *swaggerUIRenderer) Handler(prefix string, spec DocsSpec) (http.Handler, error) {
+	initializer, err := r.config.initializer(prefix, spec.URL)
+	if err != nil {
+		return nil, err
+	}
+	return swaggerUIHandler(prefix, initializer), nil
+}
+
+// docsIndexTemplate 文档首页, 列出所有渲染器
+var docsIndexTemplate = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html lang="en">
+  <head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <title>{{.Title}}</title>
+    <style>
+      body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; max-width: 640px; margin: 48px auto; padding: 0 16px; color: #24292f; }
+      li { margin: 8px 0; }
+      a { color: #0969da; text-decoration: none; }
+    </style>
+  </head>
+
+  <body>
+    <h1>{{.Title}}</h1>
+    <ul>
+      {{- range .Renderers}}
+      <li><a href="{{.Path}}/">{{.Title}}</a></li>
+      {{- end}}
+      <li><a href="{{.SpecPath}}">OpenAPI</a></li>
+    </ul>
+  </body>
+</html>
+`))
+
+func registerDocs(instance *GinEngine, docs *Docs) error {
+	if !instance.genApiEnable {
+		return nil
+	}
+	if docs == nil {
+		docs = &Docs{}
+	}
+	if docs.Path == "" {
+		docs.Path = defaultDocsPath
+	}
+	if docs.SpecPath == "" {
+		docs.SpecPath = path.Join(docs.Path, "openapi.yaml")
+	}
+	if docs.SpecJSONPath == "" {
+		docs.SpecJSONPath = path.Join(docs.Path, "openapi.json")
+	}
+	if docs.Title == "" {
+		docs.Title = instance.apiConfig.Title
+	}
+	renderers := docs.Renderers
+	if len(renderers) == 0 {
+		renderers = []DocsRenderer{SwaggerUIRenderer()}
+	}
+
+	docsPath := instance.prefixPath(docs.Path)
+	spec := DocsSpec{URL: instance.prefixPath(docs.SpecPath), JSONURL: instance.prefixPath(docs.SpecJSONPath)}
+	type link struct{ Path, Title string }
+	links := make([]link, 0, len(renderers))
+	handlers := make(map[string]http.Handler, len(renderers))
+	for _, renderer := range renderers {
+		prefix := path.Join(docsPath, renderer.Name())
+		if _, ok := handlers[prefix]; ok {
+			return fmt.Errorf("docs renderer %s is duplicated", renderer.Name())
+		}
+		handler, err := renderer.Handler(prefix, spec)
+		if err != nil {
+			return fmt.Errorf("docs renderer %s: %w", renderer.Name(), err)
+		}
+		handlers[prefix] = handler
+		links = append(links, link{Path: prefix, Title: renderer.Title()})
+	}
+	var index bytes.Buffer
+	if err := docsIndexTemplate.Execute(&index, map[string]any{"Title": docs.Title, "SpecPath": spec.URL, "Renderers": links}); err != nil {
+		return err
+	}
+
+	instance.genOpenApiYaml()
+	r := instance.Admin()
+	r.GET(spec.URL, instance.serveOpenApiYaml)
+	r.GET(spec.JSONURL, instance.serveOpenApiJSON)
+	r.GET(docsPath, func(ctx *gin.Context) {
+		ctx.Data(http.StatusOK, "text/html; charset=utf-8", index.Bytes())
+	})
+	for _, l := range links {
+		mountDocsPage(r, l.Path, handlers[l.Path])
+	}
+	return nil
+}
+
+// serveOpenApiJSON 将生成的yaml文档转换为json返回, 供只支持json的渲染器使用
+func (l *GinEngine) serveOpenApiJSON(ctx *gin.Context) {
+	file, err := os.ReadFile(l.openApiYaml())
+	if err != nil {
+		Logger().Sugar().Errorf("[GIN-PLUS] [ERROR] read openapi doc: %v", err)
+		ctx.String(http.StatusInternalServerError, "openapi doc not found")
+		return
+	}
+	var doc any
+	if err := yaml.Unmarshal(file, &doc); err != nil {
+		Logger().Sugar().Errorf("[GIN-PLUS] [ERROR] parse openapi doc: %v", err)
+		ctx.String(http.StatusInternalServerError, "openapi doc is invalid")
+		return
+	}
+	data, err := json.Marshal(jsonCompatible(doc))
+	if err != nil {
+		Logger().Sugar().Errorf("[GIN-PLUS] [ERROR] encode openapi doc: %v", err)
+		ctx.String(http.StatusInternalServerError, "openapi doc is invalid")
+		return
+	}
+	ctx.Data(http.StatusOK, "application/json; charset=utf-8", data)
+}
+
+// jsonCompatible yaml中的非字符串键(如响应码200)会被解析为map[any]any, 转换为json可以编码的结构
+func jsonCompatible(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, item := range v {
+			v[k] = jsonCompatible(item)
+		}
+		return v
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for k, item := range v {
+			m[fmt.Sprint(k)] = jsonCompatible(item)
+		}
+		return m
+	case []any:
+		for i, item := range v {
+			v[i] = jsonCompatible(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// RegisterDocs 注册文档首页和各个渲染器页面, 关闭文档生成时不注册, 渲染器错误时记录错误并由Start返回
+func (l *GinEngine) RegisterDocs(docs ...*Docs) *GinEngine {
+	if len(docs) > 0 {
+		l.docs = docs[0]
+	}
+	if err := registerDocs(l, l.docs); err != nil {
+		Logger().Sugar().Errorf("[GIN-PLUS] [ERROR] Register docs: %v", err)
+		l.registerErrs = append(l.registerErrs, err)
+	}
+	return l
+}
+
+// WithDocs 自定义文档页面
+func WithDocs(docs *Docs) OptionFun {
+	return func(g *GinEngine) {
+		g.docs = docs
+	}
+}
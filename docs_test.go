@@ -0,0 +1,115 @@
+package ginplus
+
+import (
+	"io/fs"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/aide-cloud/gin-plus/rapidoc"
+	"github.com/aide-cloud/gin-plus/redoc"
+	"github.com/aide-cloud/gin-plus/scalar"
+	"github.com/gin-gonic/gin"
+)
+
+func TestGinEngine_RegisterDocs(t *testing.T) {
+	assets := fstest.MapFS{
+		"index.html": {Data: []byte(`<title>{{.Title}}</title><doc-viewer spec-url="{{.SpecURL}}" json-url="{{.SpecJSONURL}}"></doc-viewer>`)},
+		"viewer.js":  {Data: []byte(`console.log("viewer")`)},
+	}
+	engine := New(gin.New(), WithBasePath("api"), WithOpenApiYaml(t.TempDir(), "openapi.yaml"), WithControllers(&Api{}))
+	engine.RegisterDocs(&Docs{
+		Title:     "Partner API",
+		Renderers: []DocsRenderer{SwaggerUIRenderer(), NewDocsRenderer("viewer", "Viewer", assets)},
+	})
+	if len(engine.registerErrs) > 0 {
+		t.Fatal(engine.registerErrs)
+	}
+
+	tests := []struct {
+		path string
+		code int
+		want string
+	}{
+		{path: "/api/docs", code: 200, want: `<a href="/api/docs/swagger-ui/">Swagger UI</a>`},
+		{path: "/api/docs", code: 200, want: `<a href="/api/docs/viewer/">Viewer</a>`},
+		{path: "/api/docs", code: 200, want: `<a href="/api/docs/openapi.yaml">OpenAPI</a>`},
+		{path: "/api/docs/openapi.yaml", code: 200, want: "openapi: 3.1.3"},
+		{path: "/api/docs/openapi.json", code: 200, want: `"openapi":"3.1.3"`},
+		{path: "/api/docs/swagger-ui/swagger-initializer.js", code: 200, want: `"url":"/api/docs/openapi.yaml"`},
+		{path: "/api/docs/viewer", code: 301},
+		{path: "/api/docs/viewer/", code: 200, want: `<title>Viewer</title><doc-viewer spec-url="/api/docs/openapi.yaml" json-url="/api/docs/openapi.json">`},
+		{path: "/api/docs/viewer/viewer.js", code: 200, want: "viewer"},
+		{path: "/api/docs/viewer/missing.js", code: 404},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != tt.code || !strings.Contains(w.Body.String(), tt.want) {
+			t.Errorf("%s: %d %s", tt.path, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestGinEngine_RegisterDocsDefault(t *testing.T) {
+	engine := New(gin.New(), WithOpenApiYaml(t.TempDir(), "openapi.yaml")).RegisterDocs()
+	if len(engine.registerErrs) > 0 {
+		t.Fatal(engine.registerErrs)
+	}
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/docs", nil))
+	if !strings.Contains(w.Body.String(), `<a href="/docs/swagger-ui/">Swagger UI</a>`) {
+		t.Fatalf("index: %s", w.Body.String())
+	}
+	// 默认只注册Swagger UI, 其他渲染器需要显式启用
+	for _, name := range []string{"redoc", "scalar", "rapidoc"} {
+		if strings.Contains(w.Body.String(), `/docs/`+name+`/`) {
+			t.Errorf("%s listed by default: %s", name, w.Body.String())
+		}
+	}
+}
+
+func TestEmbeddedDocsRenderer(t *testing.T) {
+	spec := DocsSpec{URL: "/docs/openapi.yaml", JSONURL: "/docs/openapi.json"}
+	for _, tt := range []struct {
+		renderer DocsRenderer
+		dist     fs.FS
+		bundle   string
+	}{
+		{renderer: ReDocRenderer(), dist: redoc.Dist, bundle: redoc.Bundle},
+		{renderer: ScalarRenderer(), dist: scalar.Dist, bundle: scalar.Bundle},
+		{renderer: RapiDocRenderer(), dist: rapidoc.Dist, bundle: rapidoc.Bundle},
+	} {
+		name := tt.renderer.Name()
+		handler, err := tt.renderer.Handler("/docs/"+name, spec)
+		// 官方脚本未下载时返回错误, 不提供无法渲染的页面
+		if _, statErr := fs.Stat(tt.dist, path.Join("dist", tt.bundle)); statErr != nil {
+			if err == nil || !strings.Contains(err.Error(), tt.bundle) {
+				t.Errorf("%s: want missing bundle error, got %v", name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/docs/"+name+"/", nil))
+		if body := w.Body.String(); !strings.Contains(body, `"/docs/openapi.json"`) || !strings.Contains(body, tt.bundle) {
+			t.Errorf("%s: %s", name, body)
+		}
+	}
+
+	// 官方脚本存在时正常注册
+	assets := fstest.MapFS{
+		"index.html":          {Data: []byte(`<redoc spec-url="{{.SpecJSONURL}}"></redoc><script src="./redoc.standalone.js"></script>`)},
+		"redoc.standalone.js": {Data: []byte(`/* redoc */`)},
+	}
+	renderer := embeddedDocsRenderer("redoc", "ReDoc", fstest.MapFS{}, redoc.Bundle).(*staticDocsRenderer)
+	renderer.assets = assets
+	if _, err := renderer.Handler("/docs/redoc", spec); err != nil {
+		t.Error(err)
+	}
+}
@@ -1,9 +1,20 @@
 package ginplus
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
+	"time"
+)
+
+const (
+	defaultStopTimeout = 30 * time.Second
+	// serverAdapterReadyDelay Server.Start超过该时间仍未返回时视为阻塞式启动, 此时认为服务已就绪
+	serverAdapterReadyDelay = 100 * time.Millisecond
 )
 
 type (
@@ -26,67 +37,496 @@ type (
 
 	// CtrlC 捕获ctrl-c的控制器
 	CtrlC struct {
-		servers []Server
+		app *App
+	}
+
+	// ServerV2 支持context的服务接口
+	ServerV2 interface {
+		// Start 启动服务, 不应阻塞, 阻塞式的服务需要实现Readier
+		Start(ctx context.Context) error
+		// Stop 在ctx超时前停止服务
+		Stop(ctx context.Context) error
+	}
+
+	// Readier 可选接口, 服务就绪时关闭返回的通道
+	Readier interface {
+		Ready() <-chan struct{}
+	}
+
+	// App 多服务的生命周期管理器
+	//
+	// 按依赖顺序启动服务, 服务在依赖就绪后才会启动; 任一服务启动失败、收到退出信号或ctx取消时,
+	// 按启动顺序的逆序停止已启动的服务, 停止过程中再次收到信号时强制退出
+	App struct {
+		services []*appService
+		// 监听的退出信号
+		signals []os.Signal
 		// 信号通道
 		signalChan chan os.Signal
+		// 停止单个服务的超时时间
+		stopTimeout time.Duration
+		// 强制退出函数, 默认为os.Exit
+		exit func(code int)
+		// 零停机重启, 收到升级信号时启动子进程, 子进程就绪后停止当前进程的服务
+		upgrader *Upgrader
+		// 升级信号, 默认为SIGUSR2
+		upgradeSignals []os.Signal
+
+		// 保护服务的启动状态, 开始停止后不再启动新的服务
+		mu       sync.Mutex
+		stopping bool
+	}
+
+	// AppOption App配置函数
+	AppOption func(*App)
+
+	// appService App中注册的服务
+	appService struct {
+		name      string
+		service   ServerV2
+		dependsOn []string
+
+		// 是否已调用Start, 由App.mu保护
+		started bool
+		// 就绪时关闭
+		ready     chan struct{}
+		readyOnce sync.Once
+	}
+
+	// serverAdapter 把Server适配为ServerV2
+	serverAdapter struct {
+		server Server
+		// Start返回或阻塞超过serverAdapterReadyDelay时关闭
+		ready     chan struct{}
+		readyOnce sync.Once
+	}
+
+	// serverV2Adapter 把ServerV2适配为Server
+	serverV2Adapter struct {
+		server ServerV2
 	}
 )
 
 // NewCtrlC 初始化生成CtrlC
 func NewCtrlC(services ...Server) *CtrlC {
 	return &CtrlC{
-		servers:    services,
-		signalChan: make(chan os.Signal, 1),
+		app: NewApp(WithAppServers(services...)),
 	}
 }
 
-// 等待键盘信号
-func (c *CtrlC) waitSignals(signals ...os.Signal) {
-	signal.Notify(c.signalChan, signals...)
-	<-c.signalChan
+// Start 开始运行程序，遇到os.Interrupt停止
+func (c *CtrlC) Start() {
+	if err := c.app.Run(context.Background()); err != nil {
+		Logger().Sugar().Errorf("[GIN-PLUS] [ERROR] %v", err)
+	}
 }
 
-// 接收到kill信号
-func (c *CtrlC) waitKill() {
-	c.waitSignals(os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+// WithUpgrader 启用零停机重启, signals为空时使用SIGUSR2
+func (c *CtrlC) WithUpgrader(upgrader *Upgrader, signals ...os.Signal) *CtrlC {
+	WithAppUpgrader(upgrader, signals...)(c.app)
+	return c
 }
 
-func (c *CtrlC) recover() {
-	if err := recover(); err != nil {
-		Logger().Sugar().Panicf("panic: %v", err)
-		c.signalChan <- os.Kill
+// NewApp 创建App
+func NewApp(opts ...AppOption) *App {
+	app := &App{
+		signals:     []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP},
+		signalChan:  make(chan os.Signal, 1),
+		stopTimeout: defaultStopTimeout,
+		exit:        os.Exit,
 	}
+	for _, opt := range opts {
+		opt(app)
+	}
+	return app
 }
 
-// Start 开始运行程序，遇到os.Interrupt停止
-func (c *CtrlC) Start() {
+// WithAppServers 添加服务, 服务名称为"类型#序号"
+func WithAppServers(servers ...Server) AppOption {
+	return func(a *App) {
+		for _, server := range servers {
+			name := fmt.Sprintf("%T#%d", server, len(a.services))
+			a.services = append(a.services, &appService{name: name, service: FromServer(server)})
+		}
+	}
+}
+
+// WithAppService 添加命名服务, 服务在dependsOn中的所有服务就绪后才会启动
+func WithAppService(name string, server ServerV2, dependsOn ...string) AppOption {
+	return func(a *App) {
+		a.services = append(a.services, &appService{name: name, service: server, dependsOn: dependsOn})
+	}
+}
+
+// WithAppSignals 设置监听的退出信号, 为空时不监听信号, 只能通过ctx取消
+func WithAppSignals(signals ...os.Signal) AppOption {
+	return func(a *App) {
+		a.signals = signals
+	}
+}
+
+// WithAppStopTimeout 设置停止单个服务的超时时间
+func WithAppStopTimeout(timeout time.Duration) AppOption {
+	return func(a *App) {
+		a.stopTimeout = timeout
+	}
+}
+
+// WithAppExit 设置强制退出函数, 默认为os.Exit
+func WithAppExit(exit func(code int)) AppOption {
+	return func(a *App) {
+		a.exit = exit
+	}
+}
+
+// WithAppUpgrader 启用零停机重启, 收到升级信号时启动子进程并传递listener, 子进程就绪后停止当前进程的服务;
+// 当前进程是子进程时, 所有服务就绪后通知父进程. signals为空时使用SIGUSR2
+func WithAppUpgrader(upgrader *Upgrader, signals ...os.Signal) AppOption {
+	return func(a *App) {
+		if len(signals) == 0 {
+			signals = defaultUpgradeSignals
+		}
+		a.upgrader = upgrader
+		a.upgradeSignals = signals
+	}
+}
+
+// Run 启动所有服务并阻塞, 直到ctx取消、收到退出信号或任一服务启动失败, 返回启动和停止过程中的所有错误
+func (a *App) Run(ctx context.Context) error {
+	services, err := a.sortServices()
+	if err != nil {
+		return err
+	}
+
+	if len(a.signals) > 0 {
+		signal.Notify(a.signalChan, a.signals...)
+		defer signal.Stop(a.signalChan)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	a.mu.Lock()
+	a.stopping = false
+	for _, service := range services {
+		service.started = false
+	}
+	a.mu.Unlock()
+
+	byName := make(map[string]*appService, len(services))
+	for _, service := range services {
+		service.ready = make(chan struct{})
+		byName[service.name] = service
+	}
+
+	startErrCh := make(chan error, len(services))
+	var wg sync.WaitGroup
+	for _, service := range services {
+		wg.Add(1)
+		go func(s *appService) {
+			defer wg.Done()
+			// 等待依赖就绪
+			for _, dep := range s.dependsOn {
+				select {
+				case <-byName[dep].ready:
+				case <-runCtx.Done():
+					return
+				}
+			}
+			a.startService(runCtx, s, startErrCh)
+		}(service)
+	}
+
+	var upgradeChan chan os.Signal
+	if a.upgrader != nil {
+		go a.notifyReady(runCtx, services)
+		if len(a.upgradeSignals) > 0 {
+			upgradeChan = make(chan os.Signal, 1)
+			signal.Notify(upgradeChan, a.upgradeSignals...)
+			defer signal.Stop(upgradeChan)
+		}
+	}
+
+	var errs []error
+wait:
+	for {
+		select {
+		case <-ctx.Done():
+			break wait
+		case sig := <-a.signalChan:
+			Logger().Sugar().Infof("[GIN-PLUS] [INFO] Received signal %s, stopping", sig)
+			break wait
+		case err := <-startErrCh:
+			errs = append(errs, err)
+			Logger().Sugar().Errorf("[GIN-PLUS] [ERROR] %v, stopping", err)
+			break wait
+		case sig := <-upgradeChan:
+			// 升级失败时继续运行
+			Logger().Sugar().Infof("[GIN-PLUS] [INFO] Received signal %s, upgrading", sig)
+			if err := a.upgrader.Upgrade(runCtx); err != nil {
+				Logger().Sugar().Errorf("[GIN-PLUS] [ERROR] Upgrade: %v", err)
+				continue
+			}
+			break wait
+		}
+	}
+	cancel()
+
+	// 停止过程中再次收到信号时强制退出
+	done := make(chan struct{})
+	defer close(done)
 	go func() {
-		defer c.recover()
-		// 启动程序内部的服务列表
-		c.startMulServices()
+		select {
+		case sig := <-a.signalChan:
+			Logger().Sugar().Warnf("[GIN-PLUS] [WARNING] Received signal %s again, forcing exit", sig)
+			a.exit(1)
+		case <-done:
+		}
 	}()
 
-	c.waitKill()
-	c.stopMulServices()
+	errs = append(errs, a.stopAll(services)...)
+
+	// 等待阻塞式的Start返回, 收集停止期间返回的启动错误
+	waitCh := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(waitCh)
+	}()
+	select {
+	case <-waitCh:
+	case <-time.After(a.stopTimeout):
+	}
+	for {
+		select {
+		case err := <-startErrCh:
+			errs = append(errs, err)
+		default:
+			return errors.Join(errs...)
+		}
+	}
+}
+
+// notifyReady 所有服务就绪后通知父进程
+func (a *App) notifyReady(ctx context.Context, services []*appService) {
+	for _, service := range services {
+		select {
+		case <-service.ready:
+		case <-ctx.Done():
+			return
+		}
+	}
+	if err := a.upgrader.Ready(); err != nil {
+		Logger().Sugar().Errorf("[GIN-PLUS] [ERROR] Notify parent ready: %v", err)
+	}
 }
 
-// 启动应用子服务
-func (c *CtrlC) startMulServices() {
-	servicesSlice := c.servers
-	for _, service := range servicesSlice {
-		go func(s Starter) {
-			defer c.recover()
-			if err := s.Start(); err != nil {
-				panic(err)
+// startService 启动服务并等待其就绪, 未实现Readier的服务在Start返回后视为就绪
+func (a *App) startService(ctx context.Context, s *appService, errCh chan<- error) {
+	if !a.markStarting(ctx, s) {
+		return
+	}
+	markReady := func() {
+		s.readyOnce.Do(func() { close(s.ready) })
+	}
+	readier, isReadier := s.service.(Readier)
+	if isReadier {
+		go func() {
+			select {
+			case <-readier.Ready():
+				markReady()
+			case <-ctx.Done():
 			}
-		}(service)
+		}()
+	}
+
+	if err := a.start(ctx, s); err != nil {
+		errCh <- err
+		return
+	}
+	if !isReadier {
+		markReady()
 	}
 }
 
-// 停止应用子服务
-func (c *CtrlC) stopMulServices() {
-	servicesSlice := c.servers
-	for _, service := range servicesSlice {
-		service.Stop()
+// markStarting 记录服务即将启动, ctx已取消或已开始停止时返回false, 与stopAll互斥, 保证启动的服务都会被停止
+func (a *App) markStarting(ctx context.Context, s *appService) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.stopping || ctx.Err() != nil {
+		return false
+	}
+	s.started = true
+	return true
+}
+
+// sortServices 按依赖关系对服务进行拓扑排序, 依赖不存在或循环依赖时返回错误
+func (a *App) sortServices() ([]*appService, error) {
+	byName := make(map[string]*appService, len(a.services))
+	for _, service := range a.services {
+		if _, ok := byName[service.name]; ok {
+			return nil, fmt.Errorf("duplicate service %q", service.name)
+		}
+		byName[service.name] = service
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int, len(a.services))
+	sorted := make([]*appService, 0, len(a.services))
+	var visit func(s *appService) error
+	visit = func(s *appService) error {
+		switch state[s.name] {
+		case visiting:
+			return fmt.Errorf("circular dependency on service %q", s.name)
+		case visited:
+			return nil
+		}
+		state[s.name] = visiting
+		for _, dep := range s.dependsOn {
+			depService, ok := byName[dep]
+			if !ok {
+				return fmt.Errorf("service %q depends on unknown service %q", s.name, dep)
+			}
+			if err := visit(depService); err != nil {
+				return err
+			}
+		}
+		state[s.name] = visited
+		sorted = append(sorted, s)
+		return nil
+	}
+	for _, service := range a.services {
+		if err := visit(service); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
+
+// start 启动服务, 把panic转换为错误
+func (a *App) start(ctx context.Context, s *appService) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("start %s panic: %v", s.name, r)
+		}
+	}()
+	if err = s.service.Start(ctx); err != nil {
+		return fmt.Errorf("start %s: %w", s.name, err)
+	}
+	return nil
+}
+
+// stopAll 按启动顺序的逆序停止已启动的服务
+func (a *App) stopAll(services []*appService) []error {
+	a.mu.Lock()
+	a.stopping = true
+	started := make([]*appService, 0, len(services))
+	for _, service := range services {
+		if service.started {
+			started = append(started, service)
+		}
+	}
+	a.mu.Unlock()
+
+	var errs []error
+	for i := len(started) - 1; i >= 0; i-- {
+		if err := a.stop(started[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// stop 在超时时间内停止服务, 把panic转换为错误
+func (a *App) stop(s *appService) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("stop %s panic: %v", s.name, r)
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), a.stopTimeout)
+	defer cancel()
+	if err = s.service.Stop(ctx); err != nil {
+		return fmt.Errorf("stop %s: %w", s.name, err)
+	}
+	return nil
+}
+
+// FromServer 把Server适配为ServerV2, 如果server实现了Readier则保留;
+// 否则Start返回时视为就绪, Start阻塞(例如直接调用ListenAndServe)超过100ms时也视为就绪
+func FromServer(server Server) ServerV2 {
+	if readier, ok := server.(Readier); ok {
+		return &readyServerAdapter{serverAdapter: serverAdapter{server: server}, readier: readier}
+	}
+	return &serverAdapter{server: server, ready: make(chan struct{})}
+}
+
+// ToServer 把ServerV2适配为Server, 例如用于NewCtrlC
+func ToServer(server ServerV2) Server {
+	return &serverV2Adapter{server: server}
+}
+
+// readyServerAdapter 实现了Readier的Server的适配器
+type readyServerAdapter struct {
+	serverAdapter
+	readier Readier
+}
+
+func (s *readyServerAdapter) Ready() <-chan struct{} {
+	return s.readier.Ready()
+}
+
+func (s *serverAdapter) Start(_ context.Context) error {
+	timer := time.AfterFunc(serverAdapterReadyDelay, s.markReady)
+	defer timer.Stop()
+	err := s.server.Start()
+	if err == nil {
+		s.markReady()
+	}
+	return err
+}
+
+// Ready 实现Readier接口
+func (s *serverAdapter) Ready() <-chan struct{} {
+	return s.ready
+}
+
+func (s *serverAdapter) markReady() {
+	if s.ready != nil {
+		s.readyOnce.Do(func() { close(s.ready) })
+	}
+}
+
+// Stop Server.Stop不支持context, 超时后不再等待其返回
+func (s *serverAdapter) Stop(ctx context.Context) error {
+	errCh := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				errCh <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		s.server.Stop()
+		errCh <- nil
+	}()
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *serverV2Adapter) Start() error {
+	return s.server.Start(context.Background())
+}
+
+func (s *serverV2Adapter) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultStopTimeout)
+	defer cancel()
+	if err := s.server.Stop(ctx); err != nil {
+		Logger().Sugar().Errorf("[GIN-PLUS] [ERROR] Stop %T: %v", s.server, err)
 	}
 }
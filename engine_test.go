@@ -1,11 +1,245 @@
 package ginplus
 
 import (
-	"github.com/gin-gonic/gin"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
 )
 
 func TestNewCtrlC(t *testing.T) {
 	ctrlC := NewCtrlC(New(gin.Default()))
 	ctrlC.Start()
 }
+
+type testServer struct {
+	name      string
+	startErr  error
+	startWait time.Duration
+	stopWait  time.Duration
+	mtx       *sync.Mutex
+	stopped   *[]string
+	// 不为nil时在Start中关闭
+	startedCh chan struct{}
+}
+
+func (s *testServer) Start() error {
+	if s.startedCh != nil {
+		close(s.startedCh)
+	}
+	time.Sleep(s.startWait)
+	return s.startErr
+}
+
+func (s *testServer) Stop() {
+	time.Sleep(s.stopWait)
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	*s.stopped = append(*s.stopped, s.name)
+}
+
+func TestApp_Run(t *testing.T) {
+	var (
+		mtx     sync.Mutex
+		stopped []string
+	)
+	newServer := func(name string, startErr error) *testServer {
+		return &testServer{name: name, startErr: startErr, mtx: &mtx, stopped: &stopped}
+	}
+
+	failed := newServer("b", errors.New("port in use"))
+	failed.startWait = 20 * time.Millisecond
+	app := NewApp(
+		WithAppSignals(),
+		WithAppServers(newServer("a", nil), failed, newServer("c", nil)),
+	)
+	err := app.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "port in use") {
+		t.Fatalf("want start error, got %v", err)
+	}
+	if got := strings.Join(stopped, ","); got != "c,b,a" {
+		t.Errorf("want services stopped in reverse order, got %s", got)
+	}
+
+	stopped = nil
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := NewApp(WithAppSignals(), WithAppServers(newServer("a", nil))).Run(ctx); err != nil {
+		t.Errorf("want no error on context cancel, got %v", err)
+	}
+	if len(stopped) != 1 {
+		t.Errorf("want service stopped on context cancel, got %v", stopped)
+	}
+}
+
+func TestApp_RunForceExit(t *testing.T) {
+	var mtx sync.Mutex
+	var stopped []string
+	exitCh := make(chan int, 1)
+	startedCh := make(chan struct{})
+	app := NewApp(
+		WithAppSignals(),
+		WithAppStopTimeout(time.Second),
+		WithAppExit(func(code int) { exitCh <- code }),
+		WithAppServers(&testServer{name: "slow", stopWait: 200 * time.Millisecond, mtx: &mtx, stopped: &stopped, startedCh: startedCh}),
+	)
+
+	// 服务启动后再发送信号, 否则服务不会启动, 也就没有需要等待的停止过程
+	go func() {
+		<-startedCh
+		app.signalChan <- os.Interrupt
+		app.signalChan <- os.Interrupt
+	}()
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case code := <-exitCh:
+		if code != 1 {
+			t.Errorf("want exit code 1, got %d", code)
+		}
+	default:
+		t.Error("want forced exit on second signal")
+	}
+}
+
+type testServerV2 struct {
+	name   string
+	ready  chan struct{}
+	events chan<- string
+}
+
+func (s *testServerV2) Start(_ context.Context) error {
+	s.events <- "start " + s.name
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		s.events <- "ready " + s.name
+		close(s.ready)
+	}()
+	return nil
+}
+
+func (s *testServerV2) Stop(_ context.Context) error {
+	s.events <- "stop " + s.name
+	return nil
+}
+
+func (s *testServerV2) Ready() <-chan struct{} {
+	return s.ready
+}
+
+// blockingServer Start阻塞到Stop被调用, 与直接调用ListenAndServe的服务一致
+type blockingServer struct {
+	stop chan struct{}
+}
+
+func (s *blockingServer) Start() error {
+	<-s.stop
+	return nil
+}
+
+func (s *blockingServer) Stop() {
+	close(s.stop)
+}
+
+func TestApp_RunBlockingServer(t *testing.T) {
+	events := make(chan string, 16)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	httpServer := &testServerV2{name: "http", ready: make(chan struct{}), events: events}
+	app := NewApp(
+		WithAppSignals(),
+		WithAppService("legacy", FromServer(&blockingServer{stop: make(chan struct{})})),
+		WithAppService("http", httpServer, "legacy"),
+	)
+	errCh := make(chan error, 1)
+	go func() { errCh <- app.Run(ctx) }()
+
+	// Start阻塞的服务也会就绪, 依赖它的服务可以启动
+	select {
+	case <-httpServer.ready:
+	case <-time.After(5 * time.Second):
+		t.Fatal("dependent service not started while the legacy server blocks in Start")
+	}
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// startOrderServer 记录Start和Stop调用, 用于检查取消后不再启动服务
+type startOrderServer struct {
+	started atomic.Bool
+	stopped atomic.Bool
+}
+
+func (s *startOrderServer) Start(_ context.Context) error {
+	s.started.Store(true)
+	return nil
+}
+
+func (s *startOrderServer) Stop(_ context.Context) error {
+	s.stopped.Store(true)
+	return nil
+}
+
+func TestApp_RunCanceledBeforeStart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	server := &startOrderServer{}
+	if err := NewApp(WithAppSignals(), WithAppService("a", server)).Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// 已取消时不再启动服务, 也不需要停止
+	if server.started.Load() || server.stopped.Load() {
+		t.Errorf("started = %v, stopped = %v", server.started.Load(), server.stopped.Load())
+	}
+}
+
+func TestApp_RunDependencyOrder(t *testing.T) {
+	events := make(chan string, 16)
+	newServer := func(name string) *testServerV2 {
+		return &testServerV2{name: name, ready: make(chan struct{}), events: events}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	httpServer := newServer("http")
+	app := NewApp(
+		WithAppSignals(),
+		WithAppService("http", httpServer, "queue", "db"),
+		WithAppService("queue", newServer("queue"), "db"),
+		WithAppService("db", newServer("db")),
+	)
+	// http最后就绪, 其ready在发送完"ready http"后关闭, 此时所有启动事件都已写入;
+	// 停止事件在Run返回前同步写入, 因此Run返回后可以安全关闭events
+	go func() {
+		<-httpServer.ready
+		cancel()
+	}()
+	if err := app.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	close(events)
+	var got []string
+	for event := range events {
+		got = append(got, event)
+	}
+	want := "start db,ready db,start queue,ready queue,start http,ready http,stop http,stop queue,stop db"
+	if strings.Join(got, ",") != want {
+		t.Errorf("want events %s, got %s", want, strings.Join(got, ","))
+	}
+
+	for _, app := range []*App{
+		NewApp(WithAppService("a", newServer("a"), "b"), WithAppService("b", newServer("b"), "a")),
+		NewApp(WithAppService("a", newServer("a"), "missing")),
+	} {
+		if err := app.Run(context.Background()); err == nil {
+			t.Error("want dependency error")
+		}
+	}
+}
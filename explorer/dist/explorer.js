@@ -0,0 +1,534 @@
+(function () {
+  'use strict';
+
+  const config = window.GRAPHQL_EXPLORER_CONFIG || {};
+  const endpoint = new URL(config.endpoint || '/graphql', location.href);
+  const storagePrefix = 'graphql-explorer:' + endpoint.pathname + ':';
+  const $ = (id) => document.getElementById(id);
+
+  const editors = {
+    query: $('query'),
+    variables: $('variables'),
+    headers: $('headers'),
+  };
+  const defaults = {
+    query: '# Ctrl+Enter to run, subscriptions use graphql-transport-ws with SSE fallback\n{\n  __typename\n}\n',
+    variables: '{}',
+    headers: '{}',
+  };
+  const runButton = $('run');
+  const operationSelect = $('operation');
+  const resultEl = $('result');
+  const statusEl = $('status');
+
+  let active = null;
+
+  // 编辑器
+
+  function load(key) {
+    try {
+      return localStorage.getItem(storagePrefix + key);
+    } catch (e) {
+      return null;
+    }
+  }
+
+  function save(key, value) {
+    try {
+      localStorage.setItem(storagePrefix + key, value);
+    } catch (e) {
+      // 隐私模式下localStorage不可用
+    }
+  }
+
+  Object.keys(editors).forEach((key) => {
+    const el = editors[key];
+    el.value = load(key) || defaults[key];
+    el.addEventListener('input', () => {
+      save(key, el.value);
+      if (key === 'query') {
+        updateOperations();
+      }
+    });
+    el.addEventListener('keydown', (e) => {
+      if ((e.ctrlKey || e.metaKey) && e.key === 'Enter') {
+        e.preventDefault();
+        run();
+      } else if (e.key === 'Tab' && !e.shiftKey) {
+        e.preventDefault();
+        const start = el.selectionStart;
+        el.setRangeText('  ', start, el.selectionEnd, 'end');
+        el.dispatchEvent(new Event('input'));
+      }
+    });
+  });
+
+  document.querySelectorAll('.tabs button').forEach((tab) => {
+    tab.addEventListener('click', () => {
+      document.querySelectorAll('.tabs button').forEach((t) => t.classList.toggle('active', t === tab));
+      editors.variables.hidden = tab.dataset.tab !== 'variables';
+      editors.headers.hidden = tab.dataset.tab !== 'headers';
+    });
+  });
+
+  $('prettify').addEventListener('click', () => {
+    ['variables', 'headers'].forEach((key) => {
+      try {
+        editors[key].value = JSON.stringify(parseJSON(key), null, 2);
+        save(key, editors[key].value);
+      } catch (e) {
+        setStatus(e.message);
+      }
+    });
+  });
+
+  function parseJSON(key) {
+    const text = editors[key].value.trim();
+    if (!text) {
+      return {};
+    }
+    try {
+      return JSON.parse(text);
+    } catch (e) {
+      throw new Error(key + ' is not valid JSON: ' + e.message);
+    }
+  }
+
+  // operations 扫描文档顶层定义的操作, 跳过注释、字符串和选择集
+  function operations(query) {
+    const ops = [];
+    let depth = 0;
+    let expectName = false;
+    for (let i = 0; i < query.length;) {
+      const c = query[i];
+      if (c === '#') {
+        while (i < query.length && query[i] !== '\n') i++;
+      } else if (c === '"') {
+        if (query.startsWith('"""', i)) {
+          const end = query.indexOf('"""', i + 3);
+          i = end < 0 ? query.length : end + 3;
+        } else {
+          for (i++; i < query.length && query[i] !== '"'; i++) {
+            if (query[i] === '\\') i++;
+          }
+          i++;
+        }
+      } else if (c === '{') {
+        if (depth === 0 && !expectName && (ops.length === 0 || ops[ops.length - 1].closed)) {
+          ops.push({ type: 'query', name: '', closed: false });
+        }
+        depth++;
+        expectName = false;
+        i++;
+      } else if (c === '}') {
+        depth--;
+        if (depth === 0 && ops.length) ops[ops.length - 1].closed = true;
+        i++;
+      } else if (/[_A-Za-z]/.test(c)) {
+        const start = i;
+        while (i < query.length && /[_0-9A-Za-z]/.test(query[i])) i++;
+        const word = query.slice(start, i);
+        if (depth > 0) continue;
+        if (expectName) {
+          ops[ops.length - 1].name = word;
+          expectName = false;
+        } else if (word === 'query' || word === 'mutation' || word === 'subscription') {
+          ops.push({ type: word, name: '', closed: false });
+          expectName = true;
+        } else if (word === 'fragment') {
+          ops.push({ type: 'fragment', name: '', closed: false });
+        }
+      } else {
+        if (!/\s|,/.test(c)) expectName = false;
+        i++;
+      }
+    }
+    return ops.filter((op) => op.type !== 'fragment');
+  }
+
+  function updateOperations() {
+    const ops = operations(editors.query.value);
+    const selected = operationSelect.value;
+    operationSelect.textContent = '';
+    ops.forEach((op) => {
+      const option = document.createElement('option');
+      option.value = op.name;
+      option.textContent = op.type + ' ' + (op.name || '(anonymous)');
+      option.selected = op.name === selected;
+      operationSelect.appendChild(option);
+    });
+    operationSelect.hidden = ops.length < 2;
+  }
+
+  // 执行
+
+  function setStatus(text) {
+    statusEl.textContent = text;
+  }
+
+  function setRunning(running) {
+    runButton.classList.toggle('running', running);
+    runButton.innerHTML = running ? '&#9632; Stop' : '&#9654; Run';
+  }
+
+  function show(value) {
+    resultEl.textContent = typeof value === 'string' ? value : JSON.stringify(value, null, 2);
+  }
+
+  function append(value) {
+    resultEl.textContent += JSON.stringify(value, null, 2) + '\n';
+    resultEl.scrollTop = resultEl.scrollHeight;
+  }
+
+  function stop() {
+    if (active) {
+      active.stop();
+      active = null;
+    }
+    setRunning(false);
+  }
+
+  function done(text) {
+    active = null;
+    setRunning(false);
+    if (text) setStatus(text);
+  }
+
+  function run() {
+    if (active) {
+      stop();
+      setStatus('Stopped');
+      return;
+    }
+    let variables;
+    let headers;
+    try {
+      variables = parseJSON('variables');
+      headers = parseJSON('headers');
+    } catch (e) {
+      setStatus(e.message);
+      return;
+    }
+    const ops = operations(editors.query.value);
+    const op = ops.find((o) => o.name === operationSelect.value) || ops[0];
+    const payload = { query: editors.query.value, variables: variables };
+    if (op && op.name) {
+      payload.operationName = op.name;
+    }
+    setRunning(true);
+    if (op && op.type === 'subscription') {
+      subscribe(payload, headers);
+    } else {
+      execute(payload, headers);
+    }
+  }
+
+  function execute(payload, headers) {
+    const controller = new AbortController();
+    const start = performance.now();
+    active = { stop: () => controller.abort() };
+    setStatus('Running...');
+    fetch(endpoint, {
+      method: 'POST',
+      headers: Object.assign({ 'Content-Type': 'application/json', Accept: 'application/json' }, headers),
+      body: JSON.stringify(payload),
+      credentials: 'include',
+      signal: controller.signal,
+    })
+      .then((response) => response.text().then((text) => {
+        try {
+          show(JSON.parse(text));
+        } catch (e) {
+          show(text);
+        }
+        done(response.status + ' ' + response.statusText + ' · ' + Math.round(performance.now() - start) + ' ms');
+      }))
+      .catch((e) => {
+        if (e.name !== 'AbortError') {
+          show(e.message);
+          done('Request failed');
+        }
+      });
+  }
+
+  // subscribe 使用graphql-transport-ws协议订阅, 请求头作为connection_init的payload, 无法建立连接时使用SSE
+  function subscribe(payload, headers) {
+    const url = new URL(endpoint);
+    url.protocol = url.protocol === 'https:' ? 'wss:' : 'ws:';
+    const ws = new WebSocket(url, 'graphql-transport-ws');
+    let acked = false;
+    let finished = false;
+    const finish = (text) => {
+      if (finished) return;
+      finished = true;
+      if (ws.readyState === WebSocket.OPEN || ws.readyState === WebSocket.CONNECTING) {
+        ws.close(1000);
+      }
+      done(text);
+    };
+    active = {
+      stop: () => {
+        if (ws.readyState === WebSocket.OPEN) {
+          ws.send(JSON.stringify({ id: '1', type: 'complete' }));
+        }
+        finished = true;
+        ws.close(1000);
+      },
+    };
+    resultEl.textContent = '';
+    setStatus('Connecting...');
+
+    ws.onopen = () => ws.send(JSON.stringify({ type: 'connection_init', payload: headers }));
+    ws.onmessage = (event) => {
+      const msg = JSON.parse(event.data);
+      switch (msg.type) {
+        case 'connection_ack':
+          acked = true;
+          ws.send(JSON.stringify({ id: '1', type: 'subscribe', payload: payload }));
+          setStatus('Subscribed via WebSocket');
+          break;
+        case 'ping':
+          ws.send(JSON.stringify({ type: 'pong' }));
+          break;
+        case 'next':
+          append(msg.payload);
+          break;
+        case 'error':
+          append({ errors: msg.payload });
+          finish('Subscription failed');
+          break;
+        case 'complete':
+          finish('Subscription complete');
+          break;
+      }
+    };
+    ws.onclose = (event) => {
+      if (finished) return;
+      if (!acked && event.code === 1006) {
+        finished = true;
+        subscribeSSE(payload, headers);
+        return;
+      }
+      finish('Connection closed: ' + event.code + (event.reason ? ' ' + event.reason : ''));
+    };
+  }
+
+  async function subscribeSSE(payload, headers) {
+    const controller = new AbortController();
+    active = { stop: () => controller.abort() };
+    setStatus('Subscribed via SSE');
+    try {
+      const response = await fetch(endpoint, {
+        method: 'POST',
+        headers: Object.assign({ 'Content-Type': 'application/json', Accept: 'text/event-stream' }, headers),
+        body: JSON.stringify(payload),
+        credentials: 'include',
+        signal: controller.signal,
+      });
+      if (!(response.headers.get('Content-Type') || '').startsWith('text/event-stream')) {
+        const text = await response.text();
+        try {
+          show(JSON.parse(text));
+        } catch (e) {
+          show(text);
+        }
+        done(response.status + ' ' + response.statusText);
+        return;
+      }
+      const reader = response.body.getReader();
+      const decoder = new TextDecoder();
+      let buffer = '';
+      for (;;) {
+        const { value, done: eof } = await reader.read();
+        if (eof) break;
+        buffer += decoder.decode(value, { stream: true });
+        let index;
+        while ((index = buffer.indexOf('\n\n')) >= 0) {
+          const chunk = buffer.slice(0, index);
+          buffer = buffer.slice(index + 2);
+          let type = 'message';
+          const data = [];
+          chunk.split('\n').forEach((line) => {
+            if (line.startsWith('event:')) type = line.slice(6).trim();
+            else if (line.startsWith('data:')) data.push(line.slice(5).trim());
+          });
+          if (type === 'next' && data.length) {
+            append(JSON.parse(data.join('\n')));
+          }
+        }
+      }
+      done('Subscription complete');
+    } catch (e) {
+      if (e.name !== 'AbortError') {
+        show(e.message);
+        done('Subscription failed');
+      }
+    }
+  }
+
+  // 文档
+
+  const introspectionQuery = `query IntrospectionQuery {
+  __schema {
+    queryType { name }
+    mutationType { name }
+    subscriptionType { name }
+    types {
+      kind name description
+      fields(includeDeprecated: true) { name description args { name defaultValue type { ...TypeRef } } type { ...TypeRef } }
+      inputFields { name defaultValue type { ...TypeRef } }
+      enumValues(includeDeprecated: true) { name description }
+      possibleTypes { name }
+    }
+  }
+}
+fragment TypeRef on __Type { kind name ofType { kind name ofType { kind name ofType { kind name ofType { kind name } } } } }`;
+
+  const docs = $('docs');
+  const docsContent = $('docs-content');
+  const docsBack = $('docs-back');
+  const docsSearch = $('docs-search');
+  let schema = null;
+  const history = [];
+
+  $('docs-toggle').addEventListener('click', () => {
+    docs.hidden = !docs.hidden;
+    if (!docs.hidden && !schema) {
+      loadSchema();
+    }
+  });
+  docsBack.addEventListener('click', () => {
+    history.pop();
+    renderDocs(history[history.length - 1]);
+  });
+  docsSearch.addEventListener('input', () => renderDocs(history[history.length - 1]));
+
+  function loadSchema() {
+    let headers = {};
+    try {
+      headers = parseJSON('headers');
+    } catch (e) {
+      // 请求头无效时不带请求头加载
+    }
+    docsContent.textContent = 'Loading...';
+    fetch(endpoint, {
+      method: 'POST',
+      headers: Object.assign({ 'Content-Type': 'application/json', Accept: 'application/json' }, headers),
+      body: JSON.stringify({ query: introspectionQuery, operationName: 'IntrospectionQuery' }),
+      credentials: 'include',
+    })
+      .then((response) => response.json())
+      .then((result) => {
+        if (!result.data) {
+          docsContent.textContent = (result.errors || []).map((e) => e.message).join('\n') || 'Introspection failed';
+          return;
+        }
+        schema = result.data.__schema;
+        renderDocs();
+      })
+      .catch((e) => {
+        docsContent.textContent = e.message;
+      });
+  }
+
+  function el(tag, text, className) {
+    const node = document.createElement(tag);
+    if (text) node.textContent = text;
+    if (className) node.className = className;
+    return node;
+  }
+
+  function typeLink(name) {
+    const link = el('a', name);
+    link.addEventListener('click', () => {
+      history.push(name);
+      docsSearch.value = '';
+      renderDocs(name);
+    });
+    return link;
+  }
+
+  // typeRef 渲染带有非空和列表包装的类型
+  function typeRef(ref) {
+    const span = el('span');
+    if (ref.kind === 'NON_NULL') {
+      span.appendChild(typeRef(ref.ofType));
+      span.appendChild(document.createTextNode('!'));
+    } else if (ref.kind === 'LIST') {
+      span.appendChild(document.createTextNode('['));
+      span.appendChild(typeRef(ref.ofType));
+      span.appendChild(document.createTextNode(']'));
+    } else {
+      span.appendChild(typeLink(ref.name));
+    }
+    return span;
+  }
+
+  function renderDocs(name) {
+    docsContent.textContent = '';
+    docsBack.hidden = history.length === 0;
+    if (!schema) return;
+    const filter = docsSearch.value.trim().toLowerCase();
+    const type = name && schema.types.find((t) => t.name === name);
+    if (!type) {
+      const roots = [schema.queryType, schema.mutationType, schema.subscriptionType].filter(Boolean);
+      docsContent.appendChild(el('h3', 'Root types'));
+      roots.forEach((root) => {
+        const row = el('div', '', 'field');
+        row.appendChild(typeLink(root.name));
+        docsContent.appendChild(row);
+      });
+      docsContent.appendChild(el('h3', 'All types'));
+      schema.types
+        .filter((t) => !t.name.startsWith('__') && t.name.toLowerCase().includes(filter))
+        .sort((a, b) => a.name.localeCompare(b.name))
+        .forEach((t) => {
+          const row = el('div', '', 'field');
+          row.appendChild(typeLink(t.name));
+          row.appendChild(document.createTextNode(' ' + t.kind.toLowerCase()));
+          docsContent.appendChild(row);
+        });
+      return;
+    }
+
+    docsContent.appendChild(el('h3', type.name + ' (' + type.kind.toLowerCase() + ')'));
+    if (type.description) {
+      docsContent.appendChild(el('p', type.description, 'description'));
+    }
+    (type.fields || type.inputFields || [])
+      .filter((f) => f.name.toLowerCase().includes(filter))
+      .forEach((f) => {
+        const row = el('div', '', 'field');
+        row.appendChild(document.createTextNode(f.name));
+        if (f.args && f.args.length) {
+          row.appendChild(document.createTextNode('('));
+          f.args.forEach((arg, i) => {
+            row.appendChild(document.createTextNode((i ? ', ' : '') + arg.name + ': '));
+            row.appendChild(typeRef(arg.type));
+            if (arg.defaultValue != null) {
+              row.appendChild(document.createTextNode(' = ' + arg.defaultValue));
+            }
+          });
+          row.appendChild(document.createTextNode(')'));
+        }
+        row.appendChild(document.createTextNode(': '));
+        row.appendChild(typeRef(f.type));
+        if (f.description) {
+          row.appendChild(el('div', f.description, 'description'));
+        }
+        docsContent.appendChild(row);
+      });
+    (type.enumValues || []).forEach((v) => docsContent.appendChild(el('div', v.name, 'field')));
+    (type.possibleTypes || []).forEach((t) => {
+      const row = el('div', '', 'field');
+      row.appendChild(typeLink(t.name));
+      docsContent.appendChild(row);
+    });
+  }
+
+  runButton.addEventListener('click', run);
+  operationSelect.addEventListener('change', () => save('operation', operationSelect.value));
+  $('endpoint').textContent = endpoint.href;
+  updateOperations();
+  operationSelect.value = load('operation') || operationSelect.value;
+})();
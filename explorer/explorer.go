@@ -0,0 +1,10 @@
+package explorer
+
+import (
+	"embed"
+)
+
+// Dist 内置的轻量GraphQL调试页面(GraphQL Explorer)的静态资源, 并非GraphiQL, 不依赖外部CDN, 可以在离线环境中使用
+//
+//go:embed dist/*
+var Dist embed.FS
@@ -0,0 +1,258 @@
+package ginplus
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// 告诉编译器这个结构体实现了gorm.Plugin接口
+var _ gorm.Plugin = (*OpentracingPlugin)(nil)
+
+const (
+	gormSpanKey      = "__gorm_span"
+	gormTime         = "__gorm_time"
+	gormOperationKey = "__gorm_operation"
+)
+
+const (
+	callBackBeforeName = "opentracing:before"
+	callBackAfterName  = "opentracing:after"
+)
+
+const (
+	gormOperationInsert = "INSERT"
+	gormOperationSelect = "SELECT"
+	gormOperationUpdate = "UPDATE"
+	gormOperationDelete = "DELETE"
+)
+
+// OpentracingPlugin gorm链路追踪和指标插件, 零值可直接使用
+type OpentracingPlugin struct {
+	// 为空时使用父span所属的TracerProvider, 没有父span时使用otel全局TracerProvider
+	provider oteltrace.TracerProvider
+	// 是否把参数值内联到sql中, 可能泄露敏感数据, 默认关闭
+	inlineParams bool
+	// 指标注册器, 为空时使用prometheus.DefaultRegisterer
+	registerer prometheus.Registerer
+	// 查询耗时直方图的桶
+	buckets []float64
+	// 是否关闭指标
+	disableMetrics bool
+
+	duration *prometheus.HistogramVec
+}
+
+// OpentracingOption gorm插件配置
+type OpentracingOption func(*OpentracingPlugin)
+
+// NewOpentracingPlugin 创建一个opentracing插件
+func NewOpentracingPlugin(opts ...OpentracingOption) *OpentracingPlugin {
+	op := &OpentracingPlugin{}
+	for _, opt := range opts {
+		opt(op)
+	}
+	return op
+}
+
+// WithGormTracerProvider 设置TracerProvider
+func WithGormTracerProvider(tp oteltrace.TracerProvider) OpentracingOption {
+	return func(op *OpentracingPlugin) {
+		op.provider = tp
+	}
+}
+
+// WithGormInlineParams 把参数值内联到db.statement中, 可能泄露敏感数据
+func WithGormInlineParams() OpentracingOption {
+	return func(op *OpentracingPlugin) {
+		op.inlineParams = true
+	}
+}
+
+// WithGormRegisterer 设置指标注册器
+func WithGormRegisterer(registerer prometheus.Registerer) OpentracingOption {
+	return func(op *OpentracingPlugin) {
+		op.registerer = registerer
+	}
+}
+
+// WithGormHistogramBuckets 设置查询耗时直方图的桶, 单位为秒
+func WithGormHistogramBuckets(buckets []float64) OpentracingOption {
+	return func(op *OpentracingPlugin) {
+		op.buckets = buckets
+	}
+}
+
+// WithGormDisableMetrics 关闭查询耗时指标
+func WithGormDisableMetrics() OpentracingOption {
+	return func(op *OpentracingPlugin) {
+		op.disableMetrics = true
+	}
+}
+
+func (op *OpentracingPlugin) Name() string {
+	return "opentracingPlugin"
+}
+
+func (op *OpentracingPlugin) Initialize(db *gorm.DB) (err error) {
+	if err = op.initMetrics(); err != nil {
+		return err
+	}
+
+	cb := db.Callback()
+	// 开始前
+	if err = cb.Create().Before("gorm:before_create").Register(callBackBeforeName, op.before(gormOperationInsert)); err != nil {
+		return err
+	}
+	if err = cb.Query().Before("gorm:query").Register(callBackBeforeName, op.before(gormOperationSelect)); err != nil {
+		return err
+	}
+	if err = cb.Delete().Before("gorm:before_delete").Register(callBackBeforeName, op.before(gormOperationDelete)); err != nil {
+		return err
+	}
+	if err = cb.Update().Before("gorm:before_update").Register(callBackBeforeName, op.before(gormOperationUpdate)); err != nil {
+		return err
+	}
+	if err = cb.Row().Before("gorm:row").Register(callBackBeforeName, op.before("")); err != nil {
+		return err
+	}
+	if err = cb.Raw().Before("gorm:raw").Register(callBackBeforeName, op.before("")); err != nil {
+		return err
+	}
+
+	// 结束后
+	if err = cb.Create().After("gorm:after_create").Register(callBackAfterName, op.after); err != nil {
+		return err
+	}
+	if err = cb.Query().After("gorm:after_query").Register(callBackAfterName, op.after); err != nil {
+		return err
+	}
+	if err = cb.Delete().After("gorm:after_delete").Register(callBackAfterName, op.after); err != nil {
+		return err
+	}
+	if err = cb.Update().After("gorm:after_update").Register(callBackAfterName, op.after); err != nil {
+		return err
+	}
+	if err = cb.Row().After("gorm:row").Register(callBackAfterName, op.after); err != nil {
+		return err
+	}
+	if err = cb.Raw().After("gorm:raw").Register(callBackAfterName, op.after); err != nil {
+		return err
+	}
+	return
+}
+
+// initMetrics 注册查询耗时直方图, 已注册时复用已有的指标
+func (op *OpentracingPlugin) initMetrics() error {
+	if op.disableMetrics {
+		return nil
+	}
+	registerer := op.registerer
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+	buckets := op.buckets
+	if len(buckets) == 0 {
+		buckets = prometheus.DefBuckets
+	}
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gorm_query_duration_seconds",
+		Help:    "Duration of gorm queries in seconds.",
+		Buckets: buckets,
+	}, []string{"db_system", "operation", "table", "status"})
+	duration, err := registerCollector(registerer, duration)
+	if err != nil {
+		return err
+	}
+	op.duration = duration
+	return nil
+}
+
+func (op *OpentracingPlugin) before(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		// raw和row在执行后才能从sql中得到操作类型, 在after中重命名
+		ctx, span := spanProvider(ctx, op.provider).Tracer("gorm").Start(ctx, gormSpanName(operation, db.Statement.Table),
+			oteltrace.WithSpanKind(oteltrace.SpanKindClient),
+			oteltrace.WithAttributes(semconv.DBSystemKey.String(db.Dialector.Name())),
+		)
+		// 利用db实例去传递span
+		db.InstanceSet(gormSpanKey, span)
+		db.InstanceSet(gormTime, time.Now())
+		db.InstanceSet(gormOperationKey, operation)
+		// 子context继续向下传递
+		db.Statement.Context = ctx
+	}
+}
+
+func (op *OpentracingPlugin) after(db *gorm.DB) {
+	// 从GORM的DB实例中取出span
+	_span, isExist := db.InstanceGet(gormSpanKey)
+	if !isExist {
+		return
+	}
+	span, ok := _span.(oteltrace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	sql := db.Statement.SQL.String()
+	operation := gormOperation(sql)
+	if operation == "" {
+		_operation, _ := db.InstanceGet(gormOperationKey)
+		operation, _ = _operation.(string)
+	}
+	table := db.Statement.Table
+	span.SetName(gormSpanName(operation, table))
+
+	statement := sql
+	if op.inlineParams {
+		statement = db.Dialector.Explain(sql, db.Statement.Vars...)
+	}
+	span.SetAttributes(
+		semconv.DBStatement(statement),
+		semconv.DBOperation(operation),
+		semconv.DBSQLTable(table),
+		attribute.Int64("db.rows_affected", db.RowsAffected),
+	)
+
+	status := "ok"
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		status = "error"
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+
+	_time, isExist := db.InstanceGet(gormTime)
+	if !isExist || op.duration == nil {
+		return
+	}
+	if startTime, ok := _time.(time.Time); ok {
+		op.duration.WithLabelValues(db.Dialector.Name(), operation, table, status).Observe(time.Since(startTime).Seconds())
+	}
+}
+
+// gormOperation 取sql的第一个关键字作为操作类型
+func gormOperation(sql string) string {
+	sql = strings.TrimSpace(sql)
+	if i := strings.IndexAny(sql, " \t\n("); i > 0 {
+		sql = sql[:i]
+	}
+	return strings.ToUpper(sql)
+}
+
+func gormSpanName(operation, table string) string {
+	name := strings.TrimSpace(operation + " " + table)
+	if name == "" {
+		return "gorm"
+	}
+	return name
+}
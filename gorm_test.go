@@ -0,0 +1,85 @@
+package ginplus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+type gormUser struct {
+	Id       uint
+	Password string
+}
+
+func TestOpentracingPlugin(t *testing.T) {
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		DSN:                       "root:12345678@tcp(localhost:3306)/test",
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := tracesdk.NewTracerProvider(tracesdk.WithSpanProcessor(recorder))
+	registry := prometheus.NewRegistry()
+	if err := db.Use(NewOpentracingPlugin(WithGormRegisterer(registry))); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+	db.WithContext(ctx).Table("users").Where("password = ?", "secret").Find(&[]gormUser{})
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("want 2 spans, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "SELECT users" {
+		t.Errorf("unexpected span name %q", span.Name())
+	}
+	if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Error("gorm span is not a child of the request span")
+	}
+	for _, attr := range span.Attributes() {
+		if attr.Key == semconv.DBStatementKey && attr.Value.AsString() != "SELECT * FROM `users` WHERE password = ?" {
+			t.Errorf("unexpected db.statement %q", attr.Value.AsString())
+		}
+	}
+	if n := testutil.CollectAndCount(registry, "gorm_query_duration_seconds"); n != 1 {
+		t.Errorf("want 1 histogram series, got %d", n)
+	}
+}
+
+func TestOpentracingPlugin_GlobalProvider(t *testing.T) {
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		DSN:                       "root:12345678@tcp(localhost:3306)/test",
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(NewOpentracingPlugin(WithGormDisableMetrics())); err != nil {
+		t.Fatal(err)
+	}
+
+	// 没有父span时使用全局TracerProvider
+	recorder := tracetest.NewSpanRecorder()
+	global := otel.GetTracerProvider()
+	otel.SetTracerProvider(tracesdk.NewTracerProvider(tracesdk.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(global)
+
+	db.WithContext(context.Background()).Table("users").Find(&[]gormUser{})
+	if spans := recorder.Ended(); len(spans) != 1 || spans[0].Name() != "SELECT users" {
+		t.Errorf("want query traced by the global provider, got %v", spans)
+	}
+}
@@ -0,0 +1,200 @@
+package ginplus
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	// defaultGraphqlMaxBatchSize 批量查询的默认最大数量
+	defaultGraphqlMaxBatchSize = 10
+	// defaultPersistedQueryCacheSize 持久化查询LRU缓存的默认大小
+	defaultPersistedQueryCacheSize = 1000
+
+	operationQuery        = "query"
+	operationMutation     = "mutation"
+	operationSubscription = "subscription"
+)
+
+var (
+	errPersistedQueryNotFound  = errors.New("PersistedQueryNotFound")
+	errPersistedQueryMismatch  = errors.New("provided sha does not match query")
+	errPersistedQueryVersion   = errors.New("unsupported persisted query version")
+	errGraphqlOperationOverGet = errors.New("only query operations are allowed over GET")
+	errGraphqlSSEOverGet       = errors.New("only query and subscription operations are allowed over GET")
+	errGraphqlEmptyBatch       = errors.New("empty batch")
+	errGraphqlBatchTooLarge    = errors.New("batch size exceeds the limit")
+	errGraphqlMissingQueryText = errors.New("missing query")
+)
+
+type (
+	// PersistedQueryStore 持久化查询(APQ)存储, 以查询的sha256哈希为key
+	PersistedQueryStore interface {
+		// Get 获取哈希对应的查询
+		Get(ctx context.Context, hash string) (query string, ok bool)
+		// Set 保存查询
+		Set(ctx context.Context, hash string, query string)
+	}
+
+	// lruPersistedQueryStore 基于LRU的内存持久化查询存储
+	lruPersistedQueryStore struct {
+		size int
+
+		mtx   sync.Mutex
+		ll    *list.List
+		items map[string]*list.Element
+	}
+
+	lruEntry struct {
+		hash  string
+		query string
+	}
+
+	// graphqlParams graphql请求参数
+	graphqlParams struct {
+		Query         string         `json:"query"`
+		OperationName string         `json:"operationName"`
+		Variables     map[string]any `json:"variables"`
+		Extensions    struct {
+			PersistedQuery *struct {
+				Version    int    `json:"version"`
+				Sha256Hash string `json:"sha256Hash"`
+			} `json:"persistedQuery"`
+		} `json:"extensions"`
+	}
+)
+
+// NewLRUPersistedQueryStore 创建基于LRU的内存持久化查询存储, size小于等于0时使用默认大小1000
+func NewLRUPersistedQueryStore(size int) PersistedQueryStore {
+	if size <= 0 {
+		size = defaultPersistedQueryCacheSize
+	}
+	return &lruPersistedQueryStore{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[string]*list.Element, size),
+	}
+}
+
+func (s *lruPersistedQueryStore) Get(_ context.Context, hash string) (string, bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	e, ok := s.items[hash]
+	if !ok {
+		return "", false
+	}
+	s.ll.MoveToFront(e)
+	return e.Value.(*lruEntry).query, true
+}
+
+func (s *lruPersistedQueryStore) Set(_ context.Context, hash string, query string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if e, ok := s.items[hash]; ok {
+		s.ll.MoveToFront(e)
+		e.Value.(*lruEntry).query = query
+		return
+	}
+	s.items[hash] = s.ll.PushFront(&lruEntry{hash: hash, query: query})
+	if s.ll.Len() > s.size {
+		oldest := s.ll.Back()
+		s.ll.Remove(oldest)
+		delete(s.items, oldest.Value.(*lruEntry).hash)
+	}
+}
+
+// paramsFromQuery 解析GET请求的参数, variables和extensions为JSON字符串
+func paramsFromQuery(r *http.Request) (graphqlParams, error) {
+	values := r.URL.Query()
+	params := graphqlParams{
+		Query:         values.Get("query"),
+		OperationName: values.Get("operationName"),
+	}
+	if variables := values.Get("variables"); variables != "" {
+		if err := json.Unmarshal([]byte(variables), &params.Variables); err != nil {
+			return params, err
+		}
+	}
+	if extensions := values.Get("extensions"); extensions != "" {
+		if err := json.Unmarshal([]byte(extensions), &params.Extensions); err != nil {
+			return params, err
+		}
+	}
+	return params, nil
+}
+
+// resolvePersistedQuery 处理持久化查询: 只有哈希时从存储中读取查询, 同时有查询和哈希时校验后保存
+func resolvePersistedQuery(ctx context.Context, store PersistedQueryStore, params *graphqlParams) error {
+	pq := params.Extensions.PersistedQuery
+	if pq == nil || store == nil {
+		if params.Query == "" {
+			return errGraphqlMissingQueryText
+		}
+		return nil
+	}
+	if pq.Version != 1 {
+		return errPersistedQueryVersion
+	}
+	if params.Query == "" {
+		query, ok := store.Get(ctx, pq.Sha256Hash)
+		if !ok {
+			return errPersistedQueryNotFound
+		}
+		params.Query = query
+		return nil
+	}
+	sum := sha256.Sum256([]byte(params.Query))
+	if hex.EncodeToString(sum[:]) != strings.ToLower(pq.Sha256Hash) {
+		return errPersistedQueryMismatch
+	}
+	store.Set(ctx, pq.Sha256Hash, params.Query)
+	return nil
+}
+
+// operationType 返回将要执行的操作类型, 与深度和复杂度检查使用同一个解析器, 无法解析或无法确定操作时返回空字符串
+func operationType(query, operationName string) string {
+	doc, err := parseQueryDocument(query)
+	if err != nil {
+		return ""
+	}
+	op := doc.operation(operationName)
+	if op == nil {
+		return ""
+	}
+	return op.typ
+}
+
+// skipString 跳过字符串和块字符串, 返回字符串之后的位置
+func skipString(query string, i int) int {
+	if strings.HasPrefix(query[i:], `"""`) {
+		end := strings.Index(query[i+3:], `"""`)
+		if end < 0 {
+			return len(query)
+		}
+		return i + 3 + end + 3
+	}
+	for i++; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return len(query)
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
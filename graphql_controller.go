@@ -0,0 +1,594 @@
+package ginplus
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/graph-gophers/graphql-go"
+)
+
+var (
+	jsonUnmarshalerType   = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	graphqlJSONType       = reflect.TypeOf(GraphqlJSON{})
+	errNoControllers      = errors.New("no controllers to generate graphql schema from")
+	errControllerResolver = errors.New("graphql Controllers requires Root generated by GenerateGraphqlResolver")
+
+	// taggedFields 结构体中graphql字段名对应的字段下标
+	taggedFields sync.Map
+)
+
+type (
+	// ControllerResolver GenerateGraphqlResolver生成的根节点嵌入该类型, RegisterGraphql时绑定controller
+	ControllerResolver struct {
+		router *controllerRouter
+	}
+
+	// GraphqlJSON controller中map和interface类型的字段对应的JSON标量
+	GraphqlJSON struct {
+		Value any
+	}
+
+	// controllerResolver 由嵌入ControllerResolver的根节点实现
+	controllerResolver interface {
+		bindControllers(router *controllerRouter)
+	}
+
+	// controllerRouter 与REST接口相同的路由和处理链, 用于执行controller生成的graphql字段
+	controllerRouter struct {
+		sdl    string
+		router *gin.Engine
+		// 根类型名.字段名 -> 回调方法
+		fields map[string]*controllerField
+	}
+
+	// controllerField controller回调方法生成的根字段
+	controllerField struct {
+		controller reflect.Value
+		method     reflect.Method
+		req        reflect.Type
+		resp       reflect.Type
+		httpMethod string
+		// 包含uri参数的完整路由
+		path string
+	}
+
+	// controllerCall 单次字段调用的结果, 由GinEngine.response记录
+	controllerCall struct {
+		done bool
+		resp any
+		err  error
+	}
+
+	controllerCallContextKey struct{}
+
+	// controllerResponseWriter 记录处理链的输出, 没有记录结果时用于返回错误
+	controllerResponseWriter struct {
+		header http.Header
+		status int
+		body   bytes.Buffer
+	}
+)
+
+// ImplementsGraphQLType 实现graphql-go的自定义标量
+func (GraphqlJSON) ImplementsGraphQLType(name string) bool {
+	return name == "JSON"
+}
+
+// UnmarshalGraphQL 保存参数的原始值
+func (j *GraphqlJSON) UnmarshalGraphQL(input any) error {
+	j.Value = input
+	return nil
+}
+
+func (j GraphqlJSON) MarshalJSON() ([]byte, error) {
+	return json.Marshal(j.Value)
+}
+
+// newControllerHandler 根据WithControllers注册的controller生成schema, root为GenerateGraphqlResolver生成的根节点,
+// 回调方法中路由为GET的作为Query字段, 其余作为Mutation字段, 字段名为方法名的lowerCamel形式
+func (l *GinEngine) newControllerHandler(root any, opts ...GraphqlHandlerOption) (*GraphqlHandler, error) {
+	resolver, ok := root.(controllerResolver)
+	if !ok {
+		return nil, errControllerResolver
+	}
+	router, err := l.controllerRouter()
+	if err != nil {
+		return nil, err
+	}
+	h, err := NewHandler(root, router.sdl, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%w, regenerate the resolver with GenerateGraphqlResolver", err)
+	}
+	resolver.bindControllers(router)
+	return h, nil
+}
+
+// controllerRouter 通过genRoute在独立的gin.Engine上注册controller, 处理链与REST接口相同,
+// 同时根据回调方法的请求和响应结构体生成schema
+func (l *GinEngine) controllerRouter() (*controllerRouter, error) {
+	if len(l.controllers) == 0 {
+		return nil, errNoControllers
+	}
+	c := &controllerRouter{router: gin.New(), fields: make(map[string]*controllerField)}
+	// controller中的context.Context可以取得graphql请求的值和超时
+	c.router.ContextWithFallback = true
+
+	g := newSchemaGenerator()
+	g.tagged = true
+	var (
+		query, mutation []string
+		genErr          error
+	)
+	routes := &GinEngine{
+		Engine:              c.router,
+		httpMethodPrefixes:  l.httpMethodPrefixes,
+		routeNamingRuleFunc: l.routeNamingRuleFunc,
+		defaultHandler:      l.defaultHandler,
+		apiRoutes:           make(map[string][]ApiRoute),
+	}
+	routes.onCallback = func(controller any, method reflect.Method, req, resp reflect.Type, httpMethod, fullPath string) {
+		if genErr != nil {
+			return
+		}
+		t := reflect.TypeOf(controller)
+		def, err := g.controllerField(method.Name, req, resp)
+		if err != nil {
+			genErr = fmt.Errorf("%s.%s: %w", t, method.Name, err)
+			return
+		}
+		typeName, defs := "Query", &query
+		if httpMethod != http.MethodGet {
+			typeName, defs = "Mutation", &mutation
+		}
+		key := typeName + "." + lowerCamel(method.Name)
+		if other, ok := c.fields[key]; ok {
+			genErr = fmt.Errorf("graphql field %s is defined by both %s.%s and %s.%s",
+				key, other.controller.Type(), other.method.Name, t, method.Name)
+			return
+		}
+		c.fields[key] = &controllerField{
+			controller: reflect.ValueOf(controller),
+			method:     method,
+			req:        req,
+			resp:       resp,
+			httpMethod: httpMethod,
+			path:       fullPath,
+		}
+		*defs = append(*defs, def)
+	}
+	for _, controller := range l.controllers {
+		routes.genRoute(nil, controller, false)
+	}
+	if genErr != nil {
+		return nil, genErr
+	}
+	sdl, err := g.print(query, mutation, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.sdl = sdl
+	return c, nil
+}
+
+// controllerField 请求结构体的字段作为参数, 响应作为字段类型
+func (g *schemaGenerator) controllerField(name string, req, resp reflect.Type) (string, error) {
+	reqType := req
+	if reqType.Kind() == reflect.Ptr {
+		reqType = reqType.Elem()
+	}
+	if reqType.Kind() != reflect.Struct {
+		return "", fmt.Errorf("request %s is not a struct", req)
+	}
+	args, err := g.arguments(reqType)
+	if err != nil {
+		return "", err
+	}
+	typ, err := g.outputType(resp)
+	if err != nil {
+		return "", err
+	}
+	def := lowerCamel(name)
+	if len(args) > 0 {
+		def += "(" + strings.Join(args, ", ") + ")"
+	}
+	return def + ": " + typ, nil
+}
+
+func (r *ControllerResolver) bindControllers(router *controllerRouter) {
+	r.router = router
+}
+
+// ResolveController 由生成的resolver调用, 把参数转换为REST请求, 经过controller的中间件、Bind和defaultHandler执行回调方法,
+// 回调方法的返回值写入out. 使用WithDefaultHandler时, 处理函数没有通过defaultResponse输出时, 输出的JSON作为字段的值
+func (r *ControllerResolver) ResolveController(ctx context.Context, typeName, fieldName string, args, out any) error {
+	if r.router == nil {
+		return fmt.Errorf("graphql field %s.%s: resolver is not registered by RegisterGraphql", typeName, fieldName)
+	}
+	f, ok := r.router.fields[typeName+"."+fieldName]
+	if !ok {
+		return fmt.Errorf("graphql field %s.%s is not served by a controller, regenerate the resolver", typeName, fieldName)
+	}
+	values := make(map[string]any)
+	if args != nil {
+		b, err := json.Marshal(args)
+		if err != nil {
+			return err
+		}
+		decoder := json.NewDecoder(bytes.NewReader(b))
+		decoder.UseNumber()
+		if err := decoder.Decode(&values); err != nil {
+			return err
+		}
+	}
+
+	call := &controllerCall{}
+	req, err := f.request(context.WithValue(ctx, controllerCallContextKey{}, call), values)
+	if err != nil {
+		return err
+	}
+	w := &controllerResponseWriter{header: make(http.Header)}
+	r.router.router.ServeHTTP(w, req)
+
+	result := reflect.ValueOf(out).Elem()
+	switch {
+	case call.done && call.err != nil:
+		return call.err
+	case call.done:
+		return convertOutput(result, reflect.ValueOf(call.resp))
+	case w.status >= http.StatusBadRequest:
+		return w.err()
+	case w.body.Len() == 0:
+		return fmt.Errorf("%s %s: handler returned no result", f.httpMethod, f.path)
+	}
+	resp := reflect.New(f.resp)
+	if err := json.Unmarshal(w.body.Bytes(), resp.Interface()); err != nil {
+		return err
+	}
+	return convertOutput(result, resp.Elem())
+}
+
+// request 按Bind的规则生成REST请求: uri参数写入路径, header标签写入请求头,
+// form标签以及GET请求的参数写入query, 其余参数作为JSON请求体
+func (f *controllerField) request(ctx context.Context, args map[string]any) (*http.Request, error) {
+	reqType := f.req
+	for reqType.Kind() == reflect.Ptr {
+		reqType = reqType.Elem()
+	}
+	var (
+		params = make(map[string]string)
+		query  = make(url.Values)
+		header = make(http.Header)
+		body   = make(map[string]any)
+	)
+	for _, sf := range exportedFields(reqType) {
+		name := taggedFieldName(sf)
+		value, ok := args[name]
+		if name == "" || !ok || value == nil {
+			continue
+		}
+		tag := fieldTag(sf)
+		uriKey, _, _ := strings.Cut(tag.UriKey, ",")
+		formKey, _, _ := strings.Cut(tag.FormKey, ",")
+		headerKey, _, _ := strings.Cut(sf.Tag.Get("header"), ",")
+		switch {
+		case uriKey != "" && uriKey != "-":
+			s, err := formValues(value)
+			if err != nil || len(s) != 1 {
+				return nil, fmt.Errorf("argument %s: uri parameter must be a single value", name)
+			}
+			params[uriKey] = s[0]
+			continue
+		case headerKey != "" && headerKey != "-":
+			s, err := formValues(value)
+			if err != nil {
+				return nil, fmt.Errorf("argument %s: %w", name, err)
+			}
+			for _, v := range s {
+				header.Add(headerKey, v)
+			}
+			continue
+		}
+		if formKey == "" && f.httpMethod == http.MethodGet {
+			// 与gin的form绑定一致, 没有form标签时使用字段名
+			formKey = sf.Name
+		}
+		if formKey != "" && formKey != "-" {
+			s, err := formValues(value)
+			if err != nil {
+				return nil, fmt.Errorf("argument %s: %w", name, err)
+			}
+			query[formKey] = s
+		}
+		if jsonKey := jsonName(sf); f.httpMethod != http.MethodGet && jsonKey != "-" && (tag.JsonKey != "" || tag.FormKey == "") {
+			body[jsonKey] = bodyValue(sf.Type, value)
+		}
+	}
+
+	segments := strings.Split(f.path, "/")
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, ":") && !strings.HasPrefix(segment, "*") {
+			continue
+		}
+		value, ok := params[segment[1:]]
+		if !ok {
+			return nil, fmt.Errorf("missing argument for uri parameter %s", segment[1:])
+		}
+		segments[i] = url.PathEscape(value)
+	}
+	target := strings.Join(segments, "/")
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if f.httpMethod != http.MethodGet {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, f.httpMethod, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	// 中间件可以读取graphql请求的请求头和客户端地址, 例如认证信息
+	if origin, ok := ctx.Value(graphqlRequestContextKey{}).(*http.Request); ok {
+		req.Header = origin.Header.Clone()
+		req.Header.Del("Content-Length")
+		req.Host, req.RemoteAddr = origin.Host, origin.RemoteAddr
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if reader != nil {
+		req.Header.Set("Content-Type", binding.MIMEJSON)
+	} else {
+		req.Header.Del("Content-Type")
+	}
+	return req, nil
+}
+
+// formValues 参数转换为query、uri和header的值, 列表为多个值
+func formValues(value any) ([]string, error) {
+	list, ok := value.([]any)
+	if !ok {
+		list = []any{value}
+	}
+	values := make([]string, 0, len(list))
+	for _, item := range list {
+		switch v := item.(type) {
+		case string:
+			values = append(values, v)
+		case json.Number:
+			values = append(values, v.String())
+		case bool:
+			values = append(values, strconv.FormatBool(v))
+		default:
+			return nil, fmt.Errorf("%T can not be sent as a form value", item)
+		}
+	}
+	return values, nil
+}
+
+// bodyValue 把graphql参数名转换为JSON请求体的字段名
+func bodyValue(t reflect.Type, value any) any {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		if t.Kind() != reflect.Struct || t == timeType || reflect.PtrTo(t).Implements(jsonUnmarshalerType) {
+			return v
+		}
+		object := make(map[string]any, len(v))
+		for _, sf := range exportedFields(t) {
+			item, ok := v[taggedFieldName(sf)]
+			if key := jsonName(sf); ok && key != "-" {
+				object[key] = bodyValue(sf.Type, item)
+			}
+		}
+		return object
+	case []any:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return v
+		}
+		list := make([]any, len(v))
+		for i, item := range v {
+			list[i] = bodyValue(t.Elem(), item)
+		}
+		return list
+	}
+	return value
+}
+
+// jsonName encoding/json使用的字段名
+func jsonName(sf reflect.StructField) string {
+	if name, _, _ := strings.Cut(sf.Tag.Get("json"), ","); name != "" {
+		return name
+	}
+	return sf.Name
+}
+
+// convertOutput 把回调方法的返回值转换为生成的resolver类型, 结构体字段按生成schema时的字段名对应
+func convertOutput(dst, src reflect.Value) error {
+	// JSON标量保留原始值, 其余类型去掉指针和接口
+	isJSON := dst.Type() == graphqlJSONType || (dst.Kind() == reflect.Ptr && dst.Type().Elem() == graphqlJSONType)
+	for src.IsValid() {
+		switch src.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Map:
+			if src.IsNil() {
+				return nil
+			}
+		}
+		if isJSON || (src.Kind() != reflect.Ptr && src.Kind() != reflect.Interface) {
+			break
+		}
+		src = src.Elem()
+	}
+	if !src.IsValid() {
+		return nil
+	}
+
+	switch dt := dst.Type(); {
+	case dt.Kind() == reflect.Ptr:
+		v := reflect.New(dt.Elem())
+		if err := convertOutput(v.Elem(), src); err != nil {
+			return err
+		}
+		dst.Set(v)
+	case dt == graphqlJSONType:
+		dst.Set(reflect.ValueOf(GraphqlJSON{Value: src.Interface()}))
+	case dt == graphqlTimeType:
+		switch t := src.Interface().(type) {
+		case graphql.Time:
+			dst.Set(reflect.ValueOf(t))
+		case time.Time:
+			dst.Set(reflect.ValueOf(graphql.Time{Time: t}))
+		default:
+			return fmt.Errorf("can not convert %s to %s", src.Type(), dt)
+		}
+	case dt.Kind() == reflect.Struct:
+		if src.Kind() != reflect.Struct {
+			return fmt.Errorf("expected a struct for %s, got %s", dt, src.Type())
+		}
+		for i := 0; i < dt.NumField(); i++ {
+			name := jsonName(dt.Field(i))
+			index, ok := taggedFieldIndex(src.Type(), name)
+			if !ok {
+				continue
+			}
+			field, err := src.FieldByIndexErr(index)
+			if err != nil {
+				continue
+			}
+			if err := convertOutput(dst.Field(i), field); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	case dt.Kind() == reflect.Slice:
+		if src.Kind() != reflect.Slice && src.Kind() != reflect.Array {
+			return fmt.Errorf("expected a list for %s, got %s", dt, src.Type())
+		}
+		list := reflect.MakeSlice(dt, src.Len(), src.Len())
+		for i := 0; i < src.Len(); i++ {
+			if err := convertOutput(list.Index(i), src.Index(i)); err != nil {
+				return err
+			}
+		}
+		dst.Set(list)
+	case dt.Kind() == reflect.Int32:
+		var n int64
+		switch {
+		case src.CanInt():
+			n = src.Int()
+		case src.CanUint() && src.Uint() <= math.MaxInt32:
+			n = int64(src.Uint())
+		case src.CanUint():
+			return fmt.Errorf("value %d overflows graphql Int", src.Uint())
+		}
+		if n < math.MinInt32 || n > math.MaxInt32 {
+			return fmt.Errorf("value %d overflows graphql Int", n)
+		}
+		dst.SetInt(n)
+	case dt.Kind() == reflect.Float64 && src.CanFloat():
+		dst.SetFloat(src.Float())
+	case dt.Kind() == reflect.String && src.Kind() == reflect.Slice:
+		// []byte与encoding/json一致, 使用base64字符串
+		dst.SetString(base64.StdEncoding.EncodeToString(src.Bytes()))
+	case dt.Kind() == reflect.String && src.Kind() == reflect.String:
+		dst.SetString(src.String())
+	case dt.Kind() == reflect.Bool && src.Kind() == reflect.Bool:
+		dst.SetBool(src.Bool())
+	default:
+		return fmt.Errorf("can not convert %s to %s", src.Type(), dt)
+	}
+	return nil
+}
+
+func (w *controllerResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *controllerResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
+
+func (w *controllerResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+// err 中间件中止请求时的错误, 例如认证失败
+func (w *controllerResponseWriter) err() error {
+	body := bytes.TrimSpace(w.body.Bytes())
+	if len(body) == 0 {
+		return errors.New(http.StatusText(w.status))
+	}
+	return fmt.Errorf("%s: %s", http.StatusText(w.status), body)
+}
+
+// taggedFieldIndex 按taggedFieldName查找结构体字段, 结果按类型缓存
+func taggedFieldIndex(t reflect.Type, name string) ([]int, bool) {
+	cached, ok := taggedFields.Load(t)
+	if !ok {
+		index := make(map[string][]int)
+		for _, f := range exportedFields(t) {
+			if fieldName := taggedFieldName(f); fieldName != "" {
+				index[fieldName] = f.Index
+			}
+		}
+		cached, _ = taggedFields.LoadOrStore(t, index)
+	}
+	index, ok := cached.(map[string][]int)[name]
+	return index, ok
+}
+
+// taggedFieldName 依次使用json、form和uri标签作为字段名, 都没有时为lowerCamel形式的字段名, 标签为"-"时忽略
+func taggedFieldName(f reflect.StructField) string {
+	tag := fieldTag(f)
+	ignored := false
+	for _, key := range []string{tag.JsonKey, tag.FormKey, tag.UriKey} {
+		key, _, _ = strings.Cut(key, ",")
+		switch key {
+		case "":
+		case "-":
+			ignored = true
+		default:
+			return key
+		}
+	}
+	if ignored {
+		return ""
+	}
+	return lowerCamel(f.Name)
+}
+
+// isRequired 是否有binding:"required"校验
+func isRequired(f reflect.StructField) bool {
+	for _, rule := range strings.Split(f.Tag.Get("binding"), ",") {
+		if rule == "required" {
+			return true
+		}
+	}
+	return false
+}
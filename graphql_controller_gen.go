@@ -0,0 +1,260 @@
+package ginplus
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/types"
+)
+
+type (
+	// resolverWriter 根据controller生成的schema输出resolver代码
+	resolverWriter struct {
+		buf bytes.Buffer
+		// 生成代码所在的包是否为ginplus
+		local bool
+		// 是否使用了graphql包中的类型
+		graphql bool
+		// 使用到的对象和输入类型
+		named map[string]types.NamedType
+		// 类型名 -> 结构体定义
+		defs    map[string]*bytes.Buffer
+		order   []string
+		current *bytes.Buffer
+	}
+)
+
+// GenerateGraphqlResolver 根据WithControllers注册的controller生成graphql resolver的Go代码, pkg为生成代码的包名,
+// 生成的GraphqlResolver作为GraphqlConfig.Root, 与Controllers同时使用, 由graphql-go执行查询:
+//
+//	//go:generate go run ../gen
+//	code, err := engine.GenerateGraphqlResolver("graph")
+//	os.WriteFile("resolver_gen.go", code, 0o644)
+//
+//	New(gin.New(), WithControllers(&Api{}), WithGraphqlConfig(GraphqlConfig{Enable: true, Controllers: true, Root: &graph.GraphqlResolver{}}))
+//
+// 生成的代码只依赖schema, controller的请求和响应结构体变化后需要重新生成, 否则注册时返回错误
+func (l *GinEngine) GenerateGraphqlResolver(pkg string) ([]byte, error) {
+	router, err := l.controllerRouter()
+	if err != nil {
+		return nil, err
+	}
+	schema, err := graphql.ParseSchema(withCostDirective(router.sdl), nil)
+	if err != nil {
+		return nil, fmt.Errorf("parse graphql schema: %w", err)
+	}
+	ast := schema.ASTSchema()
+
+	w := &resolverWriter{
+		local: pkg == "ginplus",
+		named: make(map[string]types.NamedType),
+		defs:  make(map[string]*bytes.Buffer),
+	}
+	w.printf("// GraphqlResolver controller生成的graphql根节点, 通过GraphqlConfig.Root注册\n")
+	w.printf("type GraphqlResolver struct {\n\t%sControllerResolver\n}\n", w.qualifier())
+	methods := make(map[string]string)
+	for _, typeName := range []string{"Query", "Mutation"} {
+		root, ok := ast.Types[typeName].(*types.ObjectTypeDefinition)
+		if !ok {
+			continue
+		}
+		for _, field := range root.Fields {
+			key := fieldKey(field.Name)
+			if other, ok := methods[key]; ok {
+				return nil, fmt.Errorf("graphql fields %s and %s.%s resolve to the same method", other, typeName, field.Name)
+			}
+			methods[key] = typeName + "." + field.Name
+			if err := w.rootField(typeName, field); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// 只输出根字段使用到的类型, 按名称排序
+	for emitted := make(map[string]bool); len(emitted) < len(w.named); {
+		names := make([]string, 0, len(w.named))
+		for name := range w.named {
+			if !emitted[name] {
+				names = append(names, name)
+			}
+		}
+		for _, name := range names {
+			emitted[name] = true
+			var err error
+			switch t := w.named[name].(type) {
+			case *types.ObjectTypeDefinition:
+				err = w.object(t)
+			case *types.InputObject:
+				err = w.input(t)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	sort.Strings(w.order)
+	for _, name := range w.order {
+		w.buf.Write(w.defs[name].Bytes())
+	}
+
+	var src bytes.Buffer
+	src.WriteString("// Code generated by ginplus GenerateGraphqlResolver. DO NOT EDIT.\n\n")
+	src.WriteString("package " + pkg + "\n\nimport (\n\t\"context\"\n\n")
+	if w.graphql {
+		src.WriteString("\t\"github.com/graph-gophers/graphql-go\"\n")
+	}
+	if !w.local {
+		src.WriteString("\t\"github.com/aide-cloud/gin-plus\"\n")
+	}
+	src.WriteString(")\n\n")
+	src.Write(w.buf.Bytes())
+	return format.Source(src.Bytes())
+}
+
+// rootField 根字段的方法, 调用ControllerResolver.ResolveController执行controller
+func (w *resolverWriter) rootField(typeName string, field *types.FieldDefinition) error {
+	out := w.goType(field.Type)
+	w.printf("\nfunc (r *GraphqlResolver) %s(ctx context.Context", exportedName(field.Name))
+	args := "nil"
+	if len(field.Arguments) > 0 {
+		w.printf(", args struct {\n")
+		if err := w.fields(field.Arguments); err != nil {
+			return fmt.Errorf("%s.%s: %w", typeName, field.Name, err)
+		}
+		w.printf("}")
+		args = "args"
+	}
+	w.printf(") (%s, error) {\n", out)
+	w.printf("\tvar out %s\n", out)
+	w.printf("\terr := r.ResolveController(ctx, %q, %q, %s, &out)\n", typeName, field.Name, args)
+	w.printf("\treturn out, err\n}\n")
+	return nil
+}
+
+// object 输出类型对应的结构体, json标签为graphql字段名
+func (w *resolverWriter) object(t *types.ObjectTypeDefinition) error {
+	w.define(t.Name)
+	w.printf("\ntype %s struct {\n", objectName(t.Name))
+	seen := make(map[string]string)
+	for _, field := range t.Fields {
+		key := fieldKey(field.Name)
+		if other, ok := seen[key]; ok {
+			return fmt.Errorf("graphql fields %s.%s and %s.%s resolve to the same Go field", t.Name, other, t.Name, field.Name)
+		}
+		seen[key] = field.Name
+		w.printf("\t%s %s `json:%q`\n", exportedName(field.Name), w.goType(field.Type), field.Name)
+	}
+	w.printf("}\n")
+	return nil
+}
+
+// input 输入类型对应的结构体, 可为空的字段在JSON中省略, 表示未传入
+func (w *resolverWriter) input(t *types.InputObject) error {
+	w.define(t.Name)
+	w.printf("\ntype %s struct {\n", inputName(t.Name))
+	if err := w.fields(t.Values); err != nil {
+		return fmt.Errorf("%s: %w", t.Name, err)
+	}
+	w.printf("}\n")
+	return nil
+}
+
+func (w *resolverWriter) fields(values types.ArgumentsDefinition) error {
+	seen := make(map[string]string)
+	for _, v := range values {
+		key := fieldKey(v.Name.Name)
+		if other, ok := seen[key]; ok {
+			return fmt.Errorf("arguments %s and %s resolve to the same Go field", other, v.Name.Name)
+		}
+		seen[key] = v.Name.Name
+		typ, tag := w.goType(v.Type), v.Name.Name
+		if strings.HasPrefix(typ, "*") {
+			tag += ",omitempty"
+		}
+		w.printf("\t%s %s `json:%q`\n", exportedName(v.Name.Name), typ, tag)
+	}
+	return nil
+}
+
+// goType graphql类型对应的Go类型, 与graphql-go的要求一致: 可为空的类型为指针, 对象总是使用指针
+func (w *resolverWriter) goType(t types.Type) string {
+	nonNull := false
+	if nn, ok := t.(*types.NonNull); ok {
+		nonNull, t = true, nn.OfType
+	}
+	var typ string
+	switch t := t.(type) {
+	case *types.List:
+		typ = "[]" + w.goType(t.OfType)
+	case *types.ObjectTypeDefinition:
+		w.named[t.Name] = t
+		return "*" + objectName(t.Name)
+	case *types.InputObject:
+		w.named[t.Name] = t
+		typ = inputName(t.Name)
+	case *types.ScalarTypeDefinition:
+		switch t.Name {
+		case "Int":
+			typ = "int32"
+		case "Float":
+			typ = "float64"
+		case "String":
+			typ = "string"
+		case "Boolean":
+			typ = "bool"
+		case "ID", "Time":
+			w.graphql = true
+			typ = "graphql." + t.Name
+		case "JSON":
+			typ = w.qualifier() + "GraphqlJSON"
+		}
+	}
+	if nonNull {
+		return typ
+	}
+	return "*" + typ
+}
+
+func (w *resolverWriter) qualifier() string {
+	if w.local {
+		return ""
+	}
+	return "ginplus."
+}
+
+// define 之后的输出写入类型name的定义
+func (w *resolverWriter) define(name string) {
+	w.defs[name] = new(bytes.Buffer)
+	w.order = append(w.order, name)
+	w.current = w.defs[name]
+}
+
+func (w *resolverWriter) printf(format string, args ...any) {
+	if w.current != nil {
+		fmt.Fprintf(w.current, format, args...)
+		return
+	}
+	fmt.Fprintf(&w.buf, format, args...)
+}
+
+// objectName 输出类型的结构体名, 不导出, 避免与controller中的类型重名
+func objectName(name string) string {
+	return lowerCamel(name) + "Object"
+}
+
+func inputName(name string) string {
+	return lowerCamel(name)
+}
+
+func exportedName(name string) string {
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// fieldKey graphql-go按忽略大小写和下划线的名称查找方法和字段
+func fieldKey(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", ""))
+}
@@ -0,0 +1,316 @@
+package ginplus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	GqlUserApi struct {
+		Child *GqlUserChild
+	}
+
+	GqlUserChild struct{}
+
+	GqlUserOther struct{}
+
+	// GqlUserNew 生成resolver之后新增的controller
+	GqlUserNew struct{}
+
+	GqlUserDetailReq struct {
+		Id      uint `uri:"id" binding:"max=100"`
+		Verbose bool `form:"verbose"`
+	}
+
+	GqlUserDetailResp struct {
+		Id      uint           `json:"id"`
+		Name    string         `json:"name" desc:"名称"`
+		Tags    []string       `json:"tags"`
+		Items   []*GqlUserItem `json:"items"`
+		Extra   map[string]any `json:"extra"`
+		Created time.Time      `json:"created"`
+		Secret  string         `json:"-"`
+	}
+
+	GqlUserItem struct {
+		Label string  `json:"label"`
+		Score float32 `json:"score"`
+	}
+
+	GqlUserUpdateReq struct {
+		Id     uint           `uri:"id"`
+		Filter *GqlUserFilter `json:"filter" binding:"required"`
+		Names  []string       `json:"names"`
+	}
+
+	GqlUserFilter struct {
+		Keyword string `json:"keyword"`
+	}
+
+	GqlUserUpdateResp struct {
+		Id      uint `json:"id"`
+		Keyword string
+		Names   []string `json:"names"`
+	}
+
+	GqlUserEmptyReq struct{}
+)
+
+// envUpdateGraphqlResolver 设置后重新生成graphql_resolver_gen_test.go
+const envUpdateGraphqlResolver = "GINPLUS_UPDATE_GRAPHQL_RESOLVER"
+
+// Middlewares 从请求头取得用户, graphql字段调用时同样经过该中间件
+func (a *GqlUserApi) Middlewares() []gin.HandlerFunc {
+	return []gin.HandlerFunc{func(ctx *gin.Context) {
+		if user := ctx.GetHeader("X-User"); user != "" {
+			ctx.Set("user", user)
+		}
+	}}
+}
+
+// MethodeMiddlewares PostUpdate需要X-Token请求头
+func (a *GqlUserApi) MethodeMiddlewares() map[string][]gin.HandlerFunc {
+	return map[string][]gin.HandlerFunc{
+		"PostUpdate": {func(ctx *gin.Context) {
+			if ctx.GetHeader("X-Token") == "" {
+				ctx.AbortWithStatus(http.StatusUnauthorized)
+			}
+		}},
+	}
+}
+
+func (a *GqlUserApi) GetDetail(ctx context.Context, req *GqlUserDetailReq) (*GqlUserDetailResp, error) {
+	name := "demo"
+	if user, ok := ctx.Value("user").(string); ok {
+		name = user
+	}
+	resp := &GqlUserDetailResp{
+		Id:      req.Id,
+		Name:    name,
+		Items:   []*GqlUserItem{{Label: "a", Score: 1.5}},
+		Created: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		Secret:  "secret",
+	}
+	if req.Verbose {
+		resp.Extra = map[string]any{"verbose": true}
+	}
+	return resp, nil
+}
+
+func (a *GqlUserApi) PostUpdate(_ context.Context, req *GqlUserUpdateReq) (*GqlUserUpdateResp, error) {
+	resp := &GqlUserUpdateResp{Id: req.Id, Names: req.Names}
+	if req.Filter != nil {
+		resp.Keyword = req.Filter.Keyword
+	}
+	return resp, nil
+}
+
+func (c *GqlUserChild) GetFail(context.Context, *GqlUserEmptyReq) (*GqlUserItem, error) {
+	return nil, errors.New("fail")
+}
+
+func (c *GqlUserOther) GetFail(context.Context, *GqlUserEmptyReq) (*GqlUserItem, error) {
+	return nil, errors.New("other")
+}
+
+func (c *GqlUserNew) GetNew(context.Context, *GqlUserEmptyReq) (*GqlUserItem, error) {
+	return &GqlUserItem{}, nil
+}
+
+func newGqlUserEngine(opts ...OptionFun) *GinEngine {
+	return New(gin.New(), append([]OptionFun{WithControllers(&GqlUserApi{Child: &GqlUserChild{}})}, opts...)...)
+}
+
+// graphql_resolver_gen_test.go与controller生成的代码保持一致
+func TestGinEngine_GenerateGraphqlResolver(t *testing.T) {
+	code, err := newGqlUserEngine().GenerateGraphqlResolver("ginplus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const file = "graphql_resolver_gen_test.go"
+	if os.Getenv(envUpdateGraphqlResolver) != "" {
+		if err := os.WriteFile(file, code, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	golden, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(code, golden) {
+		t.Errorf("%s is stale, run the test with %s=1:\n%s", file, envUpdateGraphqlResolver, code)
+	}
+}
+
+func TestGinEngine_RegisterGraphql_Controllers(t *testing.T) {
+	engine := newGqlUserEngine()
+	engine.RegisterGraphql(&GraphqlConfig{Enable: true, Controllers: true, Root: &GraphqlResolver{}})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/graphql/schema", nil))
+	for _, want := range []string{
+		"getDetail(id: Int, verbose: Boolean): GqlUserDetailResp\n",
+		"getFail: GqlUserItem\n",
+		"type Mutation {\n\tpostUpdate(id: Int, filter: GqlUserFilterInput!, names: [String!]): GqlUserUpdateResp\n}",
+		"\t# 名称\n\tname: String!\n",
+		"extra: JSON\n",
+		"created: Time!\n",
+		"keyword: String!\n",
+	} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("schema missing %q:\n%s", want, w.Body.String())
+		}
+	}
+	if strings.Contains(w.Body.String(), "secret") {
+		t.Errorf("schema contains ignored field:\n%s", w.Body.String())
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		header map[string]string
+		want   string
+	}{
+		{
+			name:  "query",
+			query: `{"query":"query ($id: Int!, $verbose: Boolean = true) { detail: getDetail(id: $id, verbose: $verbose) { id name tags items { label score } extra ...F } __typename } fragment F on GqlUserDetailResp { created name }","variables":{"id":1}}`,
+			want:  `{"data":{"detail":{"id":1,"name":"demo","tags":[],"items":[{"label":"a","score":1.5}],"extra":{"verbose":true},"created":"2023-01-02T03:04:05Z"},"__typename":"Query"}}`,
+		},
+		{
+			name:  "directives",
+			query: `{"query":"{ getDetail(id: 2) { id name @skip(if: true) ... @include(if: false) { tags } } }"}`,
+			want:  `{"data":{"getDetail":{"id":2}}}`,
+		},
+		{
+			name:   "controller middleware",
+			query:  `{"query":"{ getDetail(id: 2) { name } }"}`,
+			header: map[string]string{"X-User": "aide"},
+			want:   `{"data":{"getDetail":{"name":"aide"}}}`,
+		},
+		{
+			name:   "mutation",
+			query:  `{"query":"mutation { postUpdate(id: 3, filter: {keyword: \"k\"}, names: \"x\") { id keyword names } }"}`,
+			header: map[string]string{"X-Token": "token"},
+			want:   `{"data":{"postUpdate":{"id":3,"keyword":"k","names":["x"]}}}`,
+		},
+		{
+			name:  "method middleware",
+			query: `{"query":"mutation { postUpdate(id: 3, filter: {}) { id } }"}`,
+			want:  `{"errors":[{"message":"Unauthorized","path":["postUpdate"]}],"data":{"postUpdate":null}}`,
+		},
+		{
+			name:  "resolver error",
+			query: `{"query":"{ getFail { label } getDetail(id: 4) { id } }"}`,
+			want:  `{"errors":[{"message":"fail","path":["getFail"]}],"data":{"getFail":null,"getDetail":{"id":4}}}`,
+		},
+		{
+			name:  "binding",
+			query: `{"query":"{ getDetail(id: 101) { id } }"}`,
+			want:  `{"errors":[{"message":"Key: 'GqlUserDetailReq.Id' Error:Field validation for 'Id' failed on the 'max' tag","path":["getDetail"]}],"data":{"getDetail":null}}`,
+		},
+		{
+			name:  "validation",
+			query: `{"query":"{ getDetail { secret } }"}`,
+			want:  `Cannot query field \"secret\" on type \"GqlUserDetailResp\".`,
+		},
+		{
+			name:  "introspection",
+			query: `{"query":"{ __schema { mutationType { name } } __type(name: \"GqlUserItem\") { fields { name type { kind ofType { name } } } } }"}`,
+			want:  `{"data":{"__schema":{"mutationType":{"name":"Mutation"}},"__type":{"fields":[{"name":"label","type":{"kind":"NON_NULL","ofType":{"name":"String"}}},{"name":"score","type":{"kind":"NON_NULL","ofType":{"name":"Float"}}}]}}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/graphql", strings.NewReader(tt.query))
+			for key, value := range tt.header {
+				req.Header.Set(key, value)
+			}
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+			if !strings.Contains(w.Body.String(), tt.want) {
+				t.Errorf("got %s, want %s", w.Body.String(), tt.want)
+			}
+		})
+	}
+
+	// 同一个controller仍然提供REST接口
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/gqlUserApi/detail/5", nil))
+	if w.Code != 200 || !strings.Contains(w.Body.String(), `"name":"demo"`) {
+		t.Errorf("rest: %d %s", w.Code, w.Body.String())
+	}
+}
+
+type gqlUserResponse struct {
+	called bool
+}
+
+func (r *gqlUserResponse) Response(ctx *gin.Context, resp any, err error) {
+	r.called = true
+	NewResponse().Response(ctx, resp, err)
+}
+
+// 字段调用使用WithBind、WithDefaultResponse和WithDefaultHandler
+func TestGinEngine_RegisterGraphql_ControllersOptions(t *testing.T) {
+	response := &gqlUserResponse{}
+	var bound any
+	engine := newGqlUserEngine(WithDefaultResponse(response), WithBind(func(c *gin.Context, params any) error {
+		bound = params
+		return Bind(c, params)
+	}))
+	engine.RegisterGraphql(&GraphqlConfig{Enable: true, Controllers: true, Root: &GraphqlResolver{}})
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("POST", "/graphql", strings.NewReader(`{"query":"{ getDetail(id: 6) { id } }"}`)))
+	if w.Body.String() != `{"data":{"getDetail":{"id":6}}}` {
+		t.Errorf("unexpected response %s", w.Body.String())
+	}
+	if req, ok := bound.(*GqlUserDetailReq); !ok || req.Id != 6 || !response.called {
+		t.Errorf("bind or response not used: %v %v", bound, response.called)
+	}
+
+	// 处理函数直接输出JSON时, 输出作为字段的值
+	engine = newGqlUserEngine(WithDefaultHandler(func(controller any, t reflect.Method, req reflect.Type) gin.HandlerFunc {
+		return func(ctx *gin.Context) {
+			ctx.JSON(http.StatusOK, gin.H{"id": 7, "name": "raw"})
+		}
+	}))
+	engine.RegisterGraphql(&GraphqlConfig{Enable: true, Controllers: true, Root: &GraphqlResolver{}})
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("POST", "/graphql", strings.NewReader(`{"query":"{ getDetail(id: 6) { id name } }"}`)))
+	if w.Body.String() != `{"data":{"getDetail":{"id":7,"name":"raw"}}}` {
+		t.Errorf("unexpected response %s", w.Body.String())
+	}
+}
+
+func TestGinEngine_RegisterGraphql_ControllersError(t *testing.T) {
+	for name, engine := range map[string]*GinEngine{
+		"no controllers": New(gin.New()).RegisterGraphql(&GraphqlConfig{Enable: true, Controllers: true, Root: &GraphqlResolver{}}),
+		"without root":   newGqlUserEngine().RegisterGraphql(&GraphqlConfig{Enable: true, Controllers: true}),
+		"other root": newGqlUserEngine().
+			RegisterGraphql(&GraphqlConfig{Enable: true, Controllers: true, Root: &testGraphqlRoot{}}),
+		"stale root": New(gin.New(), WithControllers(&GqlUserApi{Child: &GqlUserChild{}}, &GqlUserNew{})).
+			RegisterGraphql(&GraphqlConfig{Enable: true, Controllers: true, Root: &GraphqlResolver{}}),
+		"duplicate": New(gin.New(), WithControllers(&GqlUserChild{}, &GqlUserOther{})).
+			RegisterGraphql(&GraphqlConfig{Enable: true, Controllers: true, Root: &GraphqlResolver{}}),
+	} {
+		if err := engine.Start(); err == nil {
+			engine.Stop()
+			t.Errorf("%s: expected start error", name)
+		}
+	}
+
+	// 没有通过RegisterGraphql绑定的resolver
+	if _, err := (&GraphqlResolver{}).GetFail(context.Background()); err == nil {
+		t.Error("expected error from unbound resolver")
+	}
+}
@@ -0,0 +1,329 @@
+package ginplus
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	dataLoaderTracerName = "ginplus.dataloader"
+	// defaultDataLoaderWait 收集同一批次key的默认等待时间
+	defaultDataLoaderWait = time.Millisecond
+)
+
+type (
+	// BatchFunc 批量加载函数, 返回key到值的映射, 不存在的key对应V的零值
+	BatchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+	// DataLoader 批量加载器, 同一请求中短时间内的Load合并为一次BatchFunc调用, 结果在请求内缓存.
+	// DataLoader本身是无状态的定义, 通常声明为全局变量, 批次和缓存保存在WithDataLoaders返回的context中,
+	// graphql处理函数会为每个query和mutation请求附加新的缓存
+	DataLoader[K comparable, V any] struct {
+		name  string
+		fetch BatchFunc[K, V]
+		dataLoaderConfig
+	}
+
+	dataLoaderConfig struct {
+		// 批次等待时间
+		wait time.Duration
+		// 单个批次的最大key数量, 0为不限制
+		maxBatch int
+		// 为空时使用父span所属的TracerProvider, 没有父span时使用otel全局TracerProvider
+		provider oteltrace.TracerProvider
+	}
+
+	// DataLoaderOption DataLoader配置函数
+	DataLoaderOption func(*dataLoaderConfig)
+
+	// dataLoaderContextKey context中请求级缓存的key
+	dataLoaderContextKey struct{}
+
+	// dataLoaderRegistry 请求级的DataLoader状态, 以*DataLoader为key
+	dataLoaderRegistry struct {
+		mtx     sync.Mutex
+		loaders map[any]any
+	}
+
+	// dataLoaderState 单个DataLoader在一个请求中的批次和缓存
+	dataLoaderState[K comparable, V any] struct {
+		loader *DataLoader[K, V]
+		mtx    sync.Mutex
+		cache  map[K]*dataLoaderResult[V]
+		batch  *dataLoaderBatch[K, V]
+	}
+
+	dataLoaderBatch[K comparable, V any] struct {
+		ctx     context.Context
+		keys    []K
+		results []*dataLoaderResult[V]
+		timer   *time.Timer
+	}
+
+	dataLoaderResult[V any] struct {
+		done  chan struct{}
+		value V
+		err   error
+	}
+)
+
+// NewDataLoader 创建批量加载器, name用于span名称
+func NewDataLoader[K comparable, V any](name string, fetch BatchFunc[K, V], opts ...DataLoaderOption) *DataLoader[K, V] {
+	l := &DataLoader[K, V]{
+		name:             name,
+		fetch:            fetch,
+		dataLoaderConfig: dataLoaderConfig{wait: defaultDataLoaderWait},
+	}
+	for _, opt := range opts {
+		opt(&l.dataLoaderConfig)
+	}
+	return l
+}
+
+// WithDataLoaderWait 设置收集同一批次key的等待时间, 默认1ms
+func WithDataLoaderWait(wait time.Duration) DataLoaderOption {
+	return func(c *dataLoaderConfig) {
+		c.wait = wait
+	}
+}
+
+// WithDataLoaderMaxBatch 设置单个批次的最大key数量, 达到后立即加载
+func WithDataLoaderMaxBatch(size int) DataLoaderOption {
+	return func(c *dataLoaderConfig) {
+		c.maxBatch = size
+	}
+}
+
+// WithDataLoaderTracerProvider 设置批次span的TracerProvider
+func WithDataLoaderTracerProvider(provider oteltrace.TracerProvider) DataLoaderOption {
+	return func(c *dataLoaderConfig) {
+		c.provider = provider
+	}
+}
+
+// WithDataLoaders 为context附加新的请求级DataLoader缓存, graphql处理函数会自动调用,
+// REST等其他场景需要批量加载时可手动调用
+func WithDataLoaders(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dataLoaderContextKey{}, &dataLoaderRegistry{loaders: make(map[any]any)})
+}
+
+// Load 加载key对应的值, 同一批次的key合并为一次BatchFunc调用;
+// context中没有WithDataLoaders附加的缓存时直接加载, 不合并也不缓存
+func (l *DataLoader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	state := l.state(ctx)
+	if state == nil {
+		return l.loadOne(ctx, key)
+	}
+	result := state.load(ctx, key)
+	select {
+	case <-result.done:
+		return result.value, result.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// LoadMany 加载多个key, 返回与keys顺序一致的值, 出错时返回第一个错误
+func (l *DataLoader[K, V]) LoadMany(ctx context.Context, keys []K) ([]V, error) {
+	state := l.state(ctx)
+	if state == nil {
+		values, err := l.run(ctx, keys)
+		if err != nil {
+			return nil, err
+		}
+		list := make([]V, len(keys))
+		for i, key := range keys {
+			list[i] = values[key]
+		}
+		return list, nil
+	}
+	results := make([]*dataLoaderResult[V], len(keys))
+	for i, key := range keys {
+		results[i] = state.load(ctx, key)
+	}
+	list := make([]V, len(keys))
+	for i, result := range results {
+		select {
+		case <-result.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if result.err != nil {
+			return nil, result.err
+		}
+		list[i] = result.value
+	}
+	return list, nil
+}
+
+// Prime 把值写入请求级缓存, 已存在的key不会被覆盖
+func (l *DataLoader[K, V]) Prime(ctx context.Context, key K, value V) {
+	state := l.state(ctx)
+	if state == nil {
+		return
+	}
+	state.mtx.Lock()
+	defer state.mtx.Unlock()
+	if _, ok := state.cache[key]; ok {
+		return
+	}
+	result := &dataLoaderResult[V]{done: make(chan struct{}), value: value}
+	close(result.done)
+	state.cache[key] = result
+}
+
+// Clear 删除请求级缓存中的key, 例如mutation修改数据之后
+func (l *DataLoader[K, V]) Clear(ctx context.Context, key K) {
+	state := l.state(ctx)
+	if state == nil {
+		return
+	}
+	state.mtx.Lock()
+	defer state.mtx.Unlock()
+	delete(state.cache, key)
+}
+
+// state 获取当前请求中的加载器状态, 不存在时创建
+func (l *DataLoader[K, V]) state(ctx context.Context) *dataLoaderState[K, V] {
+	registry, ok := ctx.Value(dataLoaderContextKey{}).(*dataLoaderRegistry)
+	if !ok {
+		return nil
+	}
+	registry.mtx.Lock()
+	defer registry.mtx.Unlock()
+	if state, ok := registry.loaders[l].(*dataLoaderState[K, V]); ok {
+		return state
+	}
+	state := &dataLoaderState[K, V]{loader: l, cache: make(map[K]*dataLoaderResult[V])}
+	registry.loaders[l] = state
+	return state
+}
+
+func (l *DataLoader[K, V]) loadOne(ctx context.Context, key K) (V, error) {
+	values, err := l.run(ctx, []K{key})
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+	return values[key], nil
+}
+
+// run 调用BatchFunc并为批次创建span, BatchFunc的panic转换为错误
+func (l *DataLoader[K, V]) run(ctx context.Context, keys []K) (values map[K]V, err error) {
+	ctx, span := spanProvider(ctx, l.provider).Tracer(dataLoaderTracerName).Start(ctx, "DataLoader "+l.name,
+		oteltrace.WithAttributes(
+			attribute.String("dataloader.name", l.name),
+			attribute.Int("dataloader.batch_size", len(keys)),
+		),
+	)
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic occurred: %v", r)
+			Logger().Sugar().Errorf("[GIN-PLUS] [ERROR] dataloader %s: %v", l.name, err)
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+	return l.fetch(ctx, keys)
+}
+
+// load 返回key的结果, 未缓存时加入当前批次
+func (s *dataLoaderState[K, V]) load(ctx context.Context, key K) *dataLoaderResult[V] {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if result, ok := s.cache[key]; ok {
+		return result
+	}
+	result := &dataLoaderResult[V]{done: make(chan struct{})}
+	s.cache[key] = result
+
+	if s.batch == nil {
+		// 批次可能被多个请求方共享, 不随单个调用方取消
+		batch := &dataLoaderBatch[K, V]{ctx: context.WithoutCancel(ctx)}
+		batch.timer = time.AfterFunc(s.loader.wait, func() { s.dispatch(batch) })
+		s.batch = batch
+	}
+	batch := s.batch
+	batch.keys = append(batch.keys, key)
+	batch.results = append(batch.results, result)
+	if s.loader.maxBatch > 0 && len(batch.keys) >= s.loader.maxBatch && batch.timer.Stop() {
+		s.batch = nil
+		go s.dispatch(batch)
+	}
+	return result
+}
+
+// dispatch 加载一个批次, 失败的key从缓存中删除, 之后的Load会重新加载
+func (s *dataLoaderState[K, V]) dispatch(batch *dataLoaderBatch[K, V]) {
+	s.mtx.Lock()
+	if s.batch == batch {
+		s.batch = nil
+	}
+	s.mtx.Unlock()
+
+	values, err := s.loader.run(batch.ctx, batch.keys)
+	if err != nil {
+		s.mtx.Lock()
+		for i, key := range batch.keys {
+			if s.cache[key] == batch.results[i] {
+				delete(s.cache, key)
+			}
+		}
+		s.mtx.Unlock()
+	}
+	for i, key := range batch.keys {
+		result := batch.results[i]
+		result.value, result.err = values[key], err
+		close(result.done)
+	}
+}
+
+// NewGormDataLoader 创建按主键批量查询的DataLoader, 每个批次执行一条WHERE id IN (...)查询,
+// 主键取V的gorm主键字段, K需要与主键字段的类型一致, 不存在的记录为nil
+func NewGormDataLoader[K comparable, V any](name string, db *gorm.DB, opts ...DataLoaderOption) *DataLoader[K, *V] {
+	return NewDataLoader[K, *V](name, func(ctx context.Context, keys []K) (map[K]*V, error) {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(new(V)); err != nil {
+			return nil, err
+		}
+		pk := stmt.Schema.PrioritizedPrimaryField
+		if pk == nil {
+			return nil, fmt.Errorf("%s has no primary key", stmt.Schema.Name)
+		}
+		values := make([]any, len(keys))
+		for i, key := range keys {
+			values[i] = key
+		}
+
+		var rows []*V
+		err := db.WithContext(ctx).
+			Where(clause.IN{Column: clause.Column{Table: clause.CurrentTable, Name: pk.DBName}, Values: values}).
+			Find(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+		result := make(map[K]*V, len(rows))
+		for _, row := range rows {
+			value, _ := pk.ValueOf(ctx, reflect.ValueOf(row).Elem())
+			key, ok := value.(K)
+			if !ok {
+				return nil, fmt.Errorf("primary key %s of %s is %T, not %T", pk.Name, stmt.Schema.Name, value, key)
+			}
+			result[key] = row
+		}
+		return result, nil
+	}, opts...)
+}
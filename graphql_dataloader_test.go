@@ -0,0 +1,195 @@
+package ginplus
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+	"go.opentelemetry.io/otel"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+const testDataLoaderSchema = `
+schema {
+	query: Query
+}
+type Query {
+	posts: [Post!]!
+}
+type Post {
+	id: Int!
+	author: User
+}
+type User {
+	id: Int!
+	name: String!
+}
+`
+
+type (
+	testDataLoaderRoot struct {
+		loader *DataLoader[int32, *testDataLoaderUser]
+	}
+
+	testDataLoaderPost struct {
+		root     *testDataLoaderRoot
+		ID       int32
+		authorID int32
+	}
+
+	testDataLoaderUser struct {
+		ID   int32
+		Name string
+	}
+)
+
+func (r *testDataLoaderRoot) Posts() []*testDataLoaderPost {
+	posts := make([]*testDataLoaderPost, 6)
+	for i := range posts {
+		posts[i] = &testDataLoaderPost{root: r, ID: int32(i), authorID: int32(i % 3)}
+	}
+	return posts
+}
+
+func (p *testDataLoaderPost) Author(ctx context.Context) (*testDataLoaderUser, error) {
+	return p.root.loader.Load(ctx, p.authorID)
+}
+
+func TestDataLoader_GraphqlHandler(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := tracesdk.NewTracerProvider(tracesdk.WithSpanProcessor(recorder))
+
+	var mtx sync.Mutex
+	var batches [][]int32
+	root := &testDataLoaderRoot{}
+	root.loader = NewDataLoader("user", func(ctx context.Context, keys []int32) (map[int32]*testDataLoaderUser, error) {
+		mtx.Lock()
+		batches = append(batches, keys)
+		mtx.Unlock()
+		users := make(map[int32]*testDataLoaderUser)
+		for _, key := range keys {
+			if key != 2 {
+				users[key] = &testDataLoaderUser{ID: key, Name: "user"}
+			}
+		}
+		return users, nil
+	}, WithDataLoaderTracerProvider(tp), WithDataLoaderWait(10*time.Millisecond))
+
+	schema := graphql.MustParseSchema(testDataLoaderSchema, root, graphql.UseFieldResolvers())
+	h := NewGraphqlHandler(schema)
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("POST", "/graphql", strings.NewReader(`{"query":"{ posts { id author { id } } }"}`)))
+		want := `{"data":{"posts":[{"id":0,"author":{"id":0}},{"id":1,"author":{"id":1}},{"id":2,"author":null},{"id":3,"author":{"id":0}},{"id":4,"author":{"id":1}},{"id":5,"author":null}]}}`
+		if w.Body.String() != want {
+			t.Fatalf("got %s", w.Body.String())
+		}
+	}
+
+	// 每个请求只有一个批次, 缓存不跨请求
+	if len(batches) != 2 || len(batches[0]) != 3 || len(batches[1]) != 3 {
+		t.Errorf("batches = %v", batches)
+	}
+	spans := recorder.Ended()
+	if len(spans) != 2 || spans[0].Name() != "DataLoader user" {
+		t.Fatalf("spans = %v", spans)
+	}
+	for _, attr := range spans[0].Attributes() {
+		if attr.Key == "dataloader.batch_size" && attr.Value.AsInt64() != 3 {
+			t.Errorf("batch size = %d", attr.Value.AsInt64())
+		}
+	}
+}
+
+func TestDataLoader_Load(t *testing.T) {
+	var calls atomic.Int32
+	fail := true
+	loader := NewDataLoader("num", func(ctx context.Context, keys []int) (map[int]int, error) {
+		calls.Add(1)
+		if fail {
+			return nil, errors.New("fail")
+		}
+		values := make(map[int]int)
+		for _, key := range keys {
+			values[key] = key * 10
+		}
+		return values, nil
+	}, WithDataLoaderMaxBatch(2))
+
+	// 没有请求级缓存时直接加载
+	if _, err := loader.Load(context.Background(), 1); err == nil || calls.Load() != 1 {
+		t.Fatalf("err = %v, calls = %d", err, calls.Load())
+	}
+
+	ctx := WithDataLoaders(context.Background())
+	if _, err := loader.Load(ctx, 1); err == nil {
+		t.Fatal("expected error")
+	}
+	// 错误不缓存
+	fail = false
+	values, err := loader.LoadMany(ctx, []int{1, 2, 3})
+	if err != nil || len(values) != 3 || values[0] != 10 || values[2] != 30 {
+		t.Fatalf("values = %v, err = %v", values, err)
+	}
+	if n := calls.Load(); n != 4 {
+		t.Errorf("calls = %d, want 4", n)
+	}
+
+	loader.Prime(ctx, 4, 1)
+	loader.Clear(ctx, 1)
+	values, err = loader.LoadMany(ctx, []int{1, 2, 4})
+	if err != nil || values[0] != 10 || values[2] != 1 || calls.Load() != 5 {
+		t.Errorf("values = %v, err = %v, calls = %d", values, err, calls.Load())
+	}
+}
+
+func TestDataLoader_GlobalProvider(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	global := otel.GetTracerProvider()
+	otel.SetTracerProvider(tracesdk.NewTracerProvider(tracesdk.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(global)
+
+	loader := NewDataLoader("num", func(ctx context.Context, keys []int) (map[int]int, error) {
+		return map[int]int{1: 10}, nil
+	})
+	// 没有父span时使用全局TracerProvider
+	if _, err := loader.Load(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if spans := recorder.Ended(); len(spans) != 1 || spans[0].Name() != "DataLoader num" {
+		t.Errorf("want batch traced by the global provider, got %v", spans)
+	}
+}
+
+func TestNewGormDataLoader(t *testing.T) {
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		DSN:                       "root:12345678@tcp(localhost:3306)/test",
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sqls []string
+	_ = db.Callback().Query().After("gorm:query").Register("test:sql", func(db *gorm.DB) {
+		sqls = append(sqls, db.Statement.SQL.String())
+	})
+
+	loader := NewGormDataLoader[uint, gormUser]("gormUser", db)
+	ctx := WithDataLoaders(context.Background())
+	users, err := loader.LoadMany(ctx, []uint{1, 2, 1})
+	if err != nil || len(users) != 3 || users[0] != nil {
+		t.Fatalf("users = %v, err = %v", users, err)
+	}
+	if len(sqls) != 1 || sqls[0] != "SELECT * FROM `gorm_users` WHERE `gorm_users`.`id` IN (?,?)" {
+		t.Errorf("sqls = %q", sqls)
+	}
+}
@@ -0,0 +1,934 @@
+package ginplus
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// CostDirective 字段复杂度指令, schema中未声明时自动添加
+//
+// complexity为字段自身的复杂度, 默认为1; multipliers为乘数参数名, 例如分页的first和limit,
+// 子字段的复杂度乘以第一个存在的参数值, 参数为列表时使用列表长度
+//
+//	type Query {
+//		users(first: Int!): [User!]! @cost(complexity: 2, multipliers: ["first"])
+//	}
+const CostDirective = `directive @cost(complexity: Int = 1, multipliers: [String!]) on FIELD_DEFINITION`
+
+const (
+	costDirectiveName = "cost"
+
+	// 被拒绝查询的原因, 用于错误的code扩展字段和指标标签
+	rejectDepth         = "depth"
+	rejectComplexity    = "complexity"
+	rejectIntrospection = "introspection"
+	rejectTimeout       = "timeout"
+	rejectParse         = "parse"
+
+	// maxQueryCost 复杂度计算的上限, 避免乘数过大时溢出
+	maxQueryCost = math.MaxInt32
+)
+
+var rejectCodes = map[string]string{
+	rejectDepth:         "MAX_DEPTH_EXCEEDED",
+	rejectComplexity:    "QUERY_TOO_COMPLEX",
+	rejectIntrospection: "INTROSPECTION_DISABLED",
+	rejectTimeout:       "QUERY_TIMEOUT",
+	rejectParse:         "GRAPHQL_PARSE_FAILED",
+}
+
+type (
+	// graphqlRejectError 超出限制被拒绝的查询
+	graphqlRejectError struct {
+		reason  string
+		message string
+	}
+
+	// queryDocument 查询文档, 用于计算深度和复杂度, 以及执行controller生成的schema
+	queryDocument struct {
+		operations []*queryOperation
+		fragments  map[string]*queryFragment
+	}
+
+	queryOperation struct {
+		typ        string
+		name       string
+		variables  []*queryVariableDefinition
+		selections []*querySelection
+	}
+
+	// queryVariableDefinition 操作声明的变量和默认值
+	queryVariableDefinition struct {
+		name         string
+		defaultValue any
+		hasDefault   bool
+	}
+
+	queryFragment struct {
+		typeCondition string
+		selections    []*querySelection
+	}
+
+	// querySelection 字段、片段展开或内联片段
+	querySelection struct {
+		// 字段名或片段名, 内联片段为空
+		name string
+		// 字段别名, 没有时为空
+		alias string
+		// 片段展开
+		spread bool
+		// 内联片段的类型条件
+		typeCondition string
+		inline        bool
+		arguments     map[string]any
+		directives    []*queryDirective
+		selections    []*querySelection
+	}
+
+	queryDirective struct {
+		name      string
+		arguments map[string]any
+	}
+
+	// queryVariable 引用变量的参数值
+	queryVariable string
+
+	queryLexer struct {
+		src  string
+		pos  int
+		kind tokenKind
+		text string
+	}
+
+	tokenKind int
+
+	// queryAnalyzer 根据schema计算查询的深度和复杂度
+	queryAnalyzer struct {
+		schema    *types.Schema
+		doc       *queryDocument
+		variables map[string]any
+
+		maxDepth             int
+		disableIntrospection bool
+		// 片段在同一深度的复杂度, 避免片段嵌套展开时重复计算
+		fragmentCost map[string]int
+		visiting     map[string]bool
+	}
+)
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunct
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+func (e *graphqlRejectError) Error() string {
+	return e.message
+}
+
+// WithGraphqlMaxDepth 设置查询的最大深度, 0表示不限制
+func WithGraphqlMaxDepth(depth int) GraphqlHandlerOption {
+	return func(h *GraphqlHandler) {
+		h.maxDepth = depth
+		h.schemaOpts = append(h.schemaOpts, graphql.MaxDepth(depth))
+	}
+}
+
+// WithGraphqlMaxComplexity 设置查询的最大复杂度, 0表示不限制, 字段复杂度通过@cost指令设置, 默认为1
+func WithGraphqlMaxComplexity(complexity int) GraphqlHandlerOption {
+	return func(h *GraphqlHandler) {
+		h.maxComplexity = complexity
+	}
+}
+
+// WithGraphqlMaxParallelism 设置单个查询并发执行的resolver数量, 默认为10, 只在通过Handler解析schema时生效
+func WithGraphqlMaxParallelism(n int) GraphqlHandlerOption {
+	return func(h *GraphqlHandler) {
+		h.schemaOpts = append(h.schemaOpts, graphql.MaxParallelism(n))
+	}
+}
+
+// WithGraphqlTimeout 设置单个查询的超时时间, 不作用于订阅, resolver需要响应context的取消
+func WithGraphqlTimeout(timeout time.Duration) GraphqlHandlerOption {
+	return func(h *GraphqlHandler) {
+		h.timeout = timeout
+	}
+}
+
+// WithGraphqlDisableIntrospection 禁用内省查询, 生产环境中避免暴露schema, __typename不受影响
+func WithGraphqlDisableIntrospection() GraphqlHandlerOption {
+	return func(h *GraphqlHandler) {
+		h.disableIntrospection = true
+		h.schemaOpts = append(h.schemaOpts, graphql.DisableIntrospection())
+	}
+}
+
+// WithGraphqlSchemaOptions 追加graphql-go的schema配置, 只在通过Handler解析schema时生效
+func WithGraphqlSchemaOptions(opts ...graphql.SchemaOpt) GraphqlHandlerOption {
+	return func(h *GraphqlHandler) {
+		h.schemaOpts = append(h.schemaOpts, opts...)
+	}
+}
+
+// WithGraphqlRegisterer 设置被拒绝查询指标的注册器, 默认为prometheus.DefaultRegisterer
+func WithGraphqlRegisterer(registerer prometheus.Registerer) GraphqlHandlerOption {
+	return func(h *GraphqlHandler) {
+		h.registerer = registerer
+	}
+}
+
+// withCostDirective schema中未声明@cost指令时添加声明
+func withCostDirective(schema string) string {
+	if strings.Contains(schema, "directive @"+costDirectiveName) {
+		return schema
+	}
+	return schema + "\n" + CostDirective + "\n"
+}
+
+// initRejectedMetrics 注册被拒绝查询的计数指标, 已注册时复用已有的指标
+func (h *GraphqlHandler) initRejectedMetrics() {
+	registerer := h.registerer
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+	rejected, err := registerCollector(registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: "graphql",
+		Name:      "rejected_queries_total",
+		Help:      "Total number of GraphQL queries rejected by depth, complexity, introspection or timeout limits.",
+	}, []string{"reason"}))
+	if err != nil {
+		Logger().Sugar().Errorf("[GIN-PLUS] [ERROR] register graphql metrics: %v", err)
+		return
+	}
+	h.rejected = rejected
+}
+
+func (h *GraphqlHandler) reject(reason string) {
+	if h.rejected != nil {
+		h.rejected.WithLabelValues(reason).Inc()
+	}
+}
+
+// checkLimits 执行前检查查询的深度、复杂度和内省, 查询无法解析时直接拒绝, 避免绕过检查
+func (h *GraphqlHandler) checkLimits(params graphqlParams) error {
+	if h.Schema == nil || (h.maxDepth <= 0 && h.maxComplexity <= 0 && !h.disableIntrospection) {
+		return nil
+	}
+	doc, err := parseQueryDocument(params.Query)
+	if err != nil {
+		h.reject(rejectParse)
+		return &graphqlRejectError{reason: rejectParse, message: fmt.Sprintf("parse query: %v", err)}
+	}
+	op := doc.operation(params.OperationName)
+	if op == nil {
+		return nil
+	}
+	root, ok := h.Schema.ASTSchema().EntryPoints[op.typ]
+	if !ok {
+		return nil
+	}
+	// 与执行时一致, 未传入的变量使用操作中声明的默认值
+	variables := make(map[string]any, len(params.Variables)+len(op.variables))
+	for _, v := range op.variables {
+		if v.hasDefault {
+			variables[v.name] = v.defaultValue
+		}
+	}
+	for name, value := range params.Variables {
+		variables[name] = value
+	}
+	a := &queryAnalyzer{
+		schema:               h.Schema.ASTSchema(),
+		doc:                  doc,
+		variables:            variables,
+		maxDepth:             h.maxDepth,
+		disableIntrospection: h.disableIntrospection,
+		fragmentCost:         make(map[string]int),
+		visiting:             make(map[string]bool),
+	}
+	cost, err := a.cost(root.TypeName(), op.selections, 1)
+	if err == nil && h.maxComplexity > 0 && cost > h.maxComplexity {
+		err = &graphqlRejectError{
+			reason:  rejectComplexity,
+			message: fmt.Sprintf("query complexity %d exceeds max complexity %d", cost, h.maxComplexity),
+		}
+	}
+	var rejectErr *graphqlRejectError
+	if errors.As(err, &rejectErr) {
+		h.reject(rejectErr.reason)
+	}
+	return err
+}
+
+// operation 返回将要执行的操作, 未指定名称时文档中只能有一个操作
+func (d *queryDocument) operation(name string) *queryOperation {
+	if name == "" {
+		if len(d.operations) == 1 {
+			return d.operations[0]
+		}
+		return nil
+	}
+	for _, op := range d.operations {
+		if op.name == name {
+			return op
+		}
+	}
+	return nil
+}
+
+// cost 计算选择集的复杂度, 同时检查深度和内省, 与graphql-go一致, 片段不增加深度
+func (a *queryAnalyzer) cost(typeName string, selections []*querySelection, depth int) (int, error) {
+	total := 0
+	for _, sel := range selections {
+		var (
+			cost int
+			err  error
+		)
+		switch {
+		case sel.spread:
+			cost, err = a.fragment(sel.name, depth)
+		case sel.inline:
+			typ := sel.typeCondition
+			if typ == "" {
+				typ = typeName
+			}
+			cost, err = a.cost(typ, sel.selections, depth)
+		default:
+			cost, err = a.field(typeName, sel, depth)
+		}
+		if err != nil {
+			return 0, err
+		}
+		total = saturatingAdd(total, cost)
+	}
+	return total, nil
+}
+
+func (a *queryAnalyzer) fragment(name string, depth int) (int, error) {
+	frag, ok := a.doc.fragments[name]
+	// 未知片段和循环引用由graphql-go校验
+	if !ok || a.visiting[name] {
+		return 0, nil
+	}
+	key := name + "@" + strconv.Itoa(depth)
+	if cost, ok := a.fragmentCost[key]; ok {
+		return cost, nil
+	}
+	a.visiting[name] = true
+	cost, err := a.cost(frag.typeCondition, frag.selections, depth)
+	delete(a.visiting, name)
+	if err != nil {
+		return 0, err
+	}
+	a.fragmentCost[key] = cost
+	return cost, nil
+}
+
+func (a *queryAnalyzer) field(typeName string, sel *querySelection, depth int) (int, error) {
+	if a.maxDepth > 0 && depth > a.maxDepth {
+		return 0, &graphqlRejectError{
+			reason:  rejectDepth,
+			message: fmt.Sprintf("field %q has depth %d that exceeds max depth %d", sel.name, depth, a.maxDepth),
+		}
+	}
+	if strings.HasPrefix(sel.name, "__") {
+		if a.disableIntrospection && sel.name != "__typename" {
+			return 0, &graphqlRejectError{reason: rejectIntrospection, message: "introspection is disabled"}
+		}
+		return 0, nil
+	}
+	def := lookupField(a.schema, typeName, sel.name)
+	if def == nil {
+		return 0, nil
+	}
+	complexity, multipliers := fieldCost(def)
+	childCost, err := a.cost(namedType(def.Type), sel.selections, depth+1)
+	if err != nil {
+		return 0, err
+	}
+	return saturatingAdd(complexity, saturatingMul(a.multiplier(def, sel.arguments, multipliers), childCost)), nil
+}
+
+// multiplier 返回第一个存在的乘数参数的值, 未传入的参数和变量使用schema中的默认值, 都没有时为1
+func (a *queryAnalyzer) multiplier(def *types.FieldDefinition, arguments map[string]any, names []string) int {
+	for _, name := range names {
+		value, ok := arguments[name]
+		if v, isVar := value.(queryVariable); ok && isVar {
+			value, ok = a.variables[string(v)]
+		}
+		if !ok {
+			arg := def.Arguments.Get(name)
+			if arg == nil || arg.Default == nil {
+				continue
+			}
+			value = arg.Default.Deserialize(nil)
+		}
+		switch v := value.(type) {
+		case int64:
+			return clampCost(v)
+		case int32:
+			return clampCost(int64(v))
+		case float64:
+			return clampCost(int64(v))
+		case json.Number:
+			n, err := v.Int64()
+			if err != nil {
+				continue
+			}
+			return clampCost(n)
+		case []any:
+			return len(v)
+		}
+	}
+	return 1
+}
+
+// lookupField 查找对象或接口类型的字段定义
+func lookupField(schema *types.Schema, typeName, name string) *types.FieldDefinition {
+	switch t := schema.Types[typeName].(type) {
+	case *types.ObjectTypeDefinition:
+		return t.Fields.Get(name)
+	case *types.InterfaceTypeDefinition:
+		return t.Fields.Get(name)
+	}
+	return nil
+}
+
+// fieldCost 读取字段的@cost指令, 没有时复杂度为1
+func fieldCost(def *types.FieldDefinition) (complexity int, multipliers []string) {
+	complexity = 1
+	directive := def.Directives.Get(costDirectiveName)
+	if directive == nil {
+		return complexity, nil
+	}
+	if v, ok := directive.Arguments.Get("complexity"); ok {
+		if n, ok := v.Deserialize(nil).(int32); ok {
+			complexity = int(n)
+		}
+	}
+	if v, ok := directive.Arguments.Get("multipliers"); ok {
+		list, _ := v.Deserialize(nil).([]any)
+		for _, item := range list {
+			if name, ok := item.(string); ok {
+				multipliers = append(multipliers, name)
+			}
+		}
+	}
+	return complexity, multipliers
+}
+
+// namedType 去掉非空和列表包装后的类型名
+func namedType(t types.Type) string {
+	for {
+		switch v := t.(type) {
+		case *types.NonNull:
+			t = v.OfType
+		case *types.List:
+			t = v.OfType
+		case types.NamedType:
+			return v.TypeName()
+		default:
+			return ""
+		}
+	}
+}
+
+func clampCost(n int64) int {
+	switch {
+	case n < 0:
+		return 0
+	case n > maxQueryCost:
+		return maxQueryCost
+	}
+	return int(n)
+}
+
+func saturatingAdd(a, b int) int {
+	return clampCost(int64(a) + int64(b))
+}
+
+func saturatingMul(a, b int) int {
+	if a != 0 && b > maxQueryCost/a {
+		return maxQueryCost
+	}
+	return clampCost(int64(a) * int64(b))
+}
+
+// parseQueryDocument 解析查询文档中的操作、片段、字段和参数, 不做校验,
+// graphql-go v1.5.0的查询解析器在internal包中且没有复杂度钩子, 深度和内省仍由graphql.MaxDepth和graphql.DisableIntrospection在校验时执行
+func parseQueryDocument(src string) (*queryDocument, error) {
+	l := &queryLexer{src: src}
+	if err := l.next(); err != nil {
+		return nil, err
+	}
+	doc := &queryDocument{fragments: make(map[string]*queryFragment)}
+	for l.kind != tokenEOF {
+		switch {
+		case l.is(tokenPunct, "{"):
+			selections, err := l.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, &queryOperation{typ: operationQuery, selections: selections})
+		case l.is(tokenName, operationQuery), l.is(tokenName, operationMutation), l.is(tokenName, operationSubscription):
+			op := &queryOperation{typ: l.text}
+			if err := l.next(); err != nil {
+				return nil, err
+			}
+			if l.kind == tokenName {
+				op.name = l.text
+				if err := l.next(); err != nil {
+					return nil, err
+				}
+			}
+			if l.is(tokenPunct, "(") {
+				variables, err := l.variableDefinitions()
+				if err != nil {
+					return nil, err
+				}
+				op.variables = variables
+			}
+			if _, err := l.directives(); err != nil {
+				return nil, err
+			}
+			selections, err := l.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			op.selections = selections
+			doc.operations = append(doc.operations, op)
+		case l.is(tokenName, "fragment"):
+			name, err := l.expectName()
+			if err != nil {
+				return nil, err
+			}
+			if !l.is(tokenName, "on") {
+				return nil, l.unexpected()
+			}
+			typeCondition, err := l.expectName()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := l.directives(); err != nil {
+				return nil, err
+			}
+			selections, err := l.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.fragments[name] = &queryFragment{typeCondition: typeCondition, selections: selections}
+		default:
+			return nil, l.unexpected()
+		}
+	}
+	return doc, nil
+}
+
+func (l *queryLexer) is(kind tokenKind, text string) bool {
+	return l.kind == kind && l.text == text
+}
+
+func (l *queryLexer) unexpected() error {
+	if l.kind == tokenEOF {
+		return errors.New("unexpected end of query")
+	}
+	return fmt.Errorf("unexpected %q at %d", l.text, l.pos)
+}
+
+// expectName 读取下一个名称, 并前进到名称之后
+func (l *queryLexer) expectName() (string, error) {
+	if err := l.next(); err != nil {
+		return "", err
+	}
+	if l.kind != tokenName {
+		return "", l.unexpected()
+	}
+	name := l.text
+	return name, l.next()
+}
+
+// expect 当前为指定符号时前进, 否则返回错误
+func (l *queryLexer) expect(punct string) error {
+	if !l.is(tokenPunct, punct) {
+		return l.unexpected()
+	}
+	return l.next()
+}
+
+func (l *queryLexer) directives() ([]*queryDirective, error) {
+	var directives []*queryDirective
+	for l.is(tokenPunct, "@") {
+		name, err := l.expectName()
+		if err != nil {
+			return nil, err
+		}
+		directive := &queryDirective{name: name}
+		if l.is(tokenPunct, "(") {
+			if directive.arguments, err = l.arguments(); err != nil {
+				return nil, err
+			}
+		}
+		directives = append(directives, directive)
+	}
+	return directives, nil
+}
+
+// variableDefinitions 解析操作的变量声明, 变量类型由graphql-go校验, 这里只做跳过
+func (l *queryLexer) variableDefinitions() ([]*queryVariableDefinition, error) {
+	if err := l.expect("("); err != nil {
+		return nil, err
+	}
+	var variables []*queryVariableDefinition
+	for !l.is(tokenPunct, ")") {
+		if !l.is(tokenPunct, "$") {
+			return nil, l.unexpected()
+		}
+		name, err := l.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if err := l.expect(":"); err != nil {
+			return nil, err
+		}
+		if err := l.typeRef(); err != nil {
+			return nil, err
+		}
+		variable := &queryVariableDefinition{name: name}
+		if l.is(tokenPunct, "=") {
+			if err := l.next(); err != nil {
+				return nil, err
+			}
+			if variable.defaultValue, err = l.value(); err != nil {
+				return nil, err
+			}
+			variable.hasDefault = true
+		}
+		if _, err := l.directives(); err != nil {
+			return nil, err
+		}
+		variables = append(variables, variable)
+	}
+	return variables, l.next()
+}
+
+// typeRef 跳过变量类型, 例如[String!]!
+func (l *queryLexer) typeRef() error {
+	switch {
+	case l.is(tokenPunct, "["):
+		if err := l.next(); err != nil {
+			return err
+		}
+		if err := l.typeRef(); err != nil {
+			return err
+		}
+		if err := l.expect("]"); err != nil {
+			return err
+		}
+	case l.kind == tokenName:
+		if err := l.next(); err != nil {
+			return err
+		}
+	default:
+		return l.unexpected()
+	}
+	if l.is(tokenPunct, "!") {
+		return l.next()
+	}
+	return nil
+}
+
+func (l *queryLexer) selectionSet() ([]*querySelection, error) {
+	if err := l.expect("{"); err != nil {
+		return nil, err
+	}
+	var selections []*querySelection
+	for !l.is(tokenPunct, "}") {
+		sel, err := l.selection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, sel)
+	}
+	return selections, l.next()
+}
+
+func (l *queryLexer) selection() (*querySelection, error) {
+	sel := &querySelection{}
+	if l.is(tokenPunct, "...") {
+		if err := l.next(); err != nil {
+			return nil, err
+		}
+		switch {
+		case l.is(tokenName, "on"):
+			typeCondition, err := l.expectName()
+			if err != nil {
+				return nil, err
+			}
+			sel.inline, sel.typeCondition = true, typeCondition
+		case l.kind == tokenName:
+			sel.spread, sel.name = true, l.text
+			if err := l.next(); err != nil {
+				return nil, err
+			}
+			directives, err := l.directives()
+			sel.directives = directives
+			return sel, err
+		default:
+			sel.inline = true
+		}
+		directives, err := l.directives()
+		if err != nil {
+			return nil, err
+		}
+		sel.directives = directives
+		selections, err := l.selectionSet()
+		sel.selections = selections
+		return sel, err
+	}
+
+	if l.kind != tokenName {
+		return nil, l.unexpected()
+	}
+	sel.name = l.text
+	if err := l.next(); err != nil {
+		return nil, err
+	}
+	if l.is(tokenPunct, ":") {
+		name, err := l.expectName()
+		if err != nil {
+			return nil, err
+		}
+		sel.alias, sel.name = sel.name, name
+	}
+	if l.is(tokenPunct, "(") {
+		arguments, err := l.arguments()
+		if err != nil {
+			return nil, err
+		}
+		sel.arguments = arguments
+	}
+	directives, err := l.directives()
+	if err != nil {
+		return nil, err
+	}
+	sel.directives = directives
+	if l.is(tokenPunct, "{") {
+		selections, err := l.selectionSet()
+		if err != nil {
+			return nil, err
+		}
+		sel.selections = selections
+	}
+	return sel, nil
+}
+
+func (l *queryLexer) arguments() (map[string]any, error) {
+	if err := l.expect("("); err != nil {
+		return nil, err
+	}
+	arguments := make(map[string]any)
+	for !l.is(tokenPunct, ")") {
+		if l.kind != tokenName {
+			return nil, l.unexpected()
+		}
+		name := l.text
+		if err := l.next(); err != nil {
+			return nil, err
+		}
+		if err := l.expect(":"); err != nil {
+			return nil, err
+		}
+		value, err := l.value()
+		if err != nil {
+			return nil, err
+		}
+		arguments[name] = value
+	}
+	return arguments, l.next()
+}
+
+// value 解析参数值, 变量为queryVariable, 整数为int64, 列表为[]any, 对象为map[string]any, 枚举为字符串
+func (l *queryLexer) value() (any, error) {
+	var value any
+	switch {
+	case l.is(tokenPunct, "$"):
+		name, err := l.expectName()
+		return queryVariable(name), err
+	case l.is(tokenPunct, "["):
+		list := make([]any, 0)
+		if err := l.next(); err != nil {
+			return nil, err
+		}
+		for !l.is(tokenPunct, "]") {
+			item, err := l.value()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+		}
+		return list, l.next()
+	case l.is(tokenPunct, "{"):
+		if err := l.next(); err != nil {
+			return nil, err
+		}
+		object := make(map[string]any)
+		for !l.is(tokenPunct, "}") {
+			if l.kind != tokenName {
+				return nil, l.unexpected()
+			}
+			name := l.text
+			if err := l.next(); err != nil {
+				return nil, err
+			}
+			if err := l.expect(":"); err != nil {
+				return nil, err
+			}
+			item, err := l.value()
+			if err != nil {
+				return nil, err
+			}
+			object[name] = item
+		}
+		return object, l.next()
+	case l.kind == tokenInt:
+		n, err := strconv.ParseInt(l.text, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		value = n
+	case l.kind == tokenFloat:
+		f, err := strconv.ParseFloat(l.text, 64)
+		if err != nil {
+			return nil, err
+		}
+		value = f
+	case l.kind == tokenString:
+		s, err := stringValue(l.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid string %s at %d: %w", l.text, l.pos, err)
+		}
+		value = s
+	case l.is(tokenName, "true"), l.is(tokenName, "false"):
+		value = l.text == "true"
+	case l.is(tokenName, "null"):
+		value = nil
+	case l.kind == tokenName:
+		value = l.text
+	default:
+		return nil, l.unexpected()
+	}
+	return value, l.next()
+}
+
+// next 读取下一个词法单元, 跳过空白、逗号和注释
+func (l *queryLexer) next() error {
+	src := l.src
+	for l.pos < len(src) {
+		c := src[l.pos]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			l.pos++
+			continue
+		}
+		if strings.HasPrefix(src[l.pos:], "\ufeff") {
+			l.pos += len("\ufeff")
+			continue
+		}
+		if c == '#' {
+			for l.pos < len(src) && src[l.pos] != '\n' {
+				l.pos++
+			}
+			continue
+		}
+		break
+	}
+	if l.pos >= len(src) {
+		l.kind, l.text = tokenEOF, ""
+		return nil
+	}
+
+	start := l.pos
+	c := src[l.pos]
+	switch {
+	case strings.HasPrefix(src[l.pos:], "..."):
+		l.pos += 3
+		l.kind = tokenPunct
+	case strings.IndexByte("!$&():=@[]{|}", c) >= 0:
+		l.pos++
+		l.kind = tokenPunct
+	case isNameStart(c):
+		for l.pos < len(src) && isNameChar(src[l.pos]) {
+			l.pos++
+		}
+		l.kind = tokenName
+	case c == '-' || (c >= '0' && c <= '9'):
+		l.kind = tokenInt
+		l.pos++
+		for l.pos < len(src) {
+			c := src[l.pos]
+			switch {
+			case c >= '0' && c <= '9':
+			case c == '.' || c == 'e' || c == 'E':
+				l.kind = tokenFloat
+			case (c == '+' || c == '-') && (src[l.pos-1] == 'e' || src[l.pos-1] == 'E'):
+			default:
+				l.text = src[start:l.pos]
+				return nil
+			}
+			l.pos++
+		}
+	case c == '"':
+		l.pos = skipString(src, l.pos)
+		l.kind = tokenString
+	default:
+		return fmt.Errorf("unexpected character %q at %d", c, l.pos)
+	}
+	l.text = src[start:l.pos]
+	return nil
+}
+
+// stringValue 去掉字符串的引号并处理转义, 与graphql-go一致使用Go的转义规则
+func stringValue(raw string) (string, error) {
+	if strings.HasPrefix(raw, `"""`) {
+		return blockStringValue(strings.TrimSuffix(raw[3:], `"""`)), nil
+	}
+	return strconv.Unquote(raw)
+}
+
+// blockStringValue 块字符串去掉公共缩进以及首尾的空行
+func blockStringValue(raw string) string {
+	raw = strings.NewReplacer(`\"""`, `"""`, "\r\n", "\n", "\r", "\n").Replace(raw)
+	lines := strings.Split(raw, "\n")
+	indent := -1
+	for _, line := range lines[1:] {
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if n < len(line) && (indent < 0 || n < indent) {
+			indent = n
+		}
+	}
+	for i := 1; i < len(lines) && indent > 0; i++ {
+		if len(lines[i]) < indent {
+			lines[i] = ""
+			continue
+		}
+		lines[i] = lines[i][indent:]
+	}
+	for len(lines) > 0 && strings.TrimLeft(lines[0], " \t") == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimLeft(lines[len(lines)-1], " \t") == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,202 @@
+package ginplus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+const testLimitsSchema = `
+schema {
+	query: Query
+}
+type Query {
+	user: User!
+	users(first: Int!): [User!]! @cost(complexity: 2, multipliers: ["first"])
+	top(first: Int! = 50): [User!]! @cost(multipliers: ["first"])
+	slow: String!
+}
+type User {
+	id: ID!
+	name: String!
+	friends(first: Int!): [User!]! @cost(multipliers: ["first"])
+}
+`
+
+type testLimitsRoot struct{}
+
+type testLimitsUser struct {
+	ID   graphql.ID
+	Name string
+}
+
+func (u *testLimitsUser) Friends(args struct{ First int32 }) []*testLimitsUser {
+	return testLimitsUsers(args.First)
+}
+
+func (r *testLimitsRoot) User() *testLimitsUser {
+	return &testLimitsUser{ID: "1", Name: "user"}
+}
+
+func (r *testLimitsRoot) Users(args struct{ First int32 }) []*testLimitsUser {
+	return testLimitsUsers(args.First)
+}
+
+func (r *testLimitsRoot) Top(args struct{ First int32 }) []*testLimitsUser {
+	return testLimitsUsers(args.First)
+}
+
+func (r *testLimitsRoot) Slow(ctx context.Context) (string, error) {
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func testLimitsUsers(n int32) []*testLimitsUser {
+	users := make([]*testLimitsUser, n)
+	for i := range users {
+		users[i] = &testLimitsUser{ID: "1", Name: "user"}
+	}
+	return users
+}
+
+func TestGraphqlHandler_Limits(t *testing.T) {
+	schema := graphql.MustParseSchema(withCostDirective(testLimitsSchema), &testLimitsRoot{}, graphql.UseFieldResolvers())
+	registry := prometheus.NewRegistry()
+	h := NewGraphqlHandler(schema,
+		WithGraphqlMaxDepth(3),
+		WithGraphqlMaxComplexity(20),
+		WithGraphqlTimeout(20*time.Millisecond),
+		WithGraphqlDisableIntrospection(),
+		WithGraphqlRegisterer(registry),
+	)
+	do := func(query string, variables map[string]any) (data json.RawMessage, code string) {
+		t.Helper()
+		body, _ := json.Marshal(map[string]any{"query": query, "variables": variables})
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("POST", "/graphql", strings.NewReader(string(body))))
+		var resp struct {
+			Data   json.RawMessage `json:"data"`
+			Errors []struct {
+				Message    string         `json:"message"`
+				Extensions map[string]any `json:"extensions"`
+			} `json:"errors"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: %v", w.Body.String(), err)
+		}
+		for _, e := range resp.Errors {
+			if c, ok := e.Extensions["code"].(string); ok {
+				code = c
+			}
+		}
+		return resp.Data, code
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]any
+		code      string
+	}{
+		// 2 + 5*(1+1) = 12
+		{name: "within complexity", query: `{ users(first: 5) { id name } }`},
+		// 2 + 10*(1+1) = 22
+		{name: "complexity", query: `{ users(first: 10) { id name } }`, code: "QUERY_TOO_COMPLEX"},
+		{name: "complexity escaped string", query: `{ users(first: 10, q: "\a\x41") { id name } }`, code: "QUERY_TOO_COMPLEX"},
+		{name: "parse error", query: `{ users(first: 1) { id `, code: "GRAPHQL_PARSE_FAILED"},
+		{name: "complexity variable", query: `query Q($n: Int!) { users(first: $n) { id } }`, variables: map[string]any{"n": 50}, code: "QUERY_TOO_COMPLEX"},
+		// 未传入的变量和参数使用默认值计算复杂度
+		{name: "complexity variable default", query: `query Q($n: Int = 100000) { users(first: $n) { id } }`, code: "QUERY_TOO_COMPLEX"},
+		{name: "complexity argument default", query: `{ top { id } }`, code: "QUERY_TOO_COMPLEX"},
+		{name: "argument default overridden", query: `{ top(first: 3) { id } }`},
+		{name: "complexity fragment", query: `query { users(first: 4) { ...F } } fragment F on User { id name friends(first: 2) { id } }`, code: "QUERY_TOO_COMPLEX"},
+		{name: "complexity inline fragment", query: `{ users(first: 4) { ... on User { friends(first: 5) { id } } } }`, code: "QUERY_TOO_COMPLEX"},
+		{name: "depth", query: `{ user { friends(first: 1) { friends(first: 1) { id } } } }`, code: "MAX_DEPTH_EXCEEDED"},
+		{name: "depth fragment", query: `{ user { ...F } } fragment F on User { friends(first: 1) { friends(first: 1) { id } } }`, code: "MAX_DEPTH_EXCEEDED"},
+		{name: "introspection", query: `{ __schema { types { name } } }`, code: "INTROSPECTION_DISABLED"},
+		{name: "typename", query: `{ __typename user { __typename id } }`},
+		{name: "timeout", query: `{ slow }`, code: "QUERY_TIMEOUT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, code := do(tt.query, tt.variables)
+			if code != tt.code {
+				t.Fatalf("code = %q, want %q, data %s", code, tt.code, data)
+			}
+			if tt.code == "" && len(data) == 0 {
+				t.Fatal("missing data")
+			}
+		})
+	}
+
+	for reason, want := range map[string]float64{
+		rejectComplexity:    7,
+		rejectDepth:         2,
+		rejectIntrospection: 1,
+		rejectTimeout:       1,
+		rejectParse:         1,
+	} {
+		if got := testutil.ToFloat64(h.rejected.WithLabelValues(reason)); got != want {
+			t.Errorf("rejected{reason=%q} = %v, want %v", reason, got, want)
+		}
+	}
+}
+
+func Test_parseQueryDocument(t *testing.T) {
+	doc, err := parseQueryDocument(`
+		# comment { not a selection
+		query Users($first: Int = 10, $ids: [ID!]) @live {
+			list: users(first: $first, filter: {name: "a}b", tags: ["x"]}, note: """block { """, esc: "\a\x41\u00e9") {
+				...UserFields @include(if: true)
+				... on User { id }
+				... @skip(if: false) { name }
+			}
+		}
+		fragment UserFields on User { id, name }
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	op := doc.operation("")
+	if op == nil || op.typ != operationQuery || op.name != "Users" {
+		t.Fatalf("operation = %+v", op)
+	}
+	users := op.selections[0]
+	if users.name != "users" || users.arguments["first"] != queryVariable("first") || len(users.selections) != 3 {
+		t.Fatalf("users = %+v", users)
+	}
+	if users.alias != "list" || users.arguments["note"] != "block { " || users.arguments["esc"] != "\aA\u00e9" {
+		t.Errorf("users = %+v", users)
+	}
+	if filter, _ := users.arguments["filter"].(map[string]any); filter["name"] != "a}b" || len(filter["tags"].([]any)) != 1 {
+		t.Errorf("filter = %+v", users.arguments["filter"])
+	}
+	if v := op.variables; len(v) != 2 || v[0].name != "first" || v[0].defaultValue != int64(10) || v[1].hasDefault {
+		t.Errorf("variables = %+v", v)
+	}
+	if sel := users.selections[0]; !sel.spread || sel.name != "UserFields" || len(sel.directives) != 1 || sel.directives[0].arguments["if"] != true {
+		t.Errorf("spread = %+v", sel)
+	}
+	if sel := users.selections[1]; !sel.inline || sel.typeCondition != "User" {
+		t.Errorf("inline = %+v", sel)
+	}
+	if sel := users.selections[2]; !sel.inline || sel.typeCondition != "" {
+		t.Errorf("inline without type = %+v", sel)
+	}
+	if frag := doc.fragments["UserFields"]; frag == nil || frag.typeCondition != "User" || len(frag.selections) != 2 {
+		t.Errorf("fragment = %+v", frag)
+	}
+
+	for _, query := range []string{`{ users(first: ) }`, `{ users`, `query ($a: Int { id }`, `{ a } %`} {
+		if _, err := parseQueryDocument(query); err == nil {
+			t.Errorf("parseQueryDocument(%q) expected error", query)
+		}
+	}
+}
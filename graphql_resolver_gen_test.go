@@ -0,0 +1,63 @@
+// Code generated by ginplus GenerateGraphqlResolver. DO NOT EDIT.
+
+package ginplus
+
+import (
+	"context"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+// GraphqlResolver controller生成的graphql根节点, 通过GraphqlConfig.Root注册
+type GraphqlResolver struct {
+	ControllerResolver
+}
+
+func (r *GraphqlResolver) GetDetail(ctx context.Context, args struct {
+	Id      *int32 `json:"id,omitempty"`
+	Verbose *bool  `json:"verbose,omitempty"`
+}) (*gqlUserDetailRespObject, error) {
+	var out *gqlUserDetailRespObject
+	err := r.ResolveController(ctx, "Query", "getDetail", args, &out)
+	return out, err
+}
+
+func (r *GraphqlResolver) GetFail(ctx context.Context) (*gqlUserItemObject, error) {
+	var out *gqlUserItemObject
+	err := r.ResolveController(ctx, "Query", "getFail", nil, &out)
+	return out, err
+}
+
+func (r *GraphqlResolver) PostUpdate(ctx context.Context, args struct {
+	Id     *int32             `json:"id,omitempty"`
+	Filter gqlUserFilterInput `json:"filter"`
+	Names  *[]string          `json:"names,omitempty"`
+}) (*gqlUserUpdateRespObject, error) {
+	var out *gqlUserUpdateRespObject
+	err := r.ResolveController(ctx, "Mutation", "postUpdate", args, &out)
+	return out, err
+}
+
+type gqlUserDetailRespObject struct {
+	Id      int32                `json:"id"`
+	Name    string               `json:"name"`
+	Tags    []string             `json:"tags"`
+	Items   []*gqlUserItemObject `json:"items"`
+	Extra   *GraphqlJSON         `json:"extra"`
+	Created graphql.Time         `json:"created"`
+}
+
+type gqlUserFilterInput struct {
+	Keyword *string `json:"keyword,omitempty"`
+}
+
+type gqlUserItemObject struct {
+	Label string  `json:"label"`
+	Score float64 `json:"score"`
+}
+
+type gqlUserUpdateRespObject struct {
+	Id      int32    `json:"id"`
+	Keyword string   `json:"keyword"`
+	Names   []string `json:"names"`
+}
@@ -0,0 +1,492 @@
+package ginplus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+var (
+	contextType      = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType        = reflect.TypeOf((*error)(nil)).Elem()
+	graphqlIDType    = reflect.TypeOf(graphql.ID(""))
+	graphqlTimeType  = reflect.TypeOf(graphql.Time{})
+	timeType         = reflect.TypeOf(time.Time{})
+	errNoQueryFields = errors.New("schema requires at least one query field")
+
+	// schemaSkipMethods 常见接口的方法, 不作为字段
+	schemaSkipMethods = map[string]struct{}{
+		"String":        {},
+		"GoString":      {},
+		"Error":         {},
+		"MarshalJSON":   {},
+		"UnmarshalJSON": {},
+		"MarshalText":   {},
+		"UnmarshalText": {},
+	}
+)
+
+type (
+	// SchemaOption code-first schema生成配置
+	SchemaOption func(*schemaGenerator)
+
+	// schemaGenerator 根据Go类型生成SDL
+	schemaGenerator struct {
+		// 判断根节点的方法是否为Mutation字段
+		isMutation func(method string) bool
+		// 根据controller的请求和响应结构体生成: 字段名取自json、form或uri标签, 所有数值类型都可以使用,
+		// 对象只包含结构体字段, 参数只在binding:"required"时非空
+		tagged bool
+
+		// 已生成的类型名称, input和output分开记录
+		outputs map[reflect.Type]string
+		inputs  map[reflect.Type]string
+		// 类型名称对应的Go类型, 用于检查重名
+		named map[string]reflect.Type
+		// 按生成顺序保存的类型定义
+		defs    []string
+		scalars map[string]struct{}
+	}
+
+	// schemaField 生成的字段
+	schemaField struct {
+		name string
+		def  string
+		// 返回channel的方法为订阅字段
+		subscription bool
+	}
+)
+
+// WithSchemaMutations 指定根节点中作为Mutation字段的方法名
+func WithSchemaMutations(methods ...string) SchemaOption {
+	return func(g *schemaGenerator) {
+		set := make(map[string]struct{}, len(methods))
+		for _, method := range methods {
+			set[method] = struct{}{}
+		}
+		g.isMutation = func(method string) bool {
+			_, ok := set[method]
+			return ok
+		}
+	}
+}
+
+// WithSchemaMutationFunc 自定义根节点的方法是否作为Mutation字段
+func WithSchemaMutationFunc(fn func(method string) bool) SchemaOption {
+	return func(g *schemaGenerator) {
+		g.isMutation = fn
+	}
+}
+
+// GenerateSchema 根据根节点的方法生成SDL(code-first), 可以直接用于graphql-go解析
+//
+// 根节点的方法签名为func([context.Context], [args struct或*struct]) (T, [error]),
+// 返回channel的方法为Subscription字段, WithSchemaMutations指定的方法为Mutation字段, 其余为Query字段.
+// 字段名为方法名或结构体字段名的lowerCamel形式, 指针类型可为空, 其余类型非空;
+// Int只能是int32, Float只能是float64, 与graphql-go的要求一致. 字段可以用graphql:"-"忽略
+func GenerateSchema(root any, opts ...SchemaOption) (string, error) {
+	rt := reflect.TypeOf(root)
+	if rt == nil {
+		return "", errors.New("schema root is nil")
+	}
+	g := newSchemaGenerator(opts...)
+
+	var query, mutation, subscription []string
+	for i := 0; i < rt.NumMethod(); i++ {
+		method := rt.Method(i)
+		field, ok, err := g.field(method.Name, method.Type, 1)
+		if err != nil {
+			return "", fmt.Errorf("%s.%s: %w", rt, method.Name, err)
+		}
+		switch {
+		case !ok:
+		case field.subscription:
+			subscription = append(subscription, field.def)
+		case g.isMutation(method.Name):
+			mutation = append(mutation, field.def)
+		default:
+			query = append(query, field.def)
+		}
+	}
+	return g.print(query, mutation, subscription)
+}
+
+func newSchemaGenerator(opts ...SchemaOption) *schemaGenerator {
+	g := &schemaGenerator{
+		isMutation: func(string) bool { return false },
+		outputs:    make(map[reflect.Type]string),
+		inputs:     make(map[reflect.Type]string),
+		named:      make(map[string]reflect.Type),
+		scalars:    make(map[string]struct{}),
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// print 输出完整的SDL, 根类型在前, 其余类型按生成顺序
+func (g *schemaGenerator) print(query, mutation, subscription []string) (string, error) {
+	if len(query) == 0 {
+		return "", errNoQueryFields
+	}
+	var sb strings.Builder
+	sb.WriteString("schema {\n\tquery: Query\n")
+	if len(mutation) > 0 {
+		sb.WriteString("\tmutation: Mutation\n")
+	}
+	if len(subscription) > 0 {
+		sb.WriteString("\tsubscription: Subscription\n")
+	}
+	sb.WriteString("}\n")
+	writeType := func(kind, name string, fields []string) {
+		if len(fields) == 0 {
+			return
+		}
+		sb.WriteString("\n" + kind + " " + name + " {\n")
+		for _, field := range fields {
+			sb.WriteString("\t" + field + "\n")
+		}
+		sb.WriteString("}\n")
+	}
+	writeType("type", "Query", query)
+	writeType("type", "Mutation", mutation)
+	writeType("type", "Subscription", subscription)
+	for _, def := range g.defs {
+		sb.WriteString("\n" + def)
+	}
+	scalars := make([]string, 0, len(g.scalars))
+	for scalar := range g.scalars {
+		scalars = append(scalars, scalar)
+	}
+	sort.Strings(scalars)
+	for _, scalar := range scalars {
+		sb.WriteString("\nscalar " + scalar + "\n")
+	}
+	return sb.String(), nil
+}
+
+// field 根据resolver方法生成字段, 签名不符合要求时ok为false, in为跳过的参数数量(接收者)
+func (g *schemaGenerator) field(name string, ft reflect.Type, in int) (field schemaField, ok bool, err error) {
+	if _, skip := schemaSkipMethods[name]; skip {
+		return field, false, nil
+	}
+	if in < ft.NumIn() && ft.In(in) == contextType {
+		in++
+	}
+	var args []string
+	if in < ft.NumIn() {
+		argsType := ft.In(in)
+		if argsType.Kind() == reflect.Ptr {
+			argsType = argsType.Elem()
+		}
+		if argsType.Kind() != reflect.Struct {
+			return field, false, nil
+		}
+		if args, err = g.arguments(argsType); err != nil {
+			return field, false, err
+		}
+		in++
+	}
+	if in != ft.NumIn() || ft.NumOut() == 0 || ft.NumOut() > 2 || ft.Out(0) == errorType || (ft.NumOut() == 2 && ft.Out(1) != errorType) {
+		return field, false, nil
+	}
+
+	out := ft.Out(0)
+	if out.Kind() == reflect.Chan {
+		field.subscription = true
+		out = out.Elem()
+	}
+	typ, err := g.outputType(out)
+	if err != nil {
+		return field, false, err
+	}
+	field.name = lowerCamel(name)
+	field.def = field.name
+	if len(args) > 0 {
+		field.def += "(" + strings.Join(args, ", ") + ")"
+	}
+	field.def += ": " + typ
+	return field, true, nil
+}
+
+// arguments 根据参数结构体的导出字段生成参数列表
+func (g *schemaGenerator) arguments(t reflect.Type) ([]string, error) {
+	var args []string
+	for _, f := range exportedFields(t) {
+		name, err := g.fieldName(f)
+		if err != nil {
+			return nil, err
+		}
+		if name == "" {
+			continue
+		}
+		typ, err := g.inputType(f.Type)
+		if err != nil {
+			return nil, fmt.Errorf("argument %s: %w", f.Name, err)
+		}
+		if g.tagged {
+			typ = strings.TrimSuffix(typ, "!")
+			if isRequired(f) {
+				typ += "!"
+			}
+		}
+		args = append(args, name+": "+typ)
+	}
+	return args, nil
+}
+
+// fieldName 结构体字段对应的graphql字段名, 为空时忽略该字段
+func (g *schemaGenerator) fieldName(f reflect.StructField) (string, error) {
+	if !g.tagged {
+		return lowerCamel(f.Name), nil
+	}
+	name := taggedFieldName(f)
+	if name != "" && !isGraphqlName(name) {
+		return "", fmt.Errorf("field %s: invalid graphql name %q", f.Name, name)
+	}
+	return name, nil
+}
+
+// outputType Go类型对应的输出类型, 非指针类型为非空, tagged模式下map和interface可为空
+func (g *schemaGenerator) outputType(t reflect.Type) (string, error) {
+	nullable := t.Kind() == reflect.Ptr || (g.tagged && (t.Kind() == reflect.Map || t.Kind() == reflect.Interface))
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	typ := g.scalarType(t)
+	var err error
+	switch {
+	case typ != "":
+	case t.Kind() == reflect.Slice:
+		var elem string
+		if elem, err = g.outputType(t.Elem()); err == nil {
+			typ = "[" + elem + "]"
+		}
+	case t.Kind() == reflect.Struct:
+		typ, err = g.object(t)
+	default:
+		err = g.unsupported("output", t)
+	}
+	if err != nil || nullable {
+		return typ, err
+	}
+	return typ + "!", nil
+}
+
+// inputType Go类型对应的输入类型, 结构体生成input类型
+func (g *schemaGenerator) inputType(t reflect.Type) (string, error) {
+	nullable := t.Kind() == reflect.Ptr
+	if nullable {
+		t = t.Elem()
+	}
+	typ := g.scalarType(t)
+	var err error
+	switch {
+	case typ != "":
+	case t.Kind() == reflect.Slice:
+		var elem string
+		if elem, err = g.inputType(t.Elem()); err == nil {
+			typ = "[" + elem + "]"
+		}
+	case t.Kind() == reflect.Struct:
+		typ, err = g.input(t)
+	default:
+		err = g.unsupported("input", t)
+	}
+	if err != nil || nullable {
+		return typ, err
+	}
+	return typ + "!", nil
+}
+
+// scalarType Go类型对应的标量类型, 不是标量时为空
+func (g *schemaGenerator) scalarType(t reflect.Type) string {
+	switch {
+	case t == graphqlIDType:
+		return "ID"
+	case t == graphqlTimeType, g.tagged && t == timeType:
+		return g.scalar("Time")
+	case t.Kind() == reflect.String:
+		return "String"
+	case t.Kind() == reflect.Bool:
+		return "Boolean"
+	case t.Kind() == reflect.Int32:
+		return "Int"
+	case t.Kind() == reflect.Float64:
+		return "Float"
+	case !g.tagged:
+		return ""
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "Int"
+	case reflect.Float32:
+		return "Float"
+	case reflect.Slice:
+		// []byte与encoding/json一致, 使用base64字符串
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "String"
+		}
+	case reflect.Map, reflect.Interface:
+		return g.scalar("JSON")
+	}
+	return ""
+}
+
+func (g *schemaGenerator) unsupported(kind string, t reflect.Type) error {
+	if g.tagged {
+		return fmt.Errorf("unsupported %s type %s", kind, t)
+	}
+	return fmt.Errorf("unsupported %s type %s, use int32 for Int and float64 for Float", kind, t)
+}
+
+// object 生成对象类型, 字段为resolver方法和导出的结构体字段, 同名时方法优先
+func (g *schemaGenerator) object(t reflect.Type) (string, error) {
+	if name, ok := g.outputs[t]; ok {
+		return name, nil
+	}
+	name, err := g.register(t, t.Name())
+	if err != nil {
+		return "", err
+	}
+	// 先记录名称, 支持自引用的类型
+	g.outputs[t] = name
+
+	var fields []string
+	seen := make(map[string]struct{})
+	pt := reflect.PtrTo(t)
+	for i := 0; i < pt.NumMethod() && !g.tagged; i++ {
+		method := pt.Method(i)
+		field, ok, err := g.field(method.Name, method.Type, 1)
+		if err != nil {
+			return "", fmt.Errorf("%s.%s: %w", t, method.Name, err)
+		}
+		if !ok || field.subscription {
+			continue
+		}
+		seen[strings.ToLower(field.name)] = struct{}{}
+		fields = append(fields, field.def)
+	}
+	for _, f := range exportedFields(t) {
+		fieldName, err := g.fieldName(f)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", t, err)
+		}
+		if _, ok := seen[strings.ToLower(fieldName)]; ok || fieldName == "" {
+			continue
+		}
+		typ, err := g.outputType(f.Type)
+		if err != nil {
+			return "", fmt.Errorf("%s.%s: %w", t, f.Name, err)
+		}
+		fields = append(fields, g.description(f)+fieldName+": "+typ)
+	}
+	if len(fields) == 0 {
+		return "", fmt.Errorf("type %s has no fields", t)
+	}
+	g.defs = append(g.defs, "type "+name+" {\n\t"+strings.Join(fields, "\n\t")+"\n}\n")
+	return name, nil
+}
+
+// input 生成输入类型, 名称以Input结尾
+func (g *schemaGenerator) input(t reflect.Type) (string, error) {
+	if name, ok := g.inputs[t]; ok {
+		return name, nil
+	}
+	name := t.Name()
+	if !strings.HasSuffix(name, "Input") {
+		name += "Input"
+	}
+	name, err := g.register(t, name)
+	if err != nil {
+		return "", err
+	}
+	g.inputs[t] = name
+
+	fields, err := g.arguments(t)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", t, err)
+	}
+	if len(fields) == 0 {
+		return "", fmt.Errorf("input %s has no fields", t)
+	}
+	g.defs = append(g.defs, "input "+name+" {\n\t"+strings.Join(fields, "\n\t")+"\n}\n")
+	return name, nil
+}
+
+// register 检查类型名称是否合法以及是否与其他Go类型重名
+func (g *schemaGenerator) register(t reflect.Type, name string) (string, error) {
+	if !isGraphqlName(name) {
+		return "", fmt.Errorf("can not derive a graphql type name from %s", t)
+	}
+	if other, ok := g.named[name]; ok && other != t {
+		return "", fmt.Errorf("graphql type %s is used by both %s and %s", name, other, t)
+	}
+	switch name {
+	case "Query", "Mutation", "Subscription":
+		return "", fmt.Errorf("graphql type name %s of %s is reserved", name, t)
+	}
+	g.named[name] = t
+	return name, nil
+}
+
+// description tagged模式下desc标签作为字段的描述, 使用注释以兼容graphql-go的默认配置
+func (g *schemaGenerator) description(f reflect.StructField) string {
+	desc := strings.Join(strings.Fields(fieldTag(f).Desc), " ")
+	if !g.tagged || desc == "" {
+		return ""
+	}
+	return "# " + desc + "\n\t"
+}
+
+func (g *schemaGenerator) scalar(name string) string {
+	g.scalars[name] = struct{}{}
+	return name
+}
+
+// exportedFields 结构体导出的字段, 包括嵌入结构体提升的字段
+func exportedFields(t reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	for _, f := range reflect.VisibleFields(t) {
+		if f.Anonymous || !f.IsExported() || f.Tag.Get("graphql") == "-" {
+			continue
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func isGraphqlName(name string) bool {
+	if name == "" || strings.HasPrefix(name, "__") || !isNameStart(name[0]) {
+		return false
+	}
+	for i := 1; i < len(name); i++ {
+		if !isNameChar(name[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// lowerCamel 首字母小写, 开头的缩写整体小写, 例如ID为id, URLPath为urlPath
+func lowerCamel(s string) string {
+	n := 0
+	for n < len(s) && s[n] >= 'A' && s[n] <= 'Z' {
+		n++
+	}
+	switch {
+	case n == 0:
+		return s
+	case n > 1 && n < len(s):
+		n--
+	}
+	return strings.ToLower(s[:n]) + s[n:]
+}
@@ -0,0 +1,175 @@
+package ginplus
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graph-gophers/graphql-go"
+)
+
+type (
+	testCodeFirstRoot struct{}
+
+	testCodeFirstUser struct {
+		ID      graphql.ID
+		Name    string
+		Age     *int32
+		Created graphql.Time
+		Tags    []string
+		Secret  string `graphql:"-"`
+	}
+
+	testCodeFirstFilter struct {
+		Name  *string
+		Range *struct {
+			Min int32
+			Max int32
+		}
+	}
+
+	testCodeFirstPage struct {
+		Limit int32
+	}
+)
+
+func (u *testCodeFirstUser) Friends(args testCodeFirstPage) []*testCodeFirstUser {
+	return nil
+}
+
+func (r *testCodeFirstRoot) User(ctx context.Context, args struct{ ID graphql.ID }) (*testCodeFirstUser, error) {
+	return &testCodeFirstUser{ID: args.ID, Name: "user"}, nil
+}
+
+func (r *testCodeFirstRoot) Users(args struct{ Filter *testCodeFirstFilter }) []*testCodeFirstUser {
+	return nil
+}
+
+func (r *testCodeFirstRoot) CreateUser(args struct{ Name string }) *testCodeFirstUser {
+	return &testCodeFirstUser{ID: "2", Name: args.Name}
+}
+
+func (r *testCodeFirstRoot) Watch(ctx context.Context) <-chan *testCodeFirstUser {
+	return nil
+}
+
+// Close 不符合resolver签名, 不会生成字段
+func (r *testCodeFirstRoot) Close() error {
+	return nil
+}
+
+func TestGenerateSchema(t *testing.T) {
+	sdl, err := GenerateSchema(&testCodeFirstRoot{}, WithSchemaMutations("CreateUser"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"\tmutation: Mutation\n",
+		"\tsubscription: Subscription\n",
+		"user(id: ID!): testCodeFirstUser",
+		"users(filter: testCodeFirstFilterInput): [testCodeFirstUser]!",
+		"type Mutation {\n\tcreateUser(name: String!): testCodeFirstUser\n}",
+		"type Subscription {\n\twatch: testCodeFirstUser\n}",
+		"friends(limit: Int!): [testCodeFirstUser]!",
+		"age: Int\n",
+		"created: Time!",
+		"tags: [String!]!",
+		"scalar Time",
+	} {
+		if !strings.Contains(sdl, want) {
+			t.Errorf("schema missing %q:\n%s", want, sdl)
+		}
+	}
+	if strings.Contains(sdl, "secret") || strings.Contains(sdl, "close") {
+		t.Errorf("schema contains ignored fields:\n%s", sdl)
+	}
+
+	schema, err := graphql.ParseSchema(sdl, &testCodeFirstRoot{}, graphql.UseFieldResolvers())
+	if err != nil {
+		t.Fatalf("%v\n%s", err, sdl)
+	}
+	resp := schema.Exec(context.Background(), `mutation { createUser(name: "a") { id name } }`, "", nil)
+	if len(resp.Errors) > 0 || string(resp.Data) != `{"createUser":{"id":"2","name":"a"}}` {
+		t.Errorf("unexpected response %s %v", resp.Data, resp.Errors)
+	}
+
+	for name, root := range map[string]any{
+		"nil root":    nil,
+		"no query":    &struct{}{},
+		"int output":  &testCodeFirstBadRoot{},
+		"anonymous":   &testCodeFirstAnonymousRoot{},
+		"reserved":    &testCodeFirstReservedRoot{},
+		"duplication": &testCodeFirstDuplicateRoot{},
+	} {
+		if _, err := GenerateSchema(root); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+type (
+	testCodeFirstBadRoot       struct{}
+	testCodeFirstAnonymousRoot struct{}
+	testCodeFirstReservedRoot  struct{}
+	testCodeFirstDuplicateRoot struct{}
+
+	Query struct{ A string }
+)
+
+func (r *testCodeFirstBadRoot) Count() int { return 0 }
+
+func (r *testCodeFirstAnonymousRoot) Item() *struct{ A string } { return nil }
+
+func (r *testCodeFirstReservedRoot) Item() *Query { return nil }
+
+func (r *testCodeFirstDuplicateRoot) A() *testCodeFirstPage { return nil }
+
+func (r *testCodeFirstDuplicateRoot) B(args struct{ Page testCodeFirstPageInput }) string { return "" }
+
+type testCodeFirstPageInput struct{ Limit int32 }
+
+func (r *testCodeFirstDuplicateRoot) C(args struct{ Page testCodeFirstPage }) string { return "" }
+
+func TestGinEngine_RegisterGraphql(t *testing.T) {
+	sdl := "schema {\n\tquery: Query\n}\ntype Query {\n\thello(name: String!): String!\n}\n"
+	tests := []struct {
+		name   string
+		config *GraphqlConfig
+		err    bool
+	}{
+		{name: "string", config: &GraphqlConfig{Enable: true, Root: &testGraphqlRoot{}, Schema: sdl}},
+		{name: "fs", config: &GraphqlConfig{Enable: true, Root: &testGraphqlRoot{}, SchemaFS: fstest.MapFS{
+			"schema/query.graphql": {Data: []byte(sdl)},
+			"README.md":            {Data: []byte("# schema")},
+		}}},
+		{name: "code first", config: &GraphqlConfig{Enable: true, Root: &testCodeFirstRoot{}}},
+		{name: "invalid", config: &GraphqlConfig{Enable: true, Root: &testGraphqlRoot{}, Schema: "type Query { missing: String }"}, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New(gin.New())
+			engine.RegisterGraphql(tt.config)
+			if tt.err {
+				if err := engine.Start(); err == nil {
+					engine.Stop()
+					t.Fatal("expected start error")
+				}
+				return
+			}
+
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, httptest.NewRequest("GET", "/graphql/schema", nil))
+			if w.Code != 200 || !strings.Contains(w.Body.String(), "type Query") {
+				t.Fatalf("schema endpoint: %d %s", w.Code, w.Body.String())
+			}
+			w = httptest.NewRecorder()
+			engine.ServeHTTP(w, httptest.NewRequest("POST", "/graphql", strings.NewReader(`{"query":"{ __typename }"}`)))
+			if w.Body.String() != `{"data":{"__typename":"Query"}}` {
+				t.Fatalf("query: %s", w.Body.String())
+			}
+		})
+	}
+}
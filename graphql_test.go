@@ -0,0 +1,275 @@
+package ginplus
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+)
+
+const testGraphqlSchema = `
+schema {
+	query: Query
+	mutation: Mutation
+	subscription: Subscription
+}
+type Query {
+	hello(name: String!): String!
+}
+type Mutation {
+	incr: Int!
+}
+type Subscription {
+	ticks(n: Int!): Int!
+}
+`
+
+type testGraphqlRoot struct {
+	count int32
+}
+
+func (r *testGraphqlRoot) Hello(args struct{ Name string }) string {
+	return "hello " + args.Name
+}
+
+func (r *testGraphqlRoot) Incr() int32 {
+	r.count++
+	return r.count
+}
+
+// Ticks 依次推送1到n, n为0时持续推送直到取消
+func (r *testGraphqlRoot) Ticks(ctx context.Context, args struct{ N int32 }) <-chan int32 {
+	ch := make(chan int32)
+	go func() {
+		defer close(ch)
+		for i := int32(1); args.N == 0 || i <= args.N; i++ {
+			select {
+			case ch <- i:
+			case <-ctx.Done():
+				return
+			}
+			if args.N == 0 {
+				time.Sleep(10 * time.Millisecond)
+			}
+		}
+	}()
+	return ch
+}
+
+func newTestGraphqlHandler(t *testing.T, opts ...GraphqlHandlerOption) *GraphqlHandler {
+	t.Helper()
+	schema, err := graphql.ParseSchema(testGraphqlSchema, &testGraphqlRoot{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewGraphqlHandler(schema, opts...)
+}
+
+func TestGraphqlHandler(t *testing.T) {
+	h := newTestGraphqlHandler(t, WithGraphqlMaxBatchSize(2))
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return w
+	}
+	get := func(params url.Values) *httptest.ResponseRecorder {
+		return do(http.MethodGet, "/graphql?"+params.Encode(), "")
+	}
+
+	tests := []struct {
+		name     string
+		w        *httptest.ResponseRecorder
+		status   int
+		contains string
+	}{
+		{
+			name:     "get query",
+			w:        get(url.Values{"query": {`query Hi($n: String!) { hello(name: $n) }`}, "variables": {`{"n":"gin"}`}}),
+			status:   http.StatusOK,
+			contains: `"hello":"hello gin"`,
+		},
+		{
+			name:     "get mutation",
+			w:        get(url.Values{"query": {"# comment {\nmutation { incr }"}}),
+			status:   http.StatusMethodNotAllowed,
+			contains: "allowed over GET",
+		},
+		{
+			name:     "get named mutation",
+			w:        get(url.Values{"query": {`query A { hello(name: "mutation") } mutation B { incr }`}, "operationName": {"B"}}),
+			status:   http.StatusMethodNotAllowed,
+			contains: "allowed over GET",
+		},
+		{
+			name:     "get mutation with object default",
+			w:        get(url.Values{"query": {`mutation M($a: In = {k: 1}) { incr }`}}),
+			status:   http.StatusMethodNotAllowed,
+			contains: "allowed over GET",
+		},
+		{
+			name:     "get unparsable",
+			w:        get(url.Values{"query": {`mutation { incr`}}),
+			status:   http.StatusMethodNotAllowed,
+			contains: "allowed over GET",
+		},
+		{
+			name:     "post mutation",
+			w:        do(http.MethodPost, "/graphql", `{"query":"mutation { incr }"}`),
+			status:   http.StatusOK,
+			contains: `"incr":1`,
+		},
+		{
+			name:     "batch",
+			w:        do(http.MethodPost, "/graphql", `[{"query":"{ hello(name: \"a\") }"}, {"query":"mutation { incr }"}]`),
+			status:   http.StatusOK,
+			contains: `[{"data":{"hello":"hello a"}},{"data":{"incr":2}}]`,
+		},
+		{
+			name:     "batch too large",
+			w:        do(http.MethodPost, "/graphql", `[{"query":"{ hello(name: \"a\") }"}, {"query":"{ hello(name: \"b\") }"}, {"query":"{ hello(name: \"c\") }"}]`),
+			status:   http.StatusBadRequest,
+			contains: "batch size",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.w.Code != tt.status {
+				t.Errorf("want status %d, got %d", tt.status, tt.w.Code)
+			}
+			if !strings.Contains(tt.w.Body.String(), tt.contains) {
+				t.Errorf("want body containing %s, got %s", tt.contains, tt.w.Body.String())
+			}
+		})
+	}
+}
+
+func TestGraphqlHandler_PersistedQuery(t *testing.T) {
+	h := newTestGraphqlHandler(t)
+	query := `{ hello(name: "apq") }`
+	sum := sha256.Sum256([]byte(query))
+	extensions := `{"persistedQuery":{"version":1,"sha256Hash":"` + hex.EncodeToString(sum[:]) + `"}}`
+	get := func(params url.Values) map[string]any {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/graphql?"+params.Encode(), nil))
+		var resp map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := get(url.Values{"extensions": {extensions}})
+	if !strings.Contains(toJSON(t, resp), "PERSISTED_QUERY_NOT_FOUND") {
+		t.Fatalf("want persisted query not found, got %v", resp)
+	}
+	resp = get(url.Values{"extensions": {extensions}, "query": {query}})
+	if resp["data"] == nil {
+		t.Fatalf("want registered query executed, got %v", resp)
+	}
+	resp = get(url.Values{"extensions": {extensions}})
+	if !strings.Contains(toJSON(t, resp), "hello apq") {
+		t.Fatalf("want persisted query executed by hash, got %v", resp)
+	}
+	resp = get(url.Values{"extensions": {extensions}, "query": {`{ hello(name: "other") }`}})
+	if !strings.Contains(toJSON(t, resp), "PERSISTED_QUERY_INVALID") {
+		t.Fatalf("want hash mismatch, got %v", resp)
+	}
+}
+
+func toJSON(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestLRUPersistedQueryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewLRUPersistedQueryStore(2)
+	store.Set(ctx, "a", "A")
+	store.Set(ctx, "b", "B")
+	store.Get(ctx, "a")
+	store.Set(ctx, "c", "C")
+	if _, ok := store.Get(ctx, "b"); ok {
+		t.Error("want least recently used entry evicted")
+	}
+	if query, ok := store.Get(ctx, "a"); !ok || query != "A" {
+		t.Errorf("want a kept, got %q %v", query, ok)
+	}
+}
+
+func Test_operationType(t *testing.T) {
+	tests := []struct {
+		query, operationName, want string
+	}{
+		{query: `{ a }`, want: operationQuery},
+		{query: `mutation { a }`, want: operationMutation},
+		{query: `subscription S($v: Int = 1) @live { a(s: "mutation {") }`, want: operationSubscription},
+		{query: `query Q { a(s: """mutation {""") ...F } fragment F on Query { a }`, want: operationQuery},
+		{query: `query A { a } mutation B { b }`, operationName: "B", want: operationMutation},
+		{query: `query A { a } mutation B { b }`, want: ""},
+		{query: `mutation M($a: In = {k: 1}) { inc(x: $a) }`, want: operationMutation},
+		{query: `query { a`, want: ""},
+	}
+	for _, tt := range tests {
+		if got := operationType(tt.query, tt.operationName); got != tt.want {
+			t.Errorf("operationType(%q, %q) = %q, want %q", tt.query, tt.operationName, got, tt.want)
+		}
+	}
+}
+
+func TestView(t *testing.T) {
+	w := httptest.NewRecorder()
+	View("/api/graphql")(w, httptest.NewRequest("GET", "/view", nil))
+	body := w.Body.String()
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(body, `window.GRAPHQL_EXPLORER_CONFIG = {"endpoint":"/api/graphql"};`) {
+		t.Error("missing endpoint config")
+	}
+	if strings.Contains(body, "{{") || strings.Contains(body, "https://") {
+		t.Error("page is not self-contained")
+	}
+}
+
+//go:embed testdata/*.graphql
+var testGraphqlSchemaFS embed.FS
+
+func TestHandler(t *testing.T) {
+	query := `{"query":"{ hello(name: \"gin\") }"}`
+	// Handler保持返回relay.Handler
+	var relayHandler *relay.Handler = Handler(&testGraphqlRoot{}, testGraphqlSchemaFS)
+	w := httptest.NewRecorder()
+	relayHandler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(query)))
+	if !strings.Contains(w.Body.String(), `"hello":"hello gin"`) {
+		t.Errorf("unexpected relay response %s", w.Body.String())
+	}
+
+	h, err := NewHandlerFS(&testGraphqlRoot{}, testGraphqlSchemaFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// NewHandlerFS支持GET请求
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/graphql?"+url.Values{"query": {`{ hello(name: "gin") }`}}.Encode(), nil))
+	if !strings.Contains(w.Body.String(), `"hello":"hello gin"`) {
+		t.Errorf("unexpected response %s", w.Body.String())
+	}
+	if _, err := NewHandlerFS(&testGraphqlRoot{}, fstest.MapFS{"bad.graphql": {Data: []byte("type Query {")}}); err == nil {
+		t.Error("want schema error")
+	}
+}
@@ -0,0 +1,309 @@
+package ginplus
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+	"github.com/graph-gophers/graphql-go/introspection"
+	"github.com/graph-gophers/graphql-go/trace/tracer"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// 告诉编译器这个结构体实现了tracer.Tracer接口
+var _ tracer.Tracer = (*GraphqlTracer)(nil)
+
+const (
+	graphqlTracerName = "ginplus.graphql"
+	// anonymousOperation 未命名操作的名称
+	anonymousOperation = "anonymous"
+	// otherOperation 不在白名单或超出数量上限的操作在指标中的名称
+	otherOperation = "other"
+	// defaultGraphqlMaxOperations 未设置白名单时指标中最多记录的操作名称数量
+	defaultGraphqlMaxOperations = 100
+)
+
+type (
+	// GraphqlTracer graph-gophers/graphql-go的Tracer, 为每个操作和resolver创建span,
+	// 记录操作的名称、类型、耗时和错误日志, 以及按操作名称统计的耗时直方图, 零值可直接使用
+	//
+	// 操作名称由客户端决定, 指标只记录白名单中的名称, 未设置白名单时只记录最先出现的若干个名称, 其余统一记为other
+	GraphqlTracer struct {
+		// 为空时使用父span所属的TracerProvider, 没有父span时使用otel全局TracerProvider
+		provider oteltrace.TracerProvider
+		// 是否关闭resolver的span和指标, 只保留操作
+		disableFieldSpans bool
+		// 是否关闭操作日志
+		disableLog bool
+		// 指标注册器, 为空时使用prometheus.DefaultRegisterer
+		registerer prometheus.Registerer
+		// 耗时直方图的桶
+		buckets []float64
+		// 是否关闭指标
+		disableMetrics bool
+		// 指标中记录的操作名称白名单, 为空时按数量上限记录
+		operations map[string]struct{}
+		// 未设置白名单时指标中最多记录的操作名称数量, 默认为100
+		maxOperations int
+		// 是否在span中记录完整的查询语句
+		recordDocument bool
+
+		// 已记录到指标中的操作名称
+		mu        sync.Mutex
+		seenNames map[string]struct{}
+
+		duration      *prometheus.HistogramVec
+		fieldDuration *prometheus.HistogramVec
+	}
+
+	// GraphqlTracerOption graphql tracer配置
+	GraphqlTracerOption func(*GraphqlTracer)
+)
+
+// NewGraphqlTracer 创建graphql tracer
+func NewGraphqlTracer(opts ...GraphqlTracerOption) *GraphqlTracer {
+	t := &GraphqlTracer{}
+	for _, opt := range opts {
+		opt(t)
+	}
+	t.initMetrics()
+	return t
+}
+
+// WithGraphqlTracerProvider 设置TracerProvider
+func WithGraphqlTracerProvider(tp oteltrace.TracerProvider) GraphqlTracerOption {
+	return func(t *GraphqlTracer) {
+		t.provider = tp
+	}
+}
+
+// WithGraphqlTracerDisableFieldSpans 不为resolver创建span和记录指标, 只追踪操作
+func WithGraphqlTracerDisableFieldSpans() GraphqlTracerOption {
+	return func(t *GraphqlTracer) {
+		t.disableFieldSpans = true
+	}
+}
+
+// WithGraphqlTracerDisableLog 关闭操作日志
+func WithGraphqlTracerDisableLog() GraphqlTracerOption {
+	return func(t *GraphqlTracer) {
+		t.disableLog = true
+	}
+}
+
+// WithGraphqlTracerRegisterer 设置指标注册器
+func WithGraphqlTracerRegisterer(registerer prometheus.Registerer) GraphqlTracerOption {
+	return func(t *GraphqlTracer) {
+		t.registerer = registerer
+	}
+}
+
+// WithGraphqlTracerHistogramBuckets 设置耗时直方图的桶, 单位为秒
+func WithGraphqlTracerHistogramBuckets(buckets []float64) GraphqlTracerOption {
+	return func(t *GraphqlTracer) {
+		t.buckets = buckets
+	}
+}
+
+// WithGraphqlTracerOperations 设置指标中记录的操作名称白名单, 其余操作记为other
+func WithGraphqlTracerOperations(names ...string) GraphqlTracerOption {
+	return func(t *GraphqlTracer) {
+		t.operations = make(map[string]struct{}, len(names))
+		for _, name := range names {
+			t.operations[name] = struct{}{}
+		}
+	}
+}
+
+// WithGraphqlTracerMaxOperations 设置未使用白名单时指标中最多记录的操作名称数量, 超出后记为other
+func WithGraphqlTracerMaxOperations(n int) GraphqlTracerOption {
+	return func(t *GraphqlTracer) {
+		t.maxOperations = n
+	}
+}
+
+// WithGraphqlTracerRecordDocument 在span中记录完整的查询语句(graphql.document), 查询中可能包含敏感数据, 默认不记录
+func WithGraphqlTracerRecordDocument() GraphqlTracerOption {
+	return func(t *GraphqlTracer) {
+		t.recordDocument = true
+	}
+}
+
+// WithGraphqlTracerDisableMetrics 关闭耗时指标
+func WithGraphqlTracerDisableMetrics() GraphqlTracerOption {
+	return func(t *GraphqlTracer) {
+		t.disableMetrics = true
+	}
+}
+
+// WithGraphqlTracer 设置graphql tracer, 通过Handler解析schema时默认使用NewGraphqlTracer()
+func WithGraphqlTracer(t tracer.Tracer) GraphqlHandlerOption {
+	return func(h *GraphqlHandler) {
+		h.tracer = t
+	}
+}
+
+// initMetrics 注册操作和resolver的耗时直方图, 已注册时复用已有的指标
+func (t *GraphqlTracer) initMetrics() {
+	if t.disableMetrics {
+		return
+	}
+	registerer := t.registerer
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+	buckets := t.buckets
+	if len(buckets) == 0 {
+		buckets = prometheus.DefBuckets
+	}
+	duration, err := registerCollector(registerer, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Subsystem: "graphql",
+		Name:      "operation_duration_seconds",
+		Help:      "Duration of GraphQL operations in seconds.",
+		Buckets:   buckets,
+	}, []string{"operation", "type", "status"}))
+	if err != nil {
+		Logger().Sugar().Errorf("[GIN-PLUS] [ERROR] register graphql metrics: %v", err)
+		return
+	}
+	t.duration = duration
+	if t.disableFieldSpans {
+		return
+	}
+	fieldDuration, err := registerCollector(registerer, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Subsystem: "graphql",
+		Name:      "resolver_duration_seconds",
+		Help:      "Duration of GraphQL resolvers in seconds.",
+		Buckets:   buckets,
+	}, []string{"type", "field", "status"}))
+	if err != nil {
+		Logger().Sugar().Errorf("[GIN-PLUS] [ERROR] register graphql metrics: %v", err)
+		return
+	}
+	t.fieldDuration = fieldDuration
+}
+
+// metricOperation 返回指标中使用的操作名称, 限制标签的基数
+func (t *GraphqlTracer) metricOperation(name string) string {
+	if name == anonymousOperation {
+		return name
+	}
+	if t.operations != nil {
+		if _, ok := t.operations[name]; ok {
+			return name
+		}
+		return otherOperation
+	}
+	limit := t.maxOperations
+	if limit <= 0 {
+		limit = defaultGraphqlMaxOperations
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.seenNames[name]; ok {
+		return name
+	}
+	if len(t.seenNames) >= limit {
+		return otherOperation
+	}
+	if t.seenNames == nil {
+		t.seenNames = make(map[string]struct{})
+	}
+	t.seenNames[name] = struct{}{}
+	return name
+}
+
+func (t *GraphqlTracer) tracer(ctx context.Context) oteltrace.Tracer {
+	return spanProvider(ctx, t.provider).Tracer(graphqlTracerName)
+}
+
+// TraceQuery 为操作创建span, 结束时记录日志和耗时, graphql-go只对query和mutation调用
+func (t *GraphqlTracer) TraceQuery(ctx context.Context, queryString string, operationName string, _ map[string]any, _ map[string]*introspection.Type) (context.Context, tracer.QueryFinishFunc) {
+	start := time.Now()
+	typ := operationType(queryString, operationName)
+	if typ == "" {
+		typ = operationQuery
+	}
+	name := operationName
+	if name == "" {
+		name = anonymousOperation
+	}
+	attrs := []attribute.KeyValue{
+		semconv.GraphqlOperationName(operationName),
+		semconv.GraphqlOperationTypeKey.String(typ),
+	}
+	if t.recordDocument {
+		attrs = append(attrs, semconv.GraphqlDocument(queryString))
+	}
+	ctx, span := t.tracer(ctx).Start(ctx, "GraphQL "+typ+" "+name, oteltrace.WithAttributes(attrs...))
+	return ctx, func(errs []*gqlerrors.QueryError) {
+		defer span.End()
+		latency := time.Since(start)
+		status := "ok"
+		if len(errs) > 0 {
+			status = "error"
+			for _, err := range errs {
+				span.RecordError(err)
+			}
+			span.SetStatus(codes.Error, errs[0].Message)
+		}
+		if t.duration != nil {
+			t.duration.WithLabelValues(t.metricOperation(name), typ, status).Observe(latency.Seconds())
+		}
+		if t.disableLog {
+			return
+		}
+		kv := []zap.Field{
+			zap.String("operation_name", name),
+			zap.String("operation_type", typ),
+			zap.Duration("latency_time", latency),
+		}
+		if requestID := RequestIDFromContext(ctx); requestID != "" {
+			kv = append(kv, zap.String("request_id", requestID))
+		}
+		if sc := span.SpanContext(); sc.HasTraceID() {
+			kv = append(kv, zap.String("trace_id", sc.TraceID().String()), zap.String("span_id", sc.SpanID().String()))
+		}
+		if len(errs) > 0 {
+			messages := make([]string, 0, len(errs))
+			for _, err := range errs {
+				messages = append(messages, err.Message)
+			}
+			Logger().Error("[GIN-PLUS] [ERROR] graphql operation", append(kv, zap.Strings("errors", messages))...)
+			return
+		}
+		Logger().Info("[GIN-PLUS] [INFO] graphql operation", kv...)
+	}
+}
+
+// TraceField 为非平凡的resolver创建span, 直接读取结构体字段的resolver不追踪
+func (t *GraphqlTracer) TraceField(ctx context.Context, label, typeName, fieldName string, trivial bool, _ map[string]any) (context.Context, tracer.FieldFinishFunc) {
+	if t.disableFieldSpans || trivial {
+		return ctx, func(*gqlerrors.QueryError) {}
+	}
+	start := time.Now()
+	ctx, span := t.tracer(ctx).Start(ctx, label,
+		oteltrace.WithAttributes(
+			attribute.String("graphql.type", typeName),
+			attribute.String("graphql.field", fieldName),
+		),
+	)
+	return ctx, func(err *gqlerrors.QueryError) {
+		defer span.End()
+		status := "ok"
+		if err != nil {
+			status = "error"
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Message)
+		}
+		if t.fieldDuration != nil {
+			t.fieldDuration.WithLabelValues(typeName, fieldName, status).Observe(time.Since(start).Seconds())
+		}
+	}
+}
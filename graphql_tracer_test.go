@@ -0,0 +1,115 @@
+package ginplus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+func TestGraphqlTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := tracesdk.NewTracerProvider(tracesdk.WithSpanProcessor(recorder))
+	registry := prometheus.NewRegistry()
+	tracer := NewGraphqlTracer(WithGraphqlTracerRegisterer(registry), WithGraphqlTracerDisableLog())
+	schema := graphql.MustParseSchema(testGraphqlSchema, &testGraphqlRoot{}, graphql.Tracer(tracer))
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "/graphql")
+	if resp := schema.Exec(ctx, `query Greet { hello(name: "a") }`, "", nil); len(resp.Errors) > 0 {
+		t.Fatal(resp.Errors)
+	}
+	schema.Exec(ctx, `mutation { incr } query Greet { hello(name: "a") }`, "Missing", nil)
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("want 3 spans, got %d", len(spans))
+	}
+	field, operation := spans[0], spans[1]
+	if operation.Name() != "GraphQL query Greet" {
+		t.Errorf("unexpected operation span name %q", operation.Name())
+	}
+	if operation.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Error("operation span is not a child of the request span")
+	}
+	for _, attr := range operation.Attributes() {
+		if attr.Key == semconv.GraphqlOperationNameKey && attr.Value.AsString() != "Greet" {
+			t.Errorf("unexpected graphql.operation.name %q", attr.Value.AsString())
+		}
+		if attr.Key == semconv.GraphqlDocumentKey {
+			t.Error("graphql.document recorded without WithGraphqlTracerRecordDocument")
+		}
+	}
+	if field.Parent().SpanID() != operation.SpanContext().SpanID() {
+		t.Error("resolver span is not a child of the operation span")
+	}
+
+	if n := testutil.CollectAndCount(registry, "graphql_operation_duration_seconds"); n != 1 {
+		t.Errorf("want 1 operation histogram series, got %d", n)
+	}
+	if n := testutil.CollectAndCount(registry, "graphql_resolver_duration_seconds"); n != 1 {
+		t.Errorf("want 1 resolver histogram series, got %d", n)
+	}
+}
+
+func TestGraphqlTracer_RecordDocument(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := tracesdk.NewTracerProvider(tracesdk.WithSpanProcessor(recorder))
+	tracer := NewGraphqlTracer(WithGraphqlTracerProvider(tp), WithGraphqlTracerDisableMetrics(), WithGraphqlTracerDisableLog(), WithGraphqlTracerRecordDocument())
+	schema := graphql.MustParseSchema(testGraphqlSchema, &testGraphqlRoot{}, graphql.Tracer(tracer))
+
+	query := `query Greet { hello(name: "a") }`
+	schema.Exec(context.Background(), query, "", nil)
+	for _, span := range recorder.Ended() {
+		for _, attr := range span.Attributes() {
+			if attr.Key == semconv.GraphqlDocumentKey && attr.Value.AsString() == query {
+				return
+			}
+		}
+	}
+	t.Error("graphql.document not recorded")
+}
+
+func TestGraphqlTracer_metricOperation(t *testing.T) {
+	capped := &GraphqlTracer{maxOperations: 2}
+	allowed := NewGraphqlTracer(WithGraphqlTracerDisableMetrics(), WithGraphqlTracerOperations("Greet"))
+	tests := []struct {
+		tracer *GraphqlTracer
+		name   string
+		want   string
+	}{
+		{tracer: capped, name: "A", want: "A"},
+		{tracer: capped, name: "B", want: "B"},
+		{tracer: capped, name: "C", want: otherOperation},
+		{tracer: capped, name: "A", want: "A"},
+		{tracer: capped, name: anonymousOperation, want: anonymousOperation},
+		{tracer: allowed, name: "Greet", want: "Greet"},
+		{tracer: allowed, name: "Random123", want: otherOperation},
+	}
+	for _, tt := range tests {
+		if got := tt.tracer.metricOperation(tt.name); got != tt.want {
+			t.Errorf("metricOperation(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGraphqlTracer_GlobalProvider(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	global := otel.GetTracerProvider()
+	otel.SetTracerProvider(tracesdk.NewTracerProvider(tracesdk.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(global)
+
+	tracer := NewGraphqlTracer(WithGraphqlTracerDisableMetrics(), WithGraphqlTracerDisableLog(), WithGraphqlTracerDisableFieldSpans())
+	schema := graphql.MustParseSchema(testGraphqlSchema, &testGraphqlRoot{}, graphql.Tracer(tracer))
+	// 没有父span时使用全局TracerProvider
+	schema.Exec(context.Background(), `query Greet { hello(name: "a") }`, "", nil)
+	if spans := recorder.Ended(); len(spans) != 1 || spans[0].Name() != "GraphQL query Greet" {
+		t.Errorf("want operation traced by the global provider, got %v", spans)
+	}
+}
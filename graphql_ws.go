@@ -0,0 +1,428 @@
+package ginplus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
+)
+
+// graphqlTransportWS graphql-transport-ws子协议
+const graphqlTransportWS = "graphql-transport-ws"
+
+const (
+	defaultGraphqlKeepAlive        = 15 * time.Second
+	defaultGraphqlInitTimeout      = 10 * time.Second
+	defaultGraphqlMaxSubscriptions = 100
+	graphqlWriteTimeout            = 10 * time.Second
+)
+
+// graphql-transport-ws消息类型
+const (
+	wsConnectionInit = "connection_init"
+	wsConnectionAck  = "connection_ack"
+	wsPing           = "ping"
+	wsPong           = "pong"
+	wsSubscribe      = "subscribe"
+	wsNext           = "next"
+	wsError          = "error"
+	wsComplete       = "complete"
+)
+
+// graphql-transport-ws关闭码
+const (
+	wsCloseBadRequest       = 4400
+	wsCloseUnauthorized     = 4401
+	wsCloseForbidden        = 4403
+	wsCloseInitTimeout      = 4408
+	wsCloseSubscriberExists = 4409
+	wsCloseTooManyInit      = 4429
+)
+
+type (
+	// GraphqlInitFunc websocket连接初始化钩子, 可用于校验connection_init中的认证信息,
+	// 返回的context用于该连接上的所有订阅, 返回错误时关闭连接
+	GraphqlInitFunc func(ctx context.Context, payload map[string]any) (context.Context, error)
+
+	// wsMessage graphql-transport-ws消息
+	wsMessage struct {
+		ID      string          `json:"id,omitempty"`
+		Type    string          `json:"type"`
+		Payload json.RawMessage `json:"payload,omitempty"`
+	}
+
+	// wsConn graphql-transport-ws连接
+	wsConn struct {
+		handler *GraphqlHandler
+		conn    *websocket.Conn
+
+		writeMtx sync.Mutex
+
+		mtx           sync.Mutex
+		ctx           context.Context
+		acked         bool
+		initReceived  bool
+		subscriptions map[string]context.CancelFunc
+	}
+)
+
+// WithGraphqlInitFunc 设置websocket连接初始化钩子
+func WithGraphqlInitFunc(fn GraphqlInitFunc) GraphqlHandlerOption {
+	return func(h *GraphqlHandler) {
+		h.initFunc = fn
+	}
+}
+
+// WithGraphqlKeepAlive 设置订阅的心跳间隔, websocket发送ping消息, SSE发送注释行, 默认为15s
+func WithGraphqlKeepAlive(interval time.Duration) GraphqlHandlerOption {
+	return func(h *GraphqlHandler) {
+		h.keepAlive = interval
+	}
+}
+
+// WithGraphqlInitTimeout 设置等待connection_init的超时时间, 默认为10s
+func WithGraphqlInitTimeout(timeout time.Duration) GraphqlHandlerOption {
+	return func(h *GraphqlHandler) {
+		h.initTimeout = timeout
+	}
+}
+
+// WithGraphqlMaxSubscriptions 设置单个websocket连接的最大并发订阅数, 默认为100
+func WithGraphqlMaxSubscriptions(n int) GraphqlHandlerOption {
+	return func(h *GraphqlHandler) {
+		h.maxSubscriptions = n
+	}
+}
+
+// WithGraphqlCheckOrigin 设置websocket的跨域校验, 默认只允许同源
+func WithGraphqlCheckOrigin(fn func(r *http.Request) bool) GraphqlHandlerOption {
+	return func(h *GraphqlHandler) {
+		h.checkOrigin = fn
+	}
+}
+
+// isEventStream 是否为SSE请求
+func isEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// serveWebsocket 处理graphql-transport-ws协议的websocket连接
+func (h *GraphqlHandler) serveWebsocket(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{
+		Subprotocols: []string{graphqlTransportWS},
+		CheckOrigin:  h.checkOrigin,
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	c := &wsConn{
+		handler:       h,
+		conn:          conn,
+		ctx:           r.Context(),
+		subscriptions: make(map[string]context.CancelFunc),
+	}
+	if conn.Subprotocol() != graphqlTransportWS {
+		c.close(websocket.CloseProtocolError, "Subprotocol not acceptable")
+		return
+	}
+	c.run(r.Context())
+}
+
+// run 读取并处理消息, 连接关闭时取消所有订阅
+func (c *wsConn) run(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer func() {
+		cancel()
+		c.mtx.Lock()
+		for _, cancelSub := range c.subscriptions {
+			cancelSub()
+		}
+		c.mtx.Unlock()
+		_ = c.conn.Close()
+	}()
+
+	go c.keepAlive(ctx)
+	initTimer := time.AfterFunc(c.handler.initTimeout, func() {
+		c.mtx.Lock()
+		acked := c.acked
+		c.mtx.Unlock()
+		if !acked {
+			c.close(wsCloseInitTimeout, "Connection initialisation timeout")
+		}
+	})
+	defer initTimer.Stop()
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var msg wsMessage
+		if err := json.Unmarshal(data, &msg); err != nil || msg.Type == "" {
+			c.close(wsCloseBadRequest, "Invalid message received")
+			return
+		}
+		if !c.handle(ctx, msg) {
+			return
+		}
+	}
+}
+
+// handle 处理单条消息, 返回false时关闭连接
+func (c *wsConn) handle(ctx context.Context, msg wsMessage) bool {
+	switch msg.Type {
+	case wsConnectionInit:
+		return c.init(ctx, msg)
+	case wsPing:
+		return c.write(wsMessage{Type: wsPong}) == nil
+	case wsPong:
+		return true
+	case wsSubscribe:
+		return c.subscribe(msg)
+	case wsComplete:
+		c.mtx.Lock()
+		if cancel, ok := c.subscriptions[msg.ID]; ok {
+			cancel()
+			delete(c.subscriptions, msg.ID)
+		}
+		c.mtx.Unlock()
+		return true
+	default:
+		c.close(wsCloseBadRequest, fmt.Sprintf("Unexpected message type %q", msg.Type))
+		return false
+	}
+}
+
+func (c *wsConn) init(ctx context.Context, msg wsMessage) bool {
+	c.mtx.Lock()
+	if c.initReceived {
+		c.mtx.Unlock()
+		c.close(wsCloseTooManyInit, "Too many initialisation requests")
+		return false
+	}
+	c.initReceived = true
+	c.mtx.Unlock()
+
+	var payload map[string]any
+	if len(msg.Payload) > 0 {
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			c.close(wsCloseBadRequest, "Invalid connection_init payload")
+			return false
+		}
+	}
+	if c.handler.initFunc != nil {
+		var err error
+		if ctx, err = c.handler.initFunc(ctx, payload); err != nil {
+			c.close(wsCloseForbidden, "Forbidden")
+			return false
+		}
+	}
+
+	c.mtx.Lock()
+	c.ctx = ctx
+	c.acked = true
+	c.mtx.Unlock()
+	return c.write(wsMessage{Type: wsConnectionAck}) == nil
+}
+
+func (c *wsConn) subscribe(msg wsMessage) bool {
+	var params graphqlParams
+	if msg.ID == "" || json.Unmarshal(msg.Payload, &params) != nil {
+		c.close(wsCloseBadRequest, "Invalid subscribe message")
+		return false
+	}
+
+	c.mtx.Lock()
+	if !c.acked {
+		c.mtx.Unlock()
+		c.close(wsCloseUnauthorized, "Unauthorized")
+		return false
+	}
+	if _, ok := c.subscriptions[msg.ID]; ok {
+		c.mtx.Unlock()
+		c.close(wsCloseSubscriberExists, fmt.Sprintf("Subscriber for %s already exists", msg.ID))
+		return false
+	}
+	if max := c.handler.maxSubscriptions; max > 0 && len(c.subscriptions) >= max {
+		c.mtx.Unlock()
+		return c.writeError(msg.ID, fmt.Errorf("too many subscriptions, limit is %d", max)) == nil
+	}
+	ctx, cancel := context.WithCancel(c.ctx)
+	c.subscriptions[msg.ID] = cancel
+	c.mtx.Unlock()
+
+	go func() {
+		defer cancel()
+		err := resolvePersistedQuery(ctx, c.handler.persistedQueryStore, &params)
+		if err == nil {
+			err = c.handler.checkLimits(params)
+		}
+		if err != nil {
+			_ = c.writeError(msg.ID, err)
+			c.finish(msg.ID, false)
+			return
+		}
+		responses, err := c.handler.Schema.Subscribe(ctx, params.Query, params.OperationName, params.Variables)
+		if err != nil {
+			_ = c.writeError(msg.ID, err)
+			c.finish(msg.ID, false)
+			return
+		}
+		for resp := range responses {
+			payload, err := json.Marshal(resp)
+			if err != nil {
+				continue
+			}
+			if err := c.write(wsMessage{ID: msg.ID, Type: wsNext, Payload: payload}); err != nil {
+				cancel()
+				drain(responses)
+				return
+			}
+		}
+		c.finish(msg.ID, true)
+	}()
+	return true
+}
+
+// finish 订阅结束, 客户端未主动取消且未发送error时发送complete
+func (c *wsConn) finish(id string, complete bool) {
+	c.mtx.Lock()
+	_, active := c.subscriptions[id]
+	delete(c.subscriptions, id)
+	c.mtx.Unlock()
+	if active && complete {
+		_ = c.write(wsMessage{ID: id, Type: wsComplete})
+	}
+}
+
+// keepAlive 定时发送ping消息
+func (c *wsConn) keepAlive(ctx context.Context) {
+	if c.handler.keepAlive <= 0 {
+		return
+	}
+	ticker := time.NewTicker(c.handler.keepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.write(wsMessage{Type: wsPing}); err != nil {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *wsConn) writeError(id string, err error) error {
+	payload, _ := json.Marshal(graphqlErrorResponse(err).Errors)
+	return c.write(wsMessage{ID: id, Type: wsError, Payload: payload})
+}
+
+func (c *wsConn) write(msg wsMessage) error {
+	c.writeMtx.Lock()
+	defer c.writeMtx.Unlock()
+	_ = c.conn.SetWriteDeadline(time.Now().Add(graphqlWriteTimeout))
+	return c.conn.WriteJSON(msg)
+}
+
+func (c *wsConn) close(code int, reason string) {
+	c.writeMtx.Lock()
+	defer c.writeMtx.Unlock()
+	_ = c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(graphqlWriteTimeout))
+	_ = c.conn.Close()
+}
+
+// serveSSE 以SSE方式执行订阅, 每个请求对应一个订阅, 认证等由HTTP中间件处理
+func (h *GraphqlHandler) serveSSE(w http.ResponseWriter, r *http.Request) {
+	var (
+		params graphqlParams
+		err    error
+	)
+	if r.Method == http.MethodGet {
+		params, err = paramsFromQuery(r)
+	} else {
+		err = json.NewDecoder(r.Body).Decode(&params)
+	}
+	if err != nil {
+		writeGraphqlJSON(w, http.StatusBadRequest, graphqlErrorResponse(err))
+		return
+	}
+	if err := resolvePersistedQuery(r.Context(), h.persistedQueryStore, &params); err != nil {
+		writeGraphqlJSON(w, http.StatusBadRequest, graphqlErrorResponse(err))
+		return
+	}
+	if typ := operationType(params.Query, params.OperationName); r.Method == http.MethodGet && typ != operationQuery && typ != operationSubscription {
+		w.Header().Set("Allow", http.MethodPost)
+		writeGraphqlJSON(w, http.StatusMethodNotAllowed, graphqlErrorResponse(errGraphqlSSEOverGet))
+		return
+	}
+	if err := h.checkLimits(params); err != nil {
+		writeGraphqlJSON(w, http.StatusOK, graphqlErrorResponse(err))
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	responses, err := h.Schema.Subscribe(ctx, params.Query, params.OperationName, params.Variables)
+	if err != nil {
+		writeGraphqlJSON(w, http.StatusBadRequest, graphqlErrorResponse(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	var keepAlive <-chan time.Time
+	if h.keepAlive > 0 {
+		ticker := time.NewTicker(h.keepAlive)
+		defer ticker.Stop()
+		keepAlive = ticker.C
+	}
+	for {
+		select {
+		case resp, ok := <-responses:
+			if !ok {
+				_, _ = fmt.Fprint(w, "event: complete\ndata:\n\n")
+				flusher.Flush()
+				return
+			}
+			data, err := json.Marshal(resp.(*graphql.Response))
+			if err != nil {
+				continue
+			}
+			_, _ = fmt.Fprintf(w, "event: next\ndata: %s\n\n", data)
+			flusher.Flush()
+		case <-keepAlive:
+			_, _ = fmt.Fprint(w, ":\n\n")
+			flusher.Flush()
+		case <-ctx.Done():
+			drain(responses)
+			return
+		}
+	}
+}
+
+// drain 订阅取消后读取剩余的响应, 避免graphql-go的转发goroutine阻塞
+func drain(responses <-chan any) {
+	go func() {
+		for range responses {
+		}
+	}()
+}
@@ -0,0 +1,161 @@
+package ginplus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestGraphqlHandler_Websocket(t *testing.T) {
+	h := newTestGraphqlHandler(t,
+		WithGraphqlMaxSubscriptions(1),
+		WithGraphqlInitFunc(func(ctx context.Context, payload map[string]any) (context.Context, error) {
+			if payload["token"] != "secret" {
+				return nil, errors.New("invalid token")
+			}
+			return ctx, nil
+		}),
+	)
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	dial := func() *websocket.Conn {
+		dialer := websocket.Dialer{Subprotocols: []string{graphqlTransportWS}}
+		conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		return conn
+	}
+	send := func(conn *websocket.Conn, msg string) {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(conn *websocket.Conn) wsMessage {
+		var msg wsMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatal(err)
+		}
+		return msg
+	}
+	closeCode := func(conn *websocket.Conn) int {
+		_, _, err := conn.ReadMessage()
+		var closeErr *websocket.CloseError
+		if !errors.As(err, &closeErr) {
+			t.Fatalf("want close error, got %v", err)
+		}
+		return closeErr.Code
+	}
+
+	t.Run("forbidden", func(t *testing.T) {
+		conn := dial()
+		defer conn.Close()
+		send(conn, `{"type":"connection_init","payload":{"token":"wrong"}}`)
+		if code := closeCode(conn); code != wsCloseForbidden {
+			t.Errorf("want close code %d, got %d", wsCloseForbidden, code)
+		}
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		conn := dial()
+		defer conn.Close()
+		send(conn, `{"id":"1","type":"subscribe","payload":{"query":"subscription { ticks(n: 1) }"}}`)
+		if code := closeCode(conn); code != wsCloseUnauthorized {
+			t.Errorf("want close code %d, got %d", wsCloseUnauthorized, code)
+		}
+	})
+
+	t.Run("subscribe", func(t *testing.T) {
+		conn := dial()
+		defer conn.Close()
+		send(conn, `{"type":"connection_init","payload":{"token":"secret"}}`)
+		if msg := read(conn); msg.Type != wsConnectionAck {
+			t.Fatalf("want connection_ack, got %+v", msg)
+		}
+		send(conn, `{"type":"ping"}`)
+		if msg := read(conn); msg.Type != wsPong {
+			t.Fatalf("want pong, got %+v", msg)
+		}
+
+		send(conn, `{"id":"1","type":"subscribe","payload":{"query":"subscription { ticks(n: 2) }"}}`)
+		var got []string
+		for {
+			msg := read(conn)
+			if msg.Type == wsComplete {
+				break
+			}
+			got = append(got, string(msg.Payload))
+		}
+		if want := `{"data":{"ticks":1}},{"data":{"ticks":2}}`; strings.Join(got, ",") != want {
+			t.Errorf("want %s, got %s", want, strings.Join(got, ","))
+		}
+
+		// 超过单连接订阅数限制
+		send(conn, `{"id":"2","type":"subscribe","payload":{"query":"subscription { ticks(n: 0) }"}}`)
+		if msg := read(conn); msg.Type != wsNext || msg.ID != "2" {
+			t.Fatalf("want next for subscription 2, got %+v", msg)
+		}
+		send(conn, `{"id":"3","type":"subscribe","payload":{"query":"subscription { ticks(n: 1) }"}}`)
+		for {
+			msg := read(conn)
+			if msg.ID == "3" {
+				if msg.Type != wsError || !strings.Contains(string(msg.Payload), "too many subscriptions") {
+					t.Errorf("want limit error, got %+v", msg)
+				}
+				break
+			}
+		}
+		send(conn, `{"id":"2","type":"complete"}`)
+	})
+}
+
+func TestGraphqlHandler_SSE(t *testing.T) {
+	server := httptest.NewServer(newTestGraphqlHandler(t))
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"?"+url.Values{"query": {"subscription { ticks(n: 2) }"}}.Encode(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body strings.Builder
+	buf := make([]byte, 1024)
+	for {
+		n, err := resp.Body.Read(buf)
+		body.Write(buf[:n])
+		if err != nil {
+			break
+		}
+	}
+	want := "event: next\ndata: {\"data\":{\"ticks\":1}}\n\nevent: next\ndata: {\"data\":{\"ticks\":2}}\n\nevent: complete\ndata:\n\n"
+	if resp.Header.Get("Content-Type") != "text/event-stream" || body.String() != want {
+		t.Errorf("unexpected event stream %q", body.String())
+	}
+
+	var result map[string]any
+	req.URL.RawQuery = url.Values{"query": {"mutation M($a: In = {k: 1}) { incr }"}}.Encode()
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	_ = json.NewDecoder(resp.Body).Decode(&result)
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("want mutation rejected over GET, got %d %v", resp.StatusCode, result)
+	}
+}
@@ -1,12 +1,27 @@
 package ginplus
 
 import (
+	"bytes"
+	"context"
 	"embed"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"net/http"
-	
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aide-cloud/gin-plus/explorer"
+	"github.com/gorilla/websocket"
 	"github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
 	"github.com/graph-gophers/graphql-go/relay"
+	"github.com/graph-gophers/graphql-go/trace/tracer"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 const (
@@ -14,46 +29,16 @@ const (
 	DefaultViewPath   = "/view"
 )
 
+// View graphql调试页面(GraphQL Explorer), 是内置的轻量页面而不是GraphiQL, 提供查询编辑、变量、请求头、订阅和schema文档,
+// 页面资源内嵌在explorer.Dist中, 不依赖外部CDN
 func View(handlePath string) http.HandlerFunc {
-	var page = []byte(`
-	<!DOCTYPE html>
-	<html>
-		<head>
-			<link rel="stylesheet" href="https://cdnjs.cloudflare.com/ajax/libs/graphiql/0.10.2/graphiql.css" />
-			<script src="https://cdnjs.cloudflare.com/ajax/libs/fetch/1.1.0/fetch.min.js"></script>
-			<script src="https://cdnjs.cloudflare.com/ajax/libs/react/15.5.4/react.min.js"></script>
-			<script src="https://cdnjs.cloudflare.com/ajax/libs/react/15.5.4/react-dom.min.js"></script>
-			<script src="https://cdnjs.cloudflare.com/ajax/libs/graphiql/0.10.2/graphiql.js"></script>
-		</head>
-		<body style="width: 100%; height: 100%; margin: 0; overflow: hidden;">
-			<div id="graphiql" style="height: 100vh;">Loading...</div>
-			<script>
-				function graphQLFetcher(graphQLParams) {
-					return fetch("` + handlePath + `", {
-						method: "` + "POST" + `",
-						body: JSON.stringify(graphQLParams),
-						credentials: "include",
-					}).then(function (response) {
-						return response.text();
-					}).then(function (responseBody) {
-						try {
-							return JSON.parse(responseBody);
-						} catch (error) {
-							return responseBody;
-						}
-					});
-				}
-
-				ReactDOM.render(
-					React.createElement(GraphiQL, {fetcher: graphQLFetcher}),
-					document.getElementById("graphiql")
-				);
-			</script>
-		</body>
-	</html>
-	`)
+	page, err := explorerPage(handlePath)
+	if err != nil {
+		panic(fmt.Sprintf("render graphql explorer page: %s\n", err))
+	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
 		_, err := w.Write(page)
 		if err != nil {
 			panic(fmt.Sprintf("write page error: %s\n", err))
@@ -61,6 +46,34 @@ func View(handlePath string) http.HandlerFunc {
 	}
 }
 
+// explorerPage 把样式、脚本和接口地址内联到页面中, 页面可以挂载在任意路径
+func explorerPage(handlePath string) ([]byte, error) {
+	read := func(name string) (string, error) {
+		content, err := explorer.Dist.ReadFile(path.Join("dist", name))
+		return string(content), err
+	}
+	index, err := read("index.html")
+	if err != nil {
+		return nil, err
+	}
+	style, err := read("explorer.css")
+	if err != nil {
+		return nil, err
+	}
+	script, err := read("explorer.js")
+	if err != nil {
+		return nil, err
+	}
+	// json.Marshal会转义<和>, 可以安全地放在script标签中
+	config, err := json.Marshal(map[string]string{"endpoint": handlePath})
+	if err != nil {
+		return nil, err
+	}
+	return []byte(strings.NewReplacer("{{style}}", style, "{{script}}", script, "{{config}}", string(config)).Replace(index)), nil
+}
+
+// Handler 根据schema文件和根节点创建relay.Handler, 只支持POST请求, schema错误时panic;
+// 需要GET、批量查询、持久化查询(APQ)和订阅时使用NewHandlerFS或NewHandler
 func Handler(root any, content embed.FS) *relay.Handler {
 	s, err := String(content)
 	if err != nil {
@@ -69,3 +82,246 @@ func Handler(root any, content embed.FS) *relay.Handler {
 
 	return &relay.Handler{Schema: graphql.MustParseSchema(s, root, graphql.UseFieldResolvers())}
 }
+
+// NewHandlerFS 根据schema文件和根节点创建graphql处理函数, content可以是embed.FS或os.DirFS等任意fs.FS
+func NewHandlerFS(root any, content fs.FS, opts ...GraphqlHandlerOption) (*GraphqlHandler, error) {
+	s, err := String(content)
+	if err != nil {
+		return nil, fmt.Errorf("reading schema contents: %w", err)
+	}
+	return NewHandler(root, s, opts...)
+}
+
+// NewHandler 根据SDL和根节点创建graphql处理函数, SDL可以由String读取或GenerateSchema生成
+func NewHandler(root any, schema string, opts ...GraphqlHandlerOption) (*GraphqlHandler, error) {
+	h := NewGraphqlHandler(nil, opts...)
+	if h.tracer == nil {
+		h.tracer = NewGraphqlTracer()
+	}
+	schemaOpts := append([]graphql.SchemaOpt{graphql.UseFieldResolvers(), graphql.Tracer(h.tracer)}, h.schemaOpts...)
+	parsed, err := graphql.ParseSchema(withCostDirective(schema), root, schemaOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("parse graphql schema: %w", err)
+	}
+	h.Schema = parsed
+	return h, nil
+}
+
+type (
+	// GraphqlHandler graphql请求处理, 支持GET、POST、批量查询和持久化查询(APQ), GET请求只允许执行query;
+	// 订阅支持graphql-transport-ws协议的websocket, 以及Accept为text/event-stream的SSE
+	GraphqlHandler struct {
+		Schema *graphql.Schema
+
+		// 持久化查询存储, 为空时不支持APQ
+		persistedQueryStore PersistedQueryStore
+		// 批量查询的最大数量
+		maxBatchSize int
+
+		// 订阅: websocket连接初始化钩子
+		initFunc GraphqlInitFunc
+		// 订阅: 心跳间隔
+		keepAlive time.Duration
+		// 订阅: 等待connection_init的超时时间
+		initTimeout time.Duration
+		// 订阅: 单个websocket连接的最大并发订阅数
+		maxSubscriptions int
+		// 订阅: websocket跨域校验
+		checkOrigin func(r *http.Request) bool
+
+		// 查询的最大深度
+		maxDepth int
+		// 查询的最大复杂度
+		maxComplexity int
+		// 单个查询的超时时间
+		timeout time.Duration
+		// 是否禁用内省查询
+		disableIntrospection bool
+		// 解析schema时使用的配置
+		schemaOpts []graphql.SchemaOpt
+		// 解析schema时使用的tracer
+		tracer tracer.Tracer
+		// 被拒绝查询指标的注册器
+		registerer prometheus.Registerer
+		rejected   *prometheus.CounterVec
+	}
+
+	// GraphqlHandlerOption GraphqlHandler配置函数
+	GraphqlHandlerOption func(*GraphqlHandler)
+
+	// graphqlRequestContextKey 保存graphql的HTTP请求, controller字段调用时传递请求头
+	graphqlRequestContextKey struct{}
+)
+
+// NewGraphqlHandler 创建graphql处理函数, 默认使用LRU内存存储持久化查询, 批量查询最多10个
+func NewGraphqlHandler(schema *graphql.Schema, opts ...GraphqlHandlerOption) *GraphqlHandler {
+	h := &GraphqlHandler{
+		Schema:              schema,
+		persistedQueryStore: NewLRUPersistedQueryStore(defaultPersistedQueryCacheSize),
+		maxBatchSize:        defaultGraphqlMaxBatchSize,
+		keepAlive:           defaultGraphqlKeepAlive,
+		initTimeout:         defaultGraphqlInitTimeout,
+		maxSubscriptions:    defaultGraphqlMaxSubscriptions,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	h.initRejectedMetrics()
+	return h
+}
+
+// WithGraphqlPersistedQueryStore 设置持久化查询存储, 为空时不支持APQ
+func WithGraphqlPersistedQueryStore(store PersistedQueryStore) GraphqlHandlerOption {
+	return func(h *GraphqlHandler) {
+		h.persistedQueryStore = store
+	}
+}
+
+// WithGraphqlMaxBatchSize 设置批量查询的最大数量
+func WithGraphqlMaxBatchSize(size int) GraphqlHandlerOption {
+	return func(h *GraphqlHandler) {
+		h.maxBatchSize = size
+	}
+}
+
+// SDL 返回schema的定义, 用于客户端代码生成
+func (h *GraphqlHandler) SDL() string {
+	return h.Schema.ASTSchema().SchemaString
+}
+
+// ServeSDL 输出schema的定义
+func (h *GraphqlHandler) ServeSDL(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = io.WriteString(w, h.SDL())
+}
+
+func (h *GraphqlHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r = r.WithContext(context.WithValue(r.Context(), graphqlRequestContextKey{}, r))
+	switch {
+	case websocket.IsWebSocketUpgrade(r):
+		h.serveWebsocket(w, r)
+		return
+	case isEventStream(r) && (r.Method == http.MethodGet || r.Method == http.MethodPost):
+		h.serveSSE(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		params, err := paramsFromQuery(r)
+		if err != nil {
+			writeGraphqlJSON(w, http.StatusBadRequest, graphqlErrorResponse(err))
+			return
+		}
+		status, response := h.exec(r.Context(), params, true)
+		if status == http.StatusMethodNotAllowed {
+			w.Header().Set("Allow", http.MethodPost)
+		}
+		writeGraphqlJSON(w, status, response)
+	case http.MethodPost:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeGraphqlJSON(w, http.StatusBadRequest, graphqlErrorResponse(err))
+			return
+		}
+		body = bytes.TrimSpace(body)
+		if len(body) > 0 && body[0] == '[' {
+			h.serveBatch(w, r, body)
+			return
+		}
+		var params graphqlParams
+		if err := json.Unmarshal(body, &params); err != nil {
+			writeGraphqlJSON(w, http.StatusBadRequest, graphqlErrorResponse(err))
+			return
+		}
+		status, response := h.exec(r.Context(), params, false)
+		writeGraphqlJSON(w, status, response)
+	default:
+		w.Header().Set("Allow", http.MethodGet+", "+http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// serveBatch 并发执行批量查询, 按请求顺序返回结果
+func (h *GraphqlHandler) serveBatch(w http.ResponseWriter, r *http.Request, body []byte) {
+	var batch []graphqlParams
+	if err := json.Unmarshal(body, &batch); err != nil {
+		writeGraphqlJSON(w, http.StatusBadRequest, graphqlErrorResponse(err))
+		return
+	}
+	switch {
+	case len(batch) == 0:
+		writeGraphqlJSON(w, http.StatusBadRequest, graphqlErrorResponse(errGraphqlEmptyBatch))
+		return
+	case h.maxBatchSize > 0 && len(batch) > h.maxBatchSize:
+		writeGraphqlJSON(w, http.StatusBadRequest, graphqlErrorResponse(errGraphqlBatchTooLarge))
+		return
+	}
+
+	responses := make([]*graphql.Response, len(batch))
+	var wg sync.WaitGroup
+	for i := range batch {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, responses[i] = h.exec(r.Context(), batch[i], false)
+		}(i)
+	}
+	wg.Wait()
+	writeGraphqlJSON(w, http.StatusOK, responses)
+}
+
+// exec 执行单个查询, 返回HTTP状态码和响应
+func (h *GraphqlHandler) exec(ctx context.Context, params graphqlParams, isGet bool) (int, *graphql.Response) {
+	if err := resolvePersistedQuery(ctx, h.persistedQueryStore, &params); err != nil {
+		return http.StatusOK, graphqlErrorResponse(err)
+	}
+	if isGet {
+		// 只允许确定为query的操作, 无法解析的查询同样拒绝
+		if operationType(params.Query, params.OperationName) != operationQuery {
+			return http.StatusMethodNotAllowed, graphqlErrorResponse(errGraphqlOperationOverGet)
+		}
+	}
+	if err := h.checkLimits(params); err != nil {
+		return http.StatusOK, graphqlErrorResponse(err)
+	}
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+	// 每个查询使用独立的DataLoader缓存
+	response := h.Schema.Exec(WithDataLoaders(ctx), params.Query, params.OperationName, params.Variables)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		h.reject(rejectTimeout)
+		timeoutErr := graphqlErrorResponse(&graphqlRejectError{reason: rejectTimeout, message: fmt.Sprintf("query timeout after %s", h.timeout)})
+		response.Errors = append(response.Errors, timeoutErr.Errors...)
+	}
+	return http.StatusOK, response
+}
+
+// graphqlErrorResponse 把错误转换为graphql错误响应, 持久化查询和被拒绝查询的错误带有code扩展字段
+func graphqlErrorResponse(err error) *graphql.Response {
+	queryErr := &gqlerrors.QueryError{Err: err, Message: err.Error()}
+	var rejectErr *graphqlRejectError
+	switch {
+	case errors.As(err, &rejectErr):
+		queryErr.Extensions = map[string]any{"code": rejectCodes[rejectErr.reason]}
+	case errors.Is(err, errPersistedQueryNotFound):
+		queryErr.Extensions = map[string]any{"code": "PERSISTED_QUERY_NOT_FOUND"}
+	case errors.Is(err, errPersistedQueryMismatch), errors.Is(err, errPersistedQueryVersion):
+		queryErr.Extensions = map[string]any{"code": "PERSISTED_QUERY_INVALID"}
+	}
+	return &graphql.Response{Errors: []*gqlerrors.QueryError{queryErr}}
+}
+
+func writeGraphqlJSON(w http.ResponseWriter, status int, v any) {
+	responseJSON, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(responseJSON)
+}
@@ -0,0 +1,306 @@
+package ginplus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultLivenessPath  = "/livez"
+	defaultReadinessPath = "/readyz"
+	defaultStartupPath   = "/startupz"
+	defaultHealthTimeout = 3 * time.Second
+)
+
+const (
+	// HealthStatusUp 健康
+	HealthStatusUp = "up"
+	// HealthStatusDown 不健康
+	HealthStatusDown = "down"
+	// HealthStatusDraining 服务正在停止
+	HealthStatusDraining = "draining"
+	// HealthStatusStarting 服务尚未启动
+	HealthStatusStarting = "starting"
+)
+
+// HealthProbe 健康检查探针类型
+type HealthProbe string
+
+const (
+	// HealthLiveness 存活探针, 失败时应重启实例
+	HealthLiveness HealthProbe = "liveness"
+	// HealthReadiness 就绪探针, 失败时不应接收流量
+	HealthReadiness HealthProbe = "readiness"
+	// HealthStartup 启动探针, 成功前不执行其他探针
+	HealthStartup HealthProbe = "startup"
+)
+
+type (
+	// HealthChecker 健康检查接口
+	HealthChecker interface {
+		Check(ctx context.Context) error
+	}
+
+	// HealthCheckerFunc 函数形式的健康检查
+	HealthCheckerFunc func(ctx context.Context) error
+
+	// Health 健康检查配置
+	Health struct {
+		// LivenessPath 存活探针路径, 默认为/livez
+		LivenessPath string
+		// ReadinessPath 就绪探针路径, 默认为/readyz
+		ReadinessPath string
+		// StartupPath 启动探针路径, 默认为/startupz
+		StartupPath string
+		// Timeout 单个检查的默认超时时间, 默认为3s
+		Timeout time.Duration
+		// CacheTTL 检查结果的默认缓存时间, 为0时不缓存
+		CacheTTL time.Duration
+		// Checks 检查项
+		Checks []HealthCheck
+	}
+
+	// HealthCheck 健康检查项
+	HealthCheck struct {
+		// Name 检查项名称
+		Name string
+		// Checker 检查逻辑
+		Checker HealthChecker
+		// Probes 检查项所属的探针, 默认为就绪探针
+		Probes []HealthProbe
+		// Timeout 超时时间, 为0时使用Health.Timeout
+		Timeout time.Duration
+		// CacheTTL 结果缓存时间, 为0时使用Health.CacheTTL
+		CacheTTL time.Duration
+	}
+
+	// HealthReport 健康检查报告
+	HealthReport struct {
+		Status string                       `json:"status"`
+		Checks map[string]HealthCheckResult `json:"checks,omitempty"`
+	}
+
+	// HealthCheckResult 单个检查项的结果
+	HealthCheckResult struct {
+		Status    string    `json:"status"`
+		Error     string    `json:"error,omitempty"`
+		Duration  string    `json:"duration"`
+		CheckedAt time.Time `json:"checked_at"`
+	}
+
+	// healthState 检查项运行时状态, 用于缓存结果
+	healthState struct {
+		check    HealthCheck
+		timeout  time.Duration
+		cacheTTL time.Duration
+
+		mtx    sync.Mutex
+		result HealthCheckResult
+	}
+)
+
+// Check 实现HealthChecker接口
+func (f HealthCheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// DBHealthChecker 检查数据库连接
+func DBHealthChecker(db *gorm.DB) HealthChecker {
+	return HealthCheckerFunc(func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	})
+}
+
+// HTTPHealthChecker 检查HTTP依赖, 状态码小于400视为健康, client为空时使用http.DefaultClient
+func HTTPHealthChecker(url string, client *http.Client) HealthChecker {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return HealthCheckerFunc(func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= http.StatusBadRequest {
+			return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+		}
+		return nil
+	})
+}
+
+// run 执行检查, 缓存未过期时直接返回缓存结果
+func (s *healthState) run(ctx context.Context) HealthCheckResult {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.cacheTTL > 0 && !s.result.CheckedAt.IsZero() && time.Since(s.result.CheckedAt) < s.cacheTTL {
+		return s.result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	start := time.Now()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.check.Checker.Check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := HealthCheckResult{
+		Status:    HealthStatusUp,
+		Duration:  time.Since(start).String(),
+		CheckedAt: start,
+	}
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("timeout after %s", s.timeout)
+		}
+		result.Status = HealthStatusDown
+		result.Error = err.Error()
+	}
+	s.result = result
+	return result
+}
+
+func (s *healthState) hasProbe(probe HealthProbe) bool {
+	probes := s.check.Probes
+	if len(probes) == 0 {
+		probes = []HealthProbe{HealthReadiness}
+	}
+	for _, p := range probes {
+		if p == probe {
+			return true
+		}
+	}
+	return false
+}
+
+// healthHandler 健康检查处理函数
+type healthHandler struct {
+	engine *GinEngine
+	states []*healthState
+}
+
+func newHealthHandler(engine *GinEngine, health *Health) *healthHandler {
+	timeout := health.Timeout
+	if timeout <= 0 {
+		timeout = defaultHealthTimeout
+	}
+	h := &healthHandler{engine: engine, states: make([]*healthState, 0, len(health.Checks))}
+	for _, check := range health.Checks {
+		if check.Checker == nil {
+			continue
+		}
+		state := &healthState{check: check, timeout: check.Timeout, cacheTTL: check.CacheTTL}
+		if state.timeout <= 0 {
+			state.timeout = timeout
+		}
+		if state.cacheTTL <= 0 {
+			state.cacheTTL = health.CacheTTL
+		}
+		h.states = append(h.states, state)
+	}
+	return h
+}
+
+// report 并发执行探针下的所有检查项
+func (h *healthHandler) report(ctx context.Context, probe HealthProbe) HealthReport {
+	report := HealthReport{Status: HealthStatusUp, Checks: make(map[string]HealthCheckResult)}
+	var (
+		wg  sync.WaitGroup
+		mtx sync.Mutex
+	)
+	for _, state := range h.states {
+		if !state.hasProbe(probe) {
+			continue
+		}
+		wg.Add(1)
+		go func(state *healthState) {
+			defer wg.Done()
+			result := state.run(ctx)
+			mtx.Lock()
+			defer mtx.Unlock()
+			report.Checks[state.check.Name] = result
+			if result.Status != HealthStatusUp {
+				report.Status = HealthStatusDown
+			}
+		}(state)
+	}
+	wg.Wait()
+	return report
+}
+
+func (h *healthHandler) handle(probe HealthProbe) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var report HealthReport
+		switch {
+		case probe == HealthReadiness && h.engine.draining.Load():
+			report = HealthReport{Status: HealthStatusDraining}
+		case probe != HealthLiveness && !h.engine.started.Load():
+			report = HealthReport{Status: HealthStatusStarting}
+		default:
+			report = h.report(c.Request.Context(), probe)
+		}
+
+		status := http.StatusOK
+		if report.Status != HealthStatusUp {
+			status = http.StatusServiceUnavailable
+		}
+		c.AbortWithStatusJSON(status, report)
+	}
+}
+
+func registerHealth(instance *GinEngine, health *Health) {
+	if health == nil {
+		return
+	}
+	if health.LivenessPath == "" {
+		health.LivenessPath = defaultLivenessPath
+	}
+	if health.ReadinessPath == "" {
+		health.ReadinessPath = defaultReadinessPath
+	}
+	if health.StartupPath == "" {
+		health.StartupPath = defaultStartupPath
+	}
+	h := newHealthHandler(instance, health)
+	instance.GET(health.LivenessPath, h.handle(HealthLiveness))
+	instance.GET(health.ReadinessPath, h.handle(HealthReadiness))
+	instance.GET(health.StartupPath, h.handle(HealthStartup))
+}
+
+// RegisterHealth 注册健康检查, 包括存活、就绪和启动探针
+func (l *GinEngine) RegisterHealth(health ...*Health) *GinEngine {
+	if len(health) > 0 {
+		l.health = health[0]
+	}
+	registerHealth(l, l.health)
+	return l
+}
+
+// WithHealth 自定义健康检查
+func WithHealth(health *Health) OptionFun {
+	return func(g *GinEngine) {
+		g.health = health
+	}
+}
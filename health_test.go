@@ -0,0 +1,76 @@
+package ginplus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGinEngine_RegisterHealth(t *testing.T) {
+	var calls atomic.Int32
+	failing := atomic.Bool{}
+	instance := New(gin.New())
+	instance.RegisterHealth(&Health{
+		Timeout: 50 * time.Millisecond,
+		Checks: []HealthCheck{
+			{
+				Name: "cached",
+				Checker: HealthCheckerFunc(func(ctx context.Context) error {
+					calls.Add(1)
+					if failing.Load() {
+						return errors.New("dependency down")
+					}
+					return nil
+				}),
+				Probes:   []HealthProbe{HealthReadiness, HealthStartup},
+				CacheTTL: time.Minute,
+			},
+			{
+				Name: "slow",
+				Checker: HealthCheckerFunc(func(ctx context.Context) error {
+					<-ctx.Done()
+					return ctx.Err()
+				}),
+				Probes: []HealthProbe{HealthLiveness},
+			},
+		},
+	})
+
+	get := func(path string) (int, HealthReport) {
+		w := httptest.NewRecorder()
+		instance.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var report HealthReport
+		if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+			t.Fatal(err)
+		}
+		return w.Code, report
+	}
+
+	if code, report := get(defaultReadinessPath); code != http.StatusServiceUnavailable || report.Status != HealthStatusStarting {
+		t.Errorf("readiness before start: %d %+v", code, report)
+	}
+
+	instance.started.Store(true)
+	if code, report := get(defaultStartupPath); code != http.StatusOK || report.Checks["cached"].Status != HealthStatusUp {
+		t.Errorf("startup: %d %+v", code, report)
+	}
+	failing.Store(true)
+	if code, _ := get(defaultReadinessPath); code != http.StatusOK || calls.Load() != 1 {
+		t.Errorf("readiness should use cached result: %d, calls %d", code, calls.Load())
+	}
+	if code, report := get(defaultLivenessPath); code != http.StatusServiceUnavailable || report.Checks["slow"].Error == "" {
+		t.Errorf("liveness with timed out check: %d %+v", code, report)
+	}
+
+	instance.draining.Store(true)
+	if code, report := get(defaultReadinessPath); code != http.StatusServiceUnavailable || report.Status != HealthStatusDraining {
+		t.Errorf("readiness while draining: %d %+v", code, report)
+	}
+}
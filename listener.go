@@ -0,0 +1,270 @@
+package ginplus
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// SystemdAdminListener LISTEN_FDNAMES中该名称的listener用于管理端口, 其余用于主服务
+	SystemdAdminListener = "admin"
+
+	// 继承的第一个文件描述符, 0-2为标准输入输出
+	listenFdsStart = 3
+)
+
+type (
+	// adminServer 管理端口, 用于metrics、swagger-ui、ping和debug(含pprof)等内部接口
+	adminServer struct {
+		addr      string
+		listeners []net.Listener
+		engine    *gin.Engine
+		server    *http.Server
+	}
+
+	// unixSocket unix domain socket监听配置
+	unixSocket struct {
+		path string
+		mode os.FileMode
+	}
+
+	// serverListeners HTTP服务器和其监听的listener
+	serverListeners struct {
+		name      string
+		server    *http.Server
+		listeners []net.Listener
+		// 不为空时在listener上启用TLS
+		tlsConfig *tls.Config
+	}
+)
+
+// listen 为主服务、管理端口和metrics服务创建listener, 任一失败时关闭已创建的listener
+func (l *GinEngine) listen() (servers []serverListeners, err error) {
+	var opened []net.Listener
+	defer func() {
+		if err != nil {
+			for _, ln := range opened {
+				_ = ln.Close()
+			}
+		}
+	}()
+	// 使用Upgrader时listener由Upgrader管理, 升级时传递给子进程
+	listenWith := func(network, addr string, listen func() (net.Listener, error)) (net.Listener, error) {
+		if l.upgrader != nil {
+			return l.upgrader.listen(network, addr, listen)
+		}
+		ln, err := listen()
+		if err == nil {
+			opened = append(opened, ln)
+		}
+		return ln, err
+	}
+	listenTCP := func(addr string) (net.Listener, error) {
+		return listenWith("tcp", addr, func() (net.Listener, error) {
+			return net.Listen("tcp", addr)
+		})
+	}
+
+	// adopt 使用Upgrader时把传入的listener交给Upgrader管理, 升级时传递给子进程
+	adopt := func(lns []net.Listener) {
+		if l.upgrader == nil {
+			return
+		}
+		for _, ln := range lns {
+			l.upgrader.adopt(ln.Addr().Network()+":"+ln.Addr().String(), ln)
+		}
+	}
+
+	var systemd map[string][]net.Listener
+	if l.systemdListeners {
+		if systemd, err = systemdListeners(); err != nil {
+			return nil, err
+		}
+		if l.upgrader != nil {
+			systemd = l.upgrader.systemd(systemd)
+		} else {
+			for _, lns := range systemd {
+				opened = append(opened, lns...)
+			}
+		}
+	}
+
+	// 主服务: 传入的listener或systemd的listener优先于监听地址
+	public := serverListeners{name: "Server", server: l.server}
+	public.listeners = append(public.listeners, l.listeners...)
+	adopt(l.listeners)
+	for name, lns := range systemd {
+		if name != SystemdAdminListener || l.admin == nil {
+			public.listeners = append(public.listeners, lns...)
+		}
+	}
+	if len(public.listeners) == 0 && l.server.Addr != "" {
+		ln, err := listenTCP(l.server.Addr)
+		if err != nil {
+			return nil, err
+		}
+		public.listeners = append(public.listeners, ln)
+	}
+	for _, socket := range l.unixSockets {
+		ln, err := listenWith("unix", socket.path, func() (net.Listener, error) {
+			return listenUnix(socket)
+		})
+		if err != nil {
+			return nil, err
+		}
+		public.listeners = append(public.listeners, ln)
+	}
+	if len(public.listeners) == 0 {
+		return nil, errors.New("no listener configured")
+	}
+	servers = append(servers, public)
+
+	if l.admin != nil {
+		admin := serverListeners{name: "Admin server", server: l.admin.server}
+		admin.listeners = append(admin.listeners, l.admin.listeners...)
+		adopt(l.admin.listeners)
+		admin.listeners = append(admin.listeners, systemd[SystemdAdminListener]...)
+		if len(admin.listeners) == 0 {
+			ln, err := listenTCP(l.admin.addr)
+			if err != nil {
+				return nil, err
+			}
+			admin.listeners = append(admin.listeners, ln)
+		}
+		servers = append(servers, admin)
+	}
+
+	if l.metricsServer != nil {
+		ln, err := listenTCP(l.metricsServer.Addr)
+		if err != nil {
+			return nil, err
+		}
+		servers = append(servers, serverListeners{name: "Metrics server", server: l.metricsServer, listeners: []net.Listener{ln}})
+	}
+	return servers, nil
+}
+
+// listenUnix 监听unix domain socket, 会删除残留的socket文件
+func listenUnix(socket unixSocket) (net.Listener, error) {
+	if info, err := os.Stat(socket.path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(socket.path); err != nil {
+			return nil, err
+		}
+	}
+	ln, err := net.Listen("unix", socket.path)
+	if err != nil {
+		return nil, err
+	}
+	if socket.mode != 0 {
+		if err := os.Chmod(socket.path, socket.mode); err != nil {
+			_ = ln.Close()
+			return nil, err
+		}
+	}
+	return ln, nil
+}
+
+// systemdListeners 解析systemd socket activation传入的listener, 按LISTEN_FDNAMES分组, 未命名的为空字符串
+func systemdListeners() (map[string][]net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return nil, nil
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	// 避免子进程重复使用
+	_ = os.Unsetenv("LISTEN_PID")
+	_ = os.Unsetenv("LISTEN_FDS")
+	_ = os.Unsetenv("LISTEN_FDNAMES")
+
+	listeners := make(map[string][]net.Listener, len(names))
+	for i := 0; i < count; i++ {
+		name := ""
+		if i < len(names) {
+			name = names[i]
+		}
+		file := os.NewFile(uintptr(listenFdsStart+i), name)
+		ln, err := net.FileListener(file)
+		_ = file.Close()
+		if err != nil {
+			for _, lns := range listeners {
+				for _, ln := range lns {
+					_ = ln.Close()
+				}
+			}
+			return nil, fmt.Errorf("systemd listener %d: %w", listenFdsStart+i, err)
+		}
+		listeners[name] = append(listeners[name], ln)
+	}
+	return listeners, nil
+}
+
+// Admin 返回管理端口的路由, 未设置管理端口时返回主路由
+func (l *GinEngine) Admin() *gin.Engine {
+	if l.admin != nil {
+		return l.admin.engine
+	}
+	return l.Engine
+}
+
+// WithListeners 使用已创建的listener启动主服务, 例如测试中的随机端口, 此时不再监听启动地址
+//
+// 配合WithUpgrader使用时, 子进程应通过Upgrader.Listen创建listener以继承父进程的socket
+func WithListeners(listeners ...net.Listener) OptionFun {
+	return func(g *GinEngine) {
+		g.listeners = append(g.listeners, listeners...)
+	}
+}
+
+// WithUnixSocket 主服务同时监听unix domain socket, mode为0时不修改文件权限
+func WithUnixSocket(path string, mode os.FileMode) OptionFun {
+	return func(g *GinEngine) {
+		g.unixSockets = append(g.unixSockets, unixSocket{path: path, mode: mode})
+	}
+}
+
+// WithSystemdListeners 使用systemd socket activation(LISTEN_FDS)传入的listener,
+// 名称为SystemdAdminListener的listener用于管理端口
+func WithSystemdListeners() OptionFun {
+	return func(g *GinEngine) {
+		g.systemdListeners = true
+	}
+}
+
+// WithAdminAddr 设置管理端口, metrics、swagger-ui、ping和debug(含pprof)将挂载在管理端口上, 不经过主路由的中间件
+func WithAdminAddr(addr string) OptionFun {
+	return func(g *GinEngine) {
+		g.adminServer().addr = addr
+	}
+}
+
+// WithAdminListener 使用已创建的listener启动管理端口
+func WithAdminListener(listeners ...net.Listener) OptionFun {
+	return func(g *GinEngine) {
+		admin := g.adminServer()
+		admin.listeners = append(admin.listeners, listeners...)
+	}
+}
+
+func (l *GinEngine) adminServer() *adminServer {
+	if l.admin == nil {
+		engine := gin.New()
+		engine.Use(gin.Recovery())
+		l.admin = &adminServer{
+			engine: engine,
+			server: &http.Server{Handler: engine},
+		}
+	}
+	return l.admin
+}
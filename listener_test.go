@@ -0,0 +1,84 @@
+package ginplus
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGinEngine_Listeners(t *testing.T) {
+	publicLn, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminLn, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	socket := filepath.Join(t.TempDir(), "gin-plus.sock")
+
+	r := gin.New()
+	r.GET("/hello", func(c *gin.Context) {
+		c.String(http.StatusOK, "hello")
+	})
+	instance := New(r,
+		WithAddr(""),
+		WithListeners(publicLn),
+		WithUnixSocket(socket, 0o660),
+		WithAdminListener(adminLn),
+	)
+	instance.RegisterPing().RegisterDebug(&Debug{AllowIPs: []string{"127.0.0.1"}})
+	if err := instance.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer instance.Stop()
+
+	unixClient := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+	tests := []struct {
+		name   string
+		client *http.Client
+		url    string
+		status int
+	}{
+		{name: "public", client: http.DefaultClient, url: "http://" + publicLn.Addr().String() + "/hello", status: http.StatusOK},
+		{name: "unix socket", client: unixClient, url: "http://unix/hello", status: http.StatusOK},
+		{name: "ping not on public", client: http.DefaultClient, url: "http://" + publicLn.Addr().String() + defaultPingPath, status: http.StatusNotFound},
+		{name: "admin ping", client: http.DefaultClient, url: "http://" + adminLn.Addr().String() + defaultPingPath, status: http.StatusOK},
+		{name: "admin pprof", client: http.DefaultClient, url: "http://" + adminLn.Addr().String() + defaultDebugPath + "/pprof/cmdline", status: http.StatusOK},
+		{name: "pprof not on public", client: http.DefaultClient, url: "http://" + publicLn.Addr().String() + defaultDebugPath + "/pprof/cmdline", status: http.StatusNotFound},
+		{name: "api not on admin", client: http.DefaultClient, url: "http://" + adminLn.Addr().String() + "/hello", status: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := tt.client.Get(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("want status %d, got %d", tt.status, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestSystemdListeners(t *testing.T) {
+	t.Setenv("LISTEN_PID", "1")
+	t.Setenv("LISTEN_FDS", "1")
+	listeners, err := systemdListeners()
+	if err != nil || listeners != nil {
+		t.Errorf("want listeners of other process ignored, got %v %v", listeners, err)
+	}
+
+	if err := New(gin.New(), WithAddr("")).Start(); err == nil {
+		t.Error("want error without listener")
+	}
+}
@@ -1,8 +1,17 @@
 package ginplus
 
 import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
@@ -22,3 +31,411 @@ func SetLogger(l *zap.Logger) {
 func Logger() *zap.Logger {
 	return logger
 }
+
+// LogField 访问日志可选字段
+type LogField string
+
+const (
+	// LogFieldRoute 路由模板, 例如 /user/:id
+	LogFieldRoute LogField = "route"
+	// LogFieldRequestID 请求ID
+	LogFieldRequestID LogField = "request_id"
+	// LogFieldUserID 用户ID
+	LogFieldUserID LogField = "user_id"
+	// LogFieldBytesIn 请求体大小
+	LogFieldBytesIn LogField = "bytes_in"
+	// LogFieldBytesOut 响应体大小
+	LogFieldBytesOut LogField = "bytes_out"
+	// LogFieldUserAgent User-Agent
+	LogFieldUserAgent LogField = "user_agent"
+	// LogFieldReferer Referer
+	LogFieldReferer LogField = "referer"
+)
+
+const (
+	defaultTimeLayout = time.RFC3339
+	defaultRedactMask = "***"
+	// truncatedBodyMark 被截断的body末尾追加的标记
+	truncatedBodyMark = "...[truncated]"
+	defaultUserIDKey  = "user_id"
+)
+
+type loggerConfig struct {
+	// 日志记录器, 为空时使用全局logger
+	logger *zap.Logger
+	// 时间格式
+	timeLayout string
+	// 额外输出的字段
+	fields []LogField
+	// 获取用户ID
+	userIDFunc func(c *gin.Context) string
+	// 需要记录的请求头
+	headers []string
+	// 请求体记录的最大字节数, 0表示不记录
+	reqBodyLimit int
+	// 响应体记录的最大字节数, 0表示不记录
+	respBodyLimit int
+	// 需要脱敏的请求头
+	redactHeaders map[string]struct{}
+	// 需要脱敏的字段, 作用于query、表单和json body
+	redactFields map[string]struct{}
+	// 按状态码分类的采样率, key为状态码首位, 例如2表示2xx
+	sampleRates map[int]float64
+	// 不记录日志的路径
+	skipPaths map[string]struct{}
+}
+
+// LoggerOption 访问日志配置
+type LoggerOption func(*loggerConfig)
+
+func newLoggerConfig(opts ...LoggerOption) *loggerConfig {
+	cfg := &loggerConfig{
+		timeLayout: defaultTimeLayout,
+		userIDFunc: func(c *gin.Context) string {
+			return c.GetString(defaultUserIDKey)
+		},
+		redactHeaders: map[string]struct{}{
+			"authorization": {},
+			"cookie":        {},
+		},
+		redactFields: make(map[string]struct{}),
+		sampleRates:  make(map[int]float64),
+		skipPaths: map[string]struct{}{
+			defaultPingPath:    {},
+			defaultMetricsPath: {},
+		},
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// WithLoggerZap 设置访问日志使用的日志记录器
+func WithLoggerZap(l *zap.Logger) LoggerOption {
+	return func(c *loggerConfig) {
+		c.logger = l
+	}
+}
+
+// WithLoggerTimeLayout 设置时间格式
+func WithLoggerTimeLayout(layout string) LoggerOption {
+	return func(c *loggerConfig) {
+		c.timeLayout = layout
+	}
+}
+
+// WithLoggerFields 设置额外输出的字段
+func WithLoggerFields(fields ...LogField) LoggerOption {
+	return func(c *loggerConfig) {
+		c.fields = fields
+	}
+}
+
+// WithLoggerUserIDFunc 设置获取用户ID的函数, 默认从gin.Context中读取user_id
+func WithLoggerUserIDFunc(fn func(c *gin.Context) string) LoggerOption {
+	return func(c *loggerConfig) {
+		c.userIDFunc = fn
+	}
+}
+
+// WithLoggerHeaders 设置需要记录的请求头
+func WithLoggerHeaders(headers ...string) LoggerOption {
+	return func(c *loggerConfig) {
+		c.headers = headers
+	}
+}
+
+// WithLoggerRequestBody 记录请求体, limit为最大记录字节数
+func WithLoggerRequestBody(limit int) LoggerOption {
+	return func(c *loggerConfig) {
+		c.reqBodyLimit = limit
+	}
+}
+
+// WithLoggerResponseBody 记录响应体, limit为最大记录字节数
+func WithLoggerResponseBody(limit int) LoggerOption {
+	return func(c *loggerConfig) {
+		c.respBodyLimit = limit
+	}
+}
+
+// WithLoggerRedactHeaders 设置需要脱敏的请求头, 默认脱敏Authorization和Cookie
+func WithLoggerRedactHeaders(headers ...string) LoggerOption {
+	return func(c *loggerConfig) {
+		for _, header := range headers {
+			c.redactHeaders[strings.ToLower(header)] = struct{}{}
+		}
+	}
+}
+
+// WithLoggerRedactFields 设置需要脱敏的字段, 作用于query、表单和json body
+func WithLoggerRedactFields(fields ...string) LoggerOption {
+	return func(c *loggerConfig) {
+		for _, field := range fields {
+			c.redactFields[strings.ToLower(field)] = struct{}{}
+		}
+	}
+}
+
+// WithLoggerSampleRate 设置某一类状态码的采样率, statusClass为状态码首位, 例如2表示2xx, rate取值[0, 1];
+// 5xx总是记录, 不参与采样
+func WithLoggerSampleRate(statusClass int, rate float64) LoggerOption {
+	return func(c *loggerConfig) {
+		c.sampleRates[statusClass] = rate
+	}
+}
+
+// WithLoggerSkipPaths 设置不记录日志的路径, 会覆盖默认的/ping和/metrics
+func WithLoggerSkipPaths(paths ...string) LoggerOption {
+	return func(c *loggerConfig) {
+		c.skipPaths = make(map[string]struct{}, len(paths))
+		for _, p := range paths {
+			c.skipPaths[p] = struct{}{}
+		}
+	}
+}
+
+func (c *loggerConfig) zap() *zap.Logger {
+	if c.logger != nil {
+		return c.logger
+	}
+	return Logger()
+}
+
+func (c *loggerConfig) skip(ctx *gin.Context) bool {
+	if _, ok := c.skipPaths[ctx.Request.URL.Path]; ok {
+		return true
+	}
+	_, ok := c.skipPaths[ctx.FullPath()]
+	return ok
+}
+
+// sampled 判断该状态码的请求是否需要记录, 5xx总是记录
+func (c *loggerConfig) sampled(status int) bool {
+	if status >= http.StatusInternalServerError {
+		return true
+	}
+	rate, ok := c.sampleRates[status/100]
+	if !ok || rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+	return rand.Float64() < rate
+}
+
+func (c *loggerConfig) redactHeader(name, value string) string {
+	if _, ok := c.redactHeaders[strings.ToLower(name)]; ok {
+		return defaultRedactMask
+	}
+	return value
+}
+
+// redactURI 对query中的敏感字段脱敏
+func (c *loggerConfig) redactURI(uri string) string {
+	if len(c.redactFields) == 0 {
+		return uri
+	}
+	u, err := url.ParseRequestURI(uri)
+	if err != nil || u.RawQuery == "" {
+		return uri
+	}
+	u.RawQuery = c.redactValues(u.Query()).Encode()
+	return u.String()
+}
+
+func (c *loggerConfig) redactValues(values url.Values) url.Values {
+	for key := range values {
+		if _, ok := c.redactFields[strings.ToLower(key)]; ok {
+			values[key] = []string{defaultRedactMask}
+		}
+	}
+	return values
+}
+
+// redactBody 对body中的敏感字段脱敏, truncated表示body超过记录上限被截断, 此时在末尾追加截断标记;
+// 无法完整解析的json(例如被截断)只输出解析成功并已脱敏的部分
+func (c *loggerConfig) redactBody(contentType string, body []byte, truncated bool) string {
+	out := string(body)
+	if len(c.redactFields) > 0 && len(body) > 0 {
+		switch {
+		case strings.Contains(contentType, "json"):
+			var data any
+			if err := json.Unmarshal(body, &data); err != nil {
+				out = c.redactPartialJSON(body)
+				break
+			}
+			b, _ := json.Marshal(c.redactJSON(data))
+			out = string(b)
+		case strings.Contains(contentType, "x-www-form-urlencoded"):
+			out = c.redactForm(out)
+		}
+	}
+	if truncated {
+		out += truncatedBodyMark
+	}
+	return out
+}
+
+// redactForm 按原顺序对表单中的敏感字段脱敏, 被截断或编码错误的字段同样可以处理
+func (c *loggerConfig) redactForm(body string) string {
+	pairs := strings.Split(body, "&")
+	for i, pair := range pairs {
+		rawKey, _, _ := strings.Cut(pair, "=")
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil {
+			key = rawKey
+		}
+		if _, ok := c.redactFields[strings.ToLower(key)]; ok {
+			pairs[i] = rawKey + "=" + url.QueryEscape(defaultRedactMask)
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// redactPartialJSON 逐个token对json脱敏, 遇到无法解析的位置时丢弃其后的内容, 用于被截断或格式错误的json
+func (c *loggerConfig) redactPartialJSON(body []byte) string {
+	type frame struct {
+		object    bool
+		expectKey bool
+	}
+	var (
+		out    strings.Builder
+		stack  []*frame
+		copied int64
+		redact bool
+	)
+	mask := `"` + defaultRedactMask + `"`
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	for {
+		before := dec.InputOffset()
+		tok, err := dec.Token()
+		if err == io.EOF {
+			out.Write(body[copied:])
+			return out.String()
+		}
+		if err != nil {
+			out.Write(body[copied:before])
+			return out.String()
+		}
+
+		var top *frame
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+		if key, ok := tok.(string); ok && top != nil && top.object && top.expectKey {
+			top.expectKey = false
+			_, redact = c.redactFields[strings.ToLower(key)]
+			continue
+		}
+		if top != nil && top.object {
+			top.expectKey = true
+		}
+
+		if redact {
+			redact = false
+			// 跳过值前面的冒号和空白, 整个值(包括对象和数组)替换为掩码
+			start := before + int64(len(body[before:])-len(bytes.TrimLeft(body[before:], " \t\r\n:")))
+			if d, ok := tok.(json.Delim); ok && (d == '{' || d == '[') {
+				for depth := 1; depth > 0; {
+					t, err := dec.Token()
+					if err != nil {
+						out.Write(body[copied:start])
+						out.WriteString(mask)
+						return out.String()
+					}
+					switch t {
+					case json.Delim('{'), json.Delim('['):
+						depth++
+					case json.Delim('}'), json.Delim(']'):
+						depth--
+					}
+				}
+			}
+			out.Write(body[copied:start])
+			out.WriteString(mask)
+			copied = dec.InputOffset()
+			continue
+		}
+
+		switch tok {
+		case json.Delim('{'):
+			stack = append(stack, &frame{object: true, expectKey: true})
+		case json.Delim('['):
+			stack = append(stack, &frame{})
+		case json.Delim('}'), json.Delim(']'):
+			stack = stack[:len(stack)-1]
+		}
+	}
+}
+
+func (c *loggerConfig) redactJSON(data any) any {
+	switch v := data.(type) {
+	case map[string]any:
+		for key, val := range v {
+			if _, ok := c.redactFields[strings.ToLower(key)]; ok {
+				v[key] = defaultRedactMask
+				continue
+			}
+			v[key] = c.redactJSON(val)
+		}
+	case []any:
+		for i, val := range v {
+			v[i] = c.redactJSON(val)
+		}
+	}
+	return data
+}
+
+// readRequestBody 读取最多limit字节的请求体, 并保证后续handler仍可完整读取, 请求体超过limit时truncated为true
+func readRequestBody(c *gin.Context, limit int) (body []byte, truncated bool) {
+	if limit <= 0 || c.Request.Body == nil || c.Request.Body == http.NoBody {
+		return nil, false
+	}
+	// 多读一个字节用于判断是否被截断
+	buf, _ := io.ReadAll(io.LimitReader(c.Request.Body, int64(limit)+1))
+	c.Request.Body = &multiReadCloser{
+		Reader: io.MultiReader(bytes.NewReader(buf), c.Request.Body),
+		Closer: c.Request.Body,
+	}
+	if len(buf) > limit {
+		return buf[:limit], true
+	}
+	return buf, false
+}
+
+type multiReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// bodyLogWriter 记录响应体的ResponseWriter
+type bodyLogWriter struct {
+	gin.ResponseWriter
+	body  *bytes.Buffer
+	limit int
+	// 响应体超过limit被截断
+	truncated bool
+}
+
+func (w *bodyLogWriter) Write(b []byte) (int, error) {
+	w.capture(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyLogWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *bodyLogWriter) capture(b []byte) {
+	remain := w.limit - w.body.Len()
+	if len(b) > remain {
+		w.truncated = true
+		b = b[:max(remain, 0)]
+	}
+	w.body.Write(b)
+}
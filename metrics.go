@@ -0,0 +1,334 @@
+package ginplus
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// ginplusModule ginplus的模块路径, 用于构建信息指标
+const ginplusModule = "github.com/aide-cloud/gin-plus"
+
+// unmatchedRoute 未匹配到路由时的route标签, 避免把原始路径作为标签导致基数爆炸
+const unmatchedRoute = "unmatched"
+
+type metricsConfig struct {
+	// 指标命名空间
+	namespace string
+	// 指标注册器, 为空时使用prometheus.DefaultRegisterer
+	registerer prometheus.Registerer
+	// 耗时直方图的桶, 单位为秒
+	durationBuckets []float64
+	// 请求/响应大小直方图的桶, 单位为字节
+	sizeBuckets []float64
+	// native histogram的桶增长因子, 大于1时启用native histogram
+	nativeBucketFactor float64
+}
+
+// MetricsOption http指标配置
+type MetricsOption func(*metricsConfig)
+
+// WithMetricsNamespace 设置指标命名空间
+func WithMetricsNamespace(namespace string) MetricsOption {
+	return func(c *metricsConfig) {
+		c.namespace = namespace
+	}
+}
+
+// WithMetricsRegisterer 设置指标注册器
+func WithMetricsRegisterer(registerer prometheus.Registerer) MetricsOption {
+	return func(c *metricsConfig) {
+		c.registerer = registerer
+	}
+}
+
+// WithMetricsDurationBuckets 设置耗时直方图的桶, 单位为秒
+func WithMetricsDurationBuckets(buckets []float64) MetricsOption {
+	return func(c *metricsConfig) {
+		c.durationBuckets = buckets
+	}
+}
+
+// WithMetricsSizeBuckets 设置请求/响应大小直方图的桶, 单位为字节
+func WithMetricsSizeBuckets(buckets []float64) MetricsOption {
+	return func(c *metricsConfig) {
+		c.sizeBuckets = buckets
+	}
+}
+
+// WithMetricsNativeHistogram 启用native histogram, factor为桶增长因子, 例如1.1
+func WithMetricsNativeHistogram(factor float64) MetricsOption {
+	return func(c *metricsConfig) {
+		c.nativeBucketFactor = factor
+	}
+}
+
+type httpMetrics struct {
+	requests     *prometheus.CounterVec
+	duration     *prometheus.HistogramVec
+	inFlight     prometheus.Gauge
+	requestSize  *prometheus.HistogramVec
+	responseSize *prometheus.HistogramVec
+}
+
+func newHTTPMetrics(cfg *metricsConfig) (*httpMetrics, error) {
+	labels := []string{"method", "route", "status_class"}
+	histogram := func(name, help string, buckets []float64) *prometheus.HistogramVec {
+		return prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:                   cfg.namespace,
+			Subsystem:                   "http",
+			Name:                        name,
+			Help:                        help,
+			Buckets:                     buckets,
+			NativeHistogramBucketFactor: cfg.nativeBucketFactor,
+		}, labels)
+	}
+	m := &httpMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: cfg.namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Total number of HTTP requests.",
+		}, labels),
+		duration:     histogram("request_duration_seconds", "Duration of HTTP requests in seconds.", cfg.durationBuckets),
+		requestSize:  histogram("request_size_bytes", "Size of HTTP requests in bytes.", cfg.sizeBuckets),
+		responseSize: histogram("response_size_bytes", "Size of HTTP responses in bytes.", cfg.sizeBuckets),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: cfg.namespace,
+			Subsystem: "http",
+			Name:      "requests_in_flight",
+			Help:      "Number of HTTP requests currently being served.",
+		}),
+	}
+
+	registerer := cfg.registerer
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+	var err error
+	if m.requests, err = registerCollector(registerer, m.requests); err != nil {
+		return nil, err
+	}
+	if m.duration, err = registerCollector(registerer, m.duration); err != nil {
+		return nil, err
+	}
+	if m.requestSize, err = registerCollector(registerer, m.requestSize); err != nil {
+		return nil, err
+	}
+	if m.responseSize, err = registerCollector(registerer, m.responseSize); err != nil {
+		return nil, err
+	}
+	if m.inFlight, err = registerCollector(registerer, m.inFlight); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Metrics http请求指标, 包括请求数、耗时、并发数和请求/响应大小, 按路由模板、请求方法和状态码分类打标签
+//
+// 启用Tracing中间件时, 耗时和请求数会携带trace_id exemplar; 指标注册冲突时记录错误并跳过采集, 不影响请求处理
+func (l *Middleware) Metrics(opts ...MetricsOption) gin.HandlerFunc {
+	cfg := &metricsConfig{
+		durationBuckets: prometheus.DefBuckets,
+		sizeBuckets:     prometheus.ExponentialBuckets(100, 10, 7),
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	m, err := newHTTPMetrics(cfg)
+	if err != nil {
+		Logger().Sugar().Errorf("[GIN-PLUS] [ERROR] register http metrics: %v", err)
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	return func(c *gin.Context) {
+		startTime := time.Now()
+		m.inFlight.Inc()
+		defer m.inFlight.Dec()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		lvs := []string{c.Request.Method, route, statusClass(c.Writer.Status())}
+		exemplar := traceExemplar(c)
+
+		observe(m.duration.WithLabelValues(lvs...), time.Since(startTime).Seconds(), exemplar)
+		if c.Request.ContentLength >= 0 {
+			m.requestSize.WithLabelValues(lvs...).Observe(float64(c.Request.ContentLength))
+		}
+		m.responseSize.WithLabelValues(lvs...).Observe(float64(max(c.Writer.Size(), 0)))
+
+		counter := m.requests.WithLabelValues(lvs...)
+		if adder, ok := counter.(prometheus.ExemplarAdder); ok && exemplar != nil {
+			adder.AddWithExemplar(1, exemplar)
+			return
+		}
+		counter.Inc()
+	}
+}
+
+// traceExemplar 从已采样的span中生成exemplar
+func traceExemplar(c *gin.Context) prometheus.Labels {
+	sc := oteltrace.SpanContextFromContext(c.Request.Context())
+	if !sc.HasTraceID() || !sc.IsSampled() {
+		return nil
+	}
+	return prometheus.Labels{"trace_id": sc.TraceID().String()}
+}
+
+func observe(o prometheus.Observer, v float64, exemplar prometheus.Labels) {
+	if eo, ok := o.(prometheus.ExemplarObserver); ok && exemplar != nil {
+		eo.ObserveWithExemplar(v, exemplar)
+		return
+	}
+	o.Observe(v)
+}
+
+// statusClass 把状态码转换为2xx、4xx等分类
+func statusClass(status int) string {
+	return strconv.Itoa(status/100) + "xx"
+}
+
+// registerCollector 注册指标, 已注册时返回已有的指标
+func registerCollector[T prometheus.Collector](registerer prometheus.Registerer, c T) (T, error) {
+	if err := registerer.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if !errors.As(err, &are) {
+			return c, err
+		}
+		existing, ok := are.ExistingCollector.(T)
+		if !ok {
+			return c, err
+		}
+		return existing, nil
+	}
+	return c, nil
+}
+
+// registry 返回指标注册器和采集器
+func (m *Metrics) registry() (prometheus.Registerer, prometheus.Gatherer) {
+	registerer := m.Registerer
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+	gatherer := m.Gatherer
+	if gatherer == nil {
+		if g, ok := registerer.(prometheus.Gatherer); ok {
+			gatherer = g
+		} else {
+			gatherer = prometheus.DefaultGatherer
+		}
+	}
+	return registerer, gatherer
+}
+
+// registerDefaultCollectors 注册ginplus自身的指标, withRuntime为true时同时注册go和进程指标
+func registerDefaultCollectors(instance *GinEngine, registerer prometheus.Registerer, withRuntime bool) error {
+	collectorList := []prometheus.Collector{
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "ginplus",
+			Name:      "routes",
+			Help:      "Number of routes registered in the gin engine.",
+		}, func() float64 {
+			return float64(len(instance.Routes()))
+		}),
+		newBuildInfoCollector(),
+	}
+	if withRuntime {
+		collectorList = append(collectorList,
+			collectors.NewGoCollector(),
+			collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		)
+	}
+	for _, c := range collectorList {
+		if _, err := registerCollector(registerer, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newBuildInfoCollector 构建信息指标, 值恒为1
+func newBuildInfoCollector() prometheus.Collector {
+	labels := prometheus.Labels{"goversion": runtime.Version()}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		labels["path"] = info.Main.Path
+		labels["version"] = info.Main.Version
+		for _, dep := range info.Deps {
+			if dep.Path == ginplusModule {
+				labels["ginplus_version"] = dep.Version
+			}
+		}
+	}
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   "ginplus",
+		Name:        "build_info",
+		Help:        "Build information of the running binary.",
+		ConstLabels: labels,
+	})
+	gauge.Set(1)
+	return gauge
+}
+
+// ipAllowlist 只允许列表中的IP或CIDR访问, 按连接的对端地址匹配, 不信任X-Forwarded-For等请求头
+func ipAllowlist(list []string) gin.HandlerFunc {
+	nets := parseIPNets(list)
+	return func(c *gin.Context) {
+		if !ipAllowed(nets, c.RemoteIP()) {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		c.Next()
+	}
+}
+
+// parseIPNets 解析IP或CIDR列表, 忽略无效的项
+func parseIPNets(list []string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(list))
+	for _, item := range list {
+		if !strings.Contains(item, "/") {
+			if strings.Contains(item, ":") {
+				item += "/128"
+			} else {
+				item += "/32"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(item)
+		if err != nil {
+			Logger().Sugar().Warnf("[GIN-PLUS] [WARNING] invalid ip allowlist item %q: %v", item, err)
+			continue
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets
+}
+
+// ipAllowed ip是否在nets中
+func ipAllowed(nets []*net.IPNet, clientIP string) bool {
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package ginplus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestMiddleware_Metrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	mid := NewMiddleware()
+	r := gin.New()
+	r.Use(
+		mid.Tracing("", WithTracerProvider(tracesdk.NewTracerProvider())),
+		mid.Metrics(WithMetricsRegisterer(registry), WithMetricsNamespace("test")),
+	)
+	r.GET("/user/:id", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	for _, target := range []string{"/user/1", "/user/2", "/not-found"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	requests, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mf := range requests {
+		if mf.GetName() != "test_http_requests_total" {
+			continue
+		}
+		for _, metric := range mf.GetMetric() {
+			if metric.GetCounter().GetExemplar() == nil {
+				t.Error("request counter has no trace exemplar")
+			}
+		}
+	}
+
+	if n := testutil.CollectAndCount(registry, "test_http_requests_total"); n != 2 {
+		t.Errorf("want 2 request series, got %d", n)
+	}
+	m, err := newHTTPMetrics(&metricsConfig{namespace: "test", registerer: registry})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := testutil.ToFloat64(m.requests.WithLabelValues(http.MethodGet, "/user/:id", "2xx")); v != 2 {
+		t.Errorf("want 2 requests for /user/:id, got %v", v)
+	}
+	if v := testutil.ToFloat64(m.requests.WithLabelValues(http.MethodGet, unmatchedRoute, "4xx")); v != 1 {
+		t.Errorf("want 1 unmatched request, got %v", v)
+	}
+}
+
+func TestMiddleware_MetricsConflict(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	// 同名但标签不同的指标已注册
+	registry.MustRegister(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "test",
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Conflicting counter.",
+	}, []string{"path"}))
+
+	r := gin.New()
+	r.Use(NewMiddleware().Metrics(WithMetricsRegisterer(registry), WithMetricsNamespace("test")))
+	r.GET("/ping", func(c *gin.Context) {
+		c.String(http.StatusOK, "pong")
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "pong" {
+		t.Errorf("want request passed through, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestGinEngine_RegisterMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	instance := New(gin.New())
+	instance.RegisterMetrics(&Metrics{
+		Registerer: registry,
+		Accounts:   gin.Accounts{"admin": "secret"},
+		AllowIPs:   []string{"192.0.2.0/24"},
+	})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		user       string
+		status     int
+	}{
+		{name: "ip denied", remoteAddr: "198.51.100.1:1234", user: "admin", status: http.StatusForbidden},
+		{name: "forwarded for denied", remoteAddr: "198.51.100.1:1234", forwarded: "192.0.2.1", user: "admin", status: http.StatusForbidden},
+		{name: "no auth", remoteAddr: "192.0.2.1:1234", status: http.StatusUnauthorized},
+		{name: "ok", remoteAddr: "192.0.2.1:1234", user: "admin", status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, defaultMetricsPath, nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if tt.user != "" {
+				req.SetBasicAuth(tt.user, "secret")
+			}
+			w := httptest.NewRecorder()
+			instance.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("want status %d, got %d", tt.status, w.Code)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			for _, name := range []string{"ginplus_routes 1", "ginplus_build_info", "go_goroutines"} {
+				if !strings.Contains(w.Body.String(), name) {
+					t.Errorf("metric %s not exposed", name)
+				}
+			}
+		})
+	}
+}
@@ -1,15 +1,22 @@
 package ginplus
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
-	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
 	oteltrace "go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
@@ -20,6 +27,10 @@ type Middleware struct {
 	serverName string
 	id         string
 	env        string
+	// 链路上下文传播方式
+	propagator propagation.TextMapPropagator
+	// 由中间件创建的资源的关闭函数, 例如TracerProvider
+	shutdowns []func(ctx context.Context) error
 }
 
 type MiddlewareOption func(*Middleware)
@@ -69,6 +80,19 @@ func WithEnv(env string) MiddlewareOption {
 	}
 }
 
+// Shutdown 关闭中间件创建的资源, 例如把未上报的span刷新到exporter
+//
+// 可以通过WithShutdownHooks(mid.Shutdown)在GinEngine.Stop时调用, 使用WithTracing时无需手动调用
+func (l *Middleware) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, shutdown := range l.shutdowns {
+		if err := shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // Cors 直接放行所有跨域请求并放行所有 OPTIONS 方法
 func (l *Middleware) Cors(headers ...map[string]string) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -210,8 +234,19 @@ func (l *Middleware) IpLimit(capacity int64, rate float64) gin.HandlerFunc {
 	}
 }
 
-// Tracing 链路追踪
+// Tracing 链路追踪, 默认把TracerProvider和传播方式注册为otel全局配置, 可以通过WithTracingDisableGlobal关闭
+//
+// 创建的TracerProvider需要通过Middleware.Shutdown关闭, 使用WithTracing时在GinEngine.Stop时自动关闭
 func (l *Middleware) Tracing(url string, opts ...TracingOption) gin.HandlerFunc {
+	handler, shutdown := l.newTracing(url, opts...)
+	if shutdown != nil {
+		l.shutdowns = append(l.shutdowns, shutdown)
+	}
+	return handler
+}
+
+// newTracing 创建链路追踪中间件, 返回中间件创建的TracerProvider的关闭函数, 使用自定义TracerProvider时为nil
+func (l *Middleware) newTracing(url string, opts ...TracingOption) (gin.HandlerFunc, LifecycleHook) {
 	cfg := &tracingConfig{
 		KeyValue: defaultKeyValueFunc,
 		URL:      url,
@@ -220,11 +255,36 @@ func (l *Middleware) Tracing(url string, opts ...TracingOption) gin.HandlerFunc
 		opt(cfg)
 	}
 
-	l.tracing = tracerProvider(cfg.URL, l.serverName, l.env, l.id)
+	var shutdown LifecycleHook
+	global := !cfg.DisableGlobal
+	tp := cfg.Provider
+	if tp == nil {
+		sdkTp, err := tracerProvider(cfg, l.serverName, l.env, l.id)
+		if err != nil {
+			Logger().Error("[GIN-PLUS] [ERROR] create tracer provider failed, tracing disabled", zap.Error(err))
+			tp = oteltrace.NewNoopTracerProvider()
+			// 不使用noop provider覆盖全局TracerProvider
+			global = false
+		} else {
+			tp = sdkTp
+			shutdown = sdkTp.Shutdown
+		}
+	}
+	propagator := cfg.Propagator
+	if propagator == nil {
+		propagator = NewPropagator()
+	}
+	if global {
+		otel.SetTracerProvider(tp)
+		otel.SetTextMapPropagator(propagator)
+	}
+	l.tracing = tp
+	l.propagator = propagator
+	tracer := tp.Tracer("IMiddleware.Tracing")
 
 	return func(c *gin.Context) {
 		spanOpts := []oteltrace.SpanStartOption{
-			oteltrace.WithAttributes(semconv.HTTPServerAttributesFromHTTPRequest(l.serverName, c.FullPath(), c.Request)...),
+			oteltrace.WithAttributes(httpServerAttributes(l.serverName, c.FullPath(), c.Request)...),
 			oteltrace.WithSpanKind(oteltrace.SpanKindServer),
 		}
 		spanName := c.FullPath()
@@ -232,11 +292,16 @@ func (l *Middleware) Tracing(url string, opts ...TracingOption) gin.HandlerFunc
 			spanName = fmt.Sprintf("HTTP %s route not found", c.Request.Method)
 		}
 
-		ctx, span := otel.Tracer("IMiddleware.Tracing").Start(c, spanName, spanOpts...)
+		// 从请求头中提取上游的链路上下文
+		ctx := propagator.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		ctx, span := tracer.Start(ctx, spanName, spanOpts...)
 		defer span.End()
 		c.Set("span", span)
 
 		c.Request = c.Request.WithContext(ctx)
+		if cfg.InjectResponse {
+			propagator.Inject(ctx, propagation.HeaderCarrier(c.Writer.Header()))
+		}
 		sc := span.SpanContext()
 		if sc.HasTraceID() {
 			c.Header("trace_id", sc.TraceID().String())
@@ -263,38 +328,71 @@ func (l *Middleware) Tracing(url string, opts ...TracingOption) gin.HandlerFunc
 
 		// 上报状态码
 		status := c.Writer.Status()
-		attrs := semconv.HTTPAttributesFromHTTPStatusCode(status)
-		spanStatus, spanMessage := semconv.SpanStatusFromHTTPStatusCode(status)
-		span.SetAttributes(attrs...)
-		span.SetStatus(spanStatus, spanMessage)
-	}
+		span.SetAttributes(semconv.HTTPStatusCode(status))
+		// 服务端span只把5xx视为错误
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}, shutdown
 }
 
-// Logger 日志
+// Logger 日志, 保留原有的调用方式, 记录所有请求; 需要更多配置时使用AccessLogger
 func (l *Middleware) Logger(timeLayout ...string) gin.HandlerFunc {
-	layout := time.RFC3339
+	opts := []LoggerOption{WithLoggerSkipPaths()}
 	if len(timeLayout) > 0 {
-		layout = timeLayout[0]
+		opts = append(opts, WithLoggerTimeLayout(timeLayout[0]))
 	}
+	return l.AccessLogger(opts...)
+}
+
+// AccessLogger 访问日志, 支持自定义字段、请求/响应体记录、脱敏、按状态码采样和跳过指定路径
+func (l *Middleware) AccessLogger(opts ...LoggerOption) gin.HandlerFunc {
+	cfg := newLoggerConfig(opts...)
 	return func(c *gin.Context) {
+		if cfg.skip(c) {
+			c.Next()
+			return
+		}
+
 		startTime := time.Now()
+		reqBody, reqTruncated := readRequestBody(c, cfg.reqBodyLimit)
+		var respWriter *bodyLogWriter
+		if cfg.respBodyLimit > 0 {
+			respWriter = &bodyLogWriter{ResponseWriter: c.Writer, body: &bytes.Buffer{}, limit: cfg.respBodyLimit}
+			c.Writer = respWriter
+		}
+
 		c.Next()
 		endTime := time.Now()
 		latencyTime := endTime.Sub(startTime)
 
-		reqMethod := c.Request.Method
-		reqUri := c.Request.RequestURI
 		statusCode := c.Writer.Status()
-		clientIP := c.ClientIP()
+		if !cfg.sampled(statusCode) {
+			return
+		}
+
 		kv := []zap.Field{
-			zap.String("timestamp", time.Now().Format(layout)),
-			zap.String("start_time", startTime.Format(layout)),
-			zap.String("end_time", endTime.Format(layout)),
-			zap.String("client_ip", clientIP),
+			zap.String("timestamp", endTime.Format(cfg.timeLayout)),
+			zap.String("start_time", startTime.Format(cfg.timeLayout)),
+			zap.String("end_time", endTime.Format(cfg.timeLayout)),
+			zap.String("client_ip", c.ClientIP()),
 			zap.Int("status_code", statusCode),
-			zap.String("req_method", reqMethod),
-			zap.String("req_uri", reqUri),
-			zap.String("latency_time", latencyTime.String()),
+			zap.String("req_method", c.Request.Method),
+			zap.String("req_uri", cfg.redactURI(c.Request.RequestURI)),
+			zap.Duration("latency_time", latencyTime),
+		}
+		kv = append(kv, l.loggerFields(c, cfg)...)
+
+		for _, header := range cfg.headers {
+			if val := c.Request.Header.Get(header); val != "" {
+				kv = append(kv, zap.String("header."+strings.ToLower(header), cfg.redactHeader(header, val)))
+			}
+		}
+		if reqBody != nil {
+			kv = append(kv, zap.String("req_body", cfg.redactBody(c.ContentType(), reqBody, reqTruncated)))
+		}
+		if respWriter != nil {
+			kv = append(kv, zap.String("resp_body", cfg.redactBody(c.Writer.Header().Get("Content-Type"), respWriter.body.Bytes(), respWriter.truncated)))
 		}
 
 		ctx := c.Request.Context()
@@ -320,6 +418,36 @@ func (l *Middleware) Logger(timeLayout ...string) gin.HandlerFunc {
 			}
 		}
 
-		logger.Info(l.serverName, kv...)
+		cfg.zap().Info(l.serverName, kv...)
+	}
+}
+
+// loggerFields 生成配置的额外字段
+func (l *Middleware) loggerFields(c *gin.Context, cfg *loggerConfig) []zap.Field {
+	kv := make([]zap.Field, 0, len(cfg.fields))
+	for _, field := range cfg.fields {
+		switch field {
+		case LogFieldRoute:
+			kv = append(kv, zap.String(string(field), c.FullPath()))
+		case LogFieldRequestID:
+			id := RequestIDFromContext(c)
+			if id == "" {
+				id = c.Request.Header.Get(RequestIDHeader)
+			}
+			kv = append(kv, zap.String(string(field), id))
+		case LogFieldUserID:
+			if cfg.userIDFunc != nil {
+				kv = append(kv, zap.String(string(field), cfg.userIDFunc(c)))
+			}
+		case LogFieldBytesIn:
+			kv = append(kv, zap.Int64(string(field), c.Request.ContentLength))
+		case LogFieldBytesOut:
+			kv = append(kv, zap.Int(string(field), c.Writer.Size()))
+		case LogFieldUserAgent:
+			kv = append(kv, zap.String(string(field), c.Request.UserAgent()))
+		case LogFieldReferer:
+			kv = append(kv, zap.String(string(field), c.Request.Referer()))
+		}
 	}
+	return kv
 }
@@ -1,9 +1,252 @@
 package ginplus
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestNewMiddleware(t *testing.T) {
 	NewMiddleware(WithResponse(NewResponse()), WithServerName("gin-plus"), WithID("id"), WithEnv("default"))
 }
+
+func TestMiddleware_Logger(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	r := gin.New()
+	r.Use(NewMiddleware().AccessLogger(
+		WithLoggerZap(zap.New(core)),
+		WithLoggerFields(LogFieldRoute, LogFieldUserAgent),
+		WithLoggerHeaders("Authorization"),
+		WithLoggerRequestBody(1024),
+		WithLoggerResponseBody(1024),
+		WithLoggerRedactFields("password"),
+		WithLoggerSampleRate(4, 0),
+	))
+	r.POST("/user/:id", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"password": "resp-secret", "name": "aide"})
+	})
+	r.GET("/bad", func(c *gin.Context) {
+		c.Status(http.StatusBadRequest)
+	})
+	r.GET(defaultPingPath, func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/user/1?password=query-secret", strings.NewReader(`{"password":"req-secret","name":"aide"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set("User-Agent", "gin-plus-test")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/bad", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, defaultPingPath, nil))
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("want 1 log entry, got %d", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	for key, want := range map[string]string{
+		"route":                "/user/:id",
+		"user_agent":           "gin-plus-test",
+		"header.authorization": defaultRedactMask,
+		"req_uri":              "/user/1?password=%2A%2A%2A",
+	} {
+		if fields[key] != want {
+			t.Errorf("field %s: want %q, got %q", key, want, fields[key])
+		}
+	}
+	for _, key := range []string{"req_body", "resp_body"} {
+		body, _ := fields[key].(string)
+		if strings.Contains(body, "secret") || !strings.Contains(body, "aide") {
+			t.Errorf("field %s not redacted: %s", key, body)
+		}
+	}
+}
+
+func TestMiddleware_LoggerSample5xx(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	r := gin.New()
+	r.Use(NewMiddleware().AccessLogger(
+		WithLoggerZap(zap.New(core)),
+		WithLoggerSampleRate(2, 0),
+		WithLoggerSampleRate(5, 0),
+	))
+	r.GET("/ok", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	r.GET("/fail", func(c *gin.Context) {
+		c.Status(http.StatusInternalServerError)
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ok", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+	// 5xx不参与采样, 总是记录
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("want only the 5xx request logged, got %d entries", len(entries))
+	}
+	if status := entries[0].ContextMap()["status_code"]; status != int64(http.StatusInternalServerError) {
+		t.Errorf("want 5xx request logged, got status %v", status)
+	}
+}
+
+// Logger保留原有的timeLayout参数
+func TestMiddleware_LoggerTimeLayout(t *testing.T) {
+	for _, handler := range []gin.HandlerFunc{NewMiddleware().Logger(), NewMiddleware().Logger(time.DateOnly)} {
+		r := gin.New()
+		r.Use(handler)
+		r.GET(defaultPingPath, func(c *gin.Context) {
+			c.String(http.StatusOK, "pong")
+		})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, defaultPingPath, nil))
+		if w.Body.String() != "pong" {
+			t.Errorf("unexpected response %q", w.Body.String())
+		}
+	}
+}
+
+func Test_loggerConfig_redactBody(t *testing.T) {
+	cfg := newLoggerConfig(WithLoggerRedactFields("password", "token"))
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		truncated   bool
+		want        string
+	}{
+		{name: "json", contentType: "application/json", body: `{"password":"s","name":"aide"}`, want: `{"name":"aide","password":"***"}`},
+		{name: "truncated after secret", contentType: "application/json", body: `{"password":"s", "name":"ai`, truncated: true, want: `{"password":"***", "name"...[truncated]`},
+		{name: "truncated in secret", contentType: "application/json", body: `{"name":"aide","password":"sec`, truncated: true, want: `{"name":"aide","password"...[truncated]`},
+		{name: "truncated in nested secret", contentType: "application/json", body: `[{"token":{"a":"sec`, truncated: true, want: `[{"token":"***"...[truncated]`},
+		{name: "nested secret", contentType: "application/json", body: `[{"token":{"a":"s"},"n":1},{"x":[1`, truncated: true, want: `[{"token":"***","n":1},{"x":[1...[truncated]`},
+		{name: "malformed", contentType: "application/json", body: `{"name":"aide"} x {"password":"s"}`, want: `{"name":"aide"}`},
+		{name: "form truncated", contentType: "application/x-www-form-urlencoded", body: "name=aide&password=sec%2", truncated: true, want: "name=aide&password=%2A%2A%2A...[truncated]"},
+		{name: "plain truncated", contentType: "text/plain", body: "hello", truncated: true, want: "hello...[truncated]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cfg.redactBody(tt.contentType, []byte(tt.body), tt.truncated); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMiddleware_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := tracesdk.NewTracerProvider(tracesdk.WithSpanProcessor(recorder))
+	r := gin.New()
+	r.Use(NewMiddleware().Tracing("", WithTracerProvider(tp), WithTracingDisableGlobal()))
+	r.GET("/user/:id", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/user/1", nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 1 || spans[0].Name() != "/user/:id" {
+		t.Fatalf("unexpected spans: %v", spans)
+	}
+}
+
+func TestMiddleware_Shutdown(t *testing.T) {
+	mid := NewMiddleware()
+	mid.Tracing("", WithTracingExporter(TracingExporterStdout), WithTracingSampleRatio(0.5), WithTracingDisableGlobal())
+	if len(mid.shutdowns) != 1 {
+		t.Fatalf("want 1 shutdown hook, got %d", len(mid.shutdowns))
+	}
+	if err := mid.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMiddleware_TracingGlobal(t *testing.T) {
+	globalTp, globalPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	defer func() {
+		otel.SetTracerProvider(globalTp)
+		otel.SetTextMapPropagator(globalPropagator)
+	}()
+
+	tp := tracesdk.NewTracerProvider()
+	NewMiddleware().Tracing("", WithTracerProvider(tp), WithTracingDisableGlobal())
+	if otel.GetTracerProvider() == tp {
+		t.Fatal("provider registered globally with WithTracingDisableGlobal")
+	}
+	// 默认注册为全局TracerProvider
+	NewMiddleware().Tracing("", WithTracerProvider(tp), WithTracingPropagators(TracingPropagatorB3))
+	if otel.GetTracerProvider() != tp {
+		t.Fatal("provider not registered globally")
+	}
+	if fields := strings.Join(otel.GetTextMapPropagator().Fields(), ","); !strings.Contains(fields, "b3") {
+		t.Errorf("propagator not registered globally: %v", fields)
+	}
+}
+
+func TestWithTracing(t *testing.T) {
+	engine := New(gin.New(), WithTracing(NewMiddleware(), "", WithTracingExporter(TracingExporterStdout), WithTracingDisableGlobal()))
+	if len(engine.stopHooks) != 1 {
+		t.Fatalf("want provider shutdown registered as stop hook, got %d hooks", len(engine.stopHooks))
+	}
+	engine.runStopHooks(context.Background())
+
+	// 使用自定义TracerProvider时由调用方负责关闭
+	engine = New(gin.New(), WithTracing(NewMiddleware(), "", WithTracerProvider(tracesdk.NewTracerProvider()), WithTracingDisableGlobal()))
+	if len(engine.stopHooks) != 0 {
+		t.Errorf("custom provider must not be shut down by the engine, got %d hooks", len(engine.stopHooks))
+	}
+}
+
+func TestMiddleware_TracingPropagation(t *testing.T) {
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	var upstreamTraceparent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamTraceparent = r.Header.Get("traceparent")
+	}))
+	defer upstream.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	mid := NewMiddleware()
+	r := gin.New()
+	r.Use(mid.Tracing("", WithTracerProvider(tracesdk.NewTracerProvider(tracesdk.WithSpanProcessor(recorder))), WithTracingInjectResponse(), WithTracingDisableGlobal()))
+	client := &http.Client{Transport: mid.TracingTransport(nil)}
+	r.GET("/", func(c *gin.Context) {
+		req, _ := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, upstream.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		_ = resp.Body.Close()
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("want 2 spans, got %d", len(spans))
+	}
+	for _, span := range spans {
+		if span.SpanContext().TraceID().String() != traceID {
+			t.Errorf("span %s not in inbound trace", span.Name())
+		}
+	}
+	if !strings.Contains(upstreamTraceparent, traceID) {
+		t.Errorf("traceparent not injected into outbound request: %q", upstreamTraceparent)
+	}
+	if !strings.Contains(w.Header().Get("traceparent"), traceID) {
+		t.Errorf("traceparent not injected into response: %q", w.Header().Get("traceparent"))
+	}
+}
@@ -2,22 +2,34 @@ package ginplus
 
 import (
 	"context"
+	"crypto/tls"
 	"embed"
 	"errors"
+	"fmt"
 	"io/fs"
+	"net"
 	"net/http"
-	"os"
 	"path"
 	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/aide-cloud/gin-plus/swagger"
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go/trace/tracer"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-var _ Server = (*GinEngine)(nil)
+var (
+	_ Server  = (*GinEngine)(nil)
+	_ Readier = (*GinEngine)(nil)
+)
+
+// ErrEngineStopped GinEngine已经Stop, 不能再次Start
+var ErrEngineStopped = errors.New("engine already stopped")
 
 type HandlerFunc func(controller any, t reflect.Method, req reflect.Type) gin.HandlerFunc
 
@@ -41,6 +53,8 @@ type (
 		defaultResponse IResponse
 		// 默认Bind函数
 		defaultBind func(c *gin.Context, params any) error
+		// 注册回调方法的路由后调用, 用于根据同一组controller生成graphql字段
+		onCallback func(controller any, method reflect.Method, req, resp reflect.Type, httpMethod, fullPath string)
 
 		// 文档配置
 		apiConfig          ApiConfig
@@ -49,6 +63,10 @@ type (
 
 		// 生成API路由开关, 默认为true
 		genApiEnable bool
+		// swagger ui配置
+		swaggerUI *SwaggerUI
+		// 文档页面配置
+		docs *Docs
 
 		// 内置启动地址
 		// 默认为: :8080
@@ -60,13 +78,72 @@ type (
 
 		// prom metrics
 		metrics *Metrics
+		// metrics独立端口的HTTP服务器
+		metricsServer *http.Server
 		// ping
 		ping *Ping
-	}
+		// 健康检查
+		health *Health
+		// 服务是否已启动
+		started atomic.Bool
+		// 开始接收请求时关闭
+		ready     chan struct{}
+		readyOnce sync.Once
+		// 服务是否正在停止, 此时就绪探针返回503
+		draining atomic.Bool
+		// 服务是否已停止, 停止后不能再次启动
+		stopped atomic.Bool
+
+		// 服务启动前执行的钩子
+		startHooks []LifecycleHook
+		// 服务停止时执行的钩子, 例如刷新链路追踪数据
+		stopHooks []LifecycleHook
+		// 关闭HTTP服务器和执行停止钩子的超时时间, 默认为5s
+		shutdownTimeout time.Duration
+		// 关闭HTTP服务器前的等待时间, 用于负载均衡摘除实例
+		preStopDelay time.Duration
+
+		// TLS配置
+		tlsConfig *TLSConfig
+		// 是否启用明文HTTP/2
+		h2c bool
+		// 保证多次Start时只包装一次h2c handler
+		h2cOnce sync.Once
+
+		// 主服务使用的已创建listener
+		listeners []net.Listener
+		// 主服务监听的unix domain socket
+		unixSockets []unixSocket
+		// 是否使用systemd socket activation传入的listener
+		systemdListeners bool
+		// 管理端口
+		admin *adminServer
+		// 零停机重启
+		upgrader *Upgrader
+		// 运行时诊断
+		debug *Debug
+		// 注册时的错误, 启动时返回
+		registerErrs []error
+	}
+
+	// LifecycleHook 生命周期钩子
+	LifecycleHook func(ctx context.Context) error
 
 	// Metrics prometheus metrics配置
 	Metrics struct {
 		Path string
+		// Registerer 指标注册器, 默认为prometheus.DefaultRegisterer
+		Registerer prometheus.Registerer
+		// Gatherer 指标采集器, 为空时Registerer为*prometheus.Registry则使用它, 否则为prometheus.DefaultGatherer
+		Gatherer prometheus.Gatherer
+		// Accounts basic auth账号, 为空时不校验
+		Accounts gin.Accounts
+		// AllowIPs 允许访问的IP或CIDR, 为空时不限制
+		AllowIPs []string
+		// Addr 独立的监听地址, 例如管理端口:9090, 为空时挂载在主路由上
+		Addr string
+		// OpenMetrics 是否支持OpenMetrics格式
+		OpenMetrics bool
 	}
 
 	// Ping ping配置
@@ -130,12 +207,48 @@ type (
 		Enable bool
 		// HandlePath graphql请求路径
 		HandlePath string
-		// SchemaPath graphql schema文件路径
+		// ViewPath 调试页面(内置的GraphQL Explorer, 并非GraphiQL)路径
 		ViewPath string
 		// Root graphql 服务根节点
 		Root any
 		// Content graphql schema文件内容
 		Content embed.FS
+		// Schema graphql schema定义, 优先于SchemaFS和Content
+		Schema string
+		// SchemaFS 包含.graphql文件的文件系统, 例如os.DirFS, 优先于Content
+		SchemaFS fs.FS
+		// SchemaOptions Schema、SchemaFS和Content都为空时根据Root的方法生成schema(code-first), 见GenerateSchema
+		SchemaOptions []SchemaOption
+		// Controllers 根据WithControllers注册的controller生成schema, Root为GenerateGraphqlResolver生成的根节点;
+		// 回调方法中GET路由为Query字段, 其余为Mutation字段, 请求和响应结构体按json、form和uri标签生成参数和类型,
+		// 字段与REST接口执行相同的中间件、Bind和defaultHandler
+		Controllers bool
+		// PersistedQueryStore 持久化查询(APQ)存储, 为空时使用LRU内存存储
+		PersistedQueryStore PersistedQueryStore
+		// MaxBatchSize 批量查询的最大数量, 默认为10
+		MaxBatchSize int
+		// InitFunc 订阅websocket连接的初始化钩子, 用于认证
+		InitFunc GraphqlInitFunc
+		// KeepAlive 订阅的心跳间隔, 默认为15s
+		KeepAlive time.Duration
+		// MaxSubscriptions 单个websocket连接的最大并发订阅数, 默认为100
+		MaxSubscriptions int
+		// CheckOrigin websocket跨域校验, 默认只允许同源
+		CheckOrigin func(r *http.Request) bool
+		// MaxDepth 查询的最大深度, 0表示不限制
+		MaxDepth int
+		// MaxComplexity 查询的最大复杂度, 0表示不限制, 字段复杂度通过@cost指令设置, 见CostDirective
+		MaxComplexity int
+		// MaxParallelism 单个查询并发执行的resolver数量, 默认为10
+		MaxParallelism int
+		// Timeout 单个查询的超时时间, 不作用于订阅
+		Timeout time.Duration
+		// DisableIntrospection 禁用内省查询, 建议在生产环境中开启
+		DisableIntrospection bool
+		// Tracer graphql tracer, 为空时使用NewGraphqlTracer(), 可以使用noop.Tracer关闭
+		Tracer tracer.Tracer
+		// DisablePlayground 不注册调试页面(GraphQL Explorer), 建议在生产环境中开启
+		DisablePlayground bool
 	}
 )
 
@@ -154,6 +267,8 @@ const (
 	defaultVersion     = "v0.5.0"
 	defaultMetricsPath = "/metrics"
 	defaultPingPath    = "/ping"
+
+	defaultShutdownTimeout = 5 * time.Second
 )
 
 var (
@@ -195,8 +310,10 @@ func New(r *gin.Engine, opts ...OptionFun) *GinEngine {
 		ping: &Ping{HandlerFunc: func(ctx *gin.Context) {
 			ctx.Status(http.StatusOK)
 		}},
-		metrics: &Metrics{Path: defaultMetricsPath},
-		addr:    ":8080",
+		metrics:         &Metrics{Path: defaultMetricsPath},
+		addr:            ":8080",
+		shutdownTimeout: defaultShutdownTimeout,
+		ready:           make(chan struct{}),
 	}
 	for _, opt := range opts {
 		opt(instance)
@@ -206,6 +323,9 @@ func New(r *gin.Engine, opts ...OptionFun) *GinEngine {
 		instance.defaultHandler = instance.newDefaultHandler
 	}
 
+	if instance.tlsConfig != nil {
+		instance.Use(clientIdentity())
+	}
 	instance.Use(instance.middlewares...)
 
 	for _, c := range instance.controllers {
@@ -216,7 +336,39 @@ func New(r *gin.Engine, opts ...OptionFun) *GinEngine {
 }
 
 // Start 启动HTTP服务器
+//
+// 依次执行OnStart注册的钩子, 监听失败(例如端口被占用)时按逆序执行OnStop注册的钩子并返回错误;
+// Stop之后再次调用返回ErrEngineStopped, 需要重新启动时创建新的GinEngine
 func (l *GinEngine) Start() error {
+	if l.stopped.Load() {
+		return ErrEngineStopped
+	}
+	if err := errors.Join(l.registerErrs...); err != nil {
+		return err
+	}
+	ctx := context.Background()
+	for _, hook := range l.startHooks {
+		if err := hook(ctx); err != nil {
+			return fmt.Errorf("start hook: %w", err)
+		}
+	}
+	if err := l.serveAll(); err != nil {
+		// 启动钩子已执行, 释放钩子中创建的资源
+		stopCtx, cancel := context.WithTimeout(context.Background(), l.shutdownTimeout)
+		defer cancel()
+		l.runStopHooks(stopCtx)
+		return err
+	}
+
+	l.started.Store(true)
+	l.readyOnce.Do(func() { close(l.ready) })
+	return nil
+}
+
+// serveAll 监听所有地址并在后台启动HTTP服务器
+//
+// 只有监听失败时才会再次调用, 此时HTTP服务器还没有Serve过, 可以复用; Stop之后Start直接返回ErrEngineStopped
+func (l *GinEngine) serveAll() error {
 	if l.server == nil {
 		//创建HTTP服务器
 		server := &http.Server{
@@ -226,35 +378,122 @@ func (l *GinEngine) Start() error {
 		l.server = server
 	}
 
-	//启动HTTP服务器
-	go func() {
-		if err := l.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			Logger().Sugar().Infof("[GIN-PLUS] [INFO] Server listen: %s\n", err)
+	var tlsConfig *tls.Config
+	if l.tlsConfig != nil {
+		var err error
+		if tlsConfig, err = l.tlsConfig.build(); err != nil {
+			return err
 		}
-	}()
+		l.server.TLSConfig = tlsConfig
+	}
+	if l.h2c {
+		l.h2cOnce.Do(func() {
+			l.server.Handler = h2cHandler(l.server.Handler)
+		})
+	}
 
-	Logger().Sugar().Infof("[GIN-PLUS] [INFO] Server is running at %s", l.addr)
+	// 同步监听, 保证监听错误能返回给调用方
+	servers, err := l.listen()
+	if err != nil {
+		return err
+	}
 
+	//启动HTTP服务器
+	// 主服务总是第一个
+	servers[0].tlsConfig = tlsConfig
+	for _, s := range servers {
+		for _, ln := range s.listeners {
+			if s.tlsConfig != nil {
+				ln = tls.NewListener(ln, s.tlsConfig)
+			}
+			go serve(s.server, ln, s.name)
+			Logger().Sugar().Infof("[GIN-PLUS] [INFO] %s is running at %s://%s", s.name, ln.Addr().Network(), ln.Addr())
+		}
+	}
 	return nil
 }
 
+// Ready 实现Readier接口, HTTP服务器开始接收请求时关闭
+func (l *GinEngine) Ready() <-chan struct{} {
+	return l.ready
+}
+
+// serve 在已监听的listener上启动HTTP服务器
+func serve(server *http.Server, ln net.Listener, name string) {
+	err := server.Serve(ln)
+	if errors.Is(err, http.ErrServerClosed) {
+		Logger().Sugar().Infof("[GIN-PLUS] [INFO] %s at %s://%s closed", name, ln.Addr().Network(), ln.Addr())
+		return
+	}
+	Logger().Sugar().Errorf("[GIN-PLUS] [ERROR] %s serve: %s", name, err)
+}
+
 // Stop 停止HTTP服务器
+//
+// 先把就绪探针置为不可用, 等待preStopDelay让负载均衡摘除实例, 再在shutdownTimeout内关闭连接,
+// 最后按注册的逆序执行OnStop注册的钩子; 重复调用时直接返回
 func (l *GinEngine) Stop() {
+	if !l.stopped.CompareAndSwap(false, true) {
+		return
+	}
+	// 就绪探针立即返回503, 让负载均衡摘除该实例
+	l.draining.Store(true)
+	if l.preStopDelay > 0 {
+		Logger().Sugar().Infof("[GIN-PLUS] [INFO] Waiting %s before shutdown", l.preStopDelay)
+		time.Sleep(l.preStopDelay)
+	}
+
 	//创建超时上下文，Shutdown可以让未处理的连接在这个时间内关闭
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), l.shutdownTimeout)
 	defer cancel()
 
 	//停止HTTP服务器
-	if err := l.server.Shutdown(ctx); err != nil {
-		Logger().Sugar().Errorf("[GIN-PLUS] [INFO] Server Shutdown: %v", err)
+	if l.server != nil {
+		if err := l.server.Shutdown(ctx); err != nil {
+			Logger().Sugar().Errorf("[GIN-PLUS] [INFO] Server Shutdown: %v", err)
+		}
+	}
+	if l.admin != nil {
+		if err := l.admin.server.Shutdown(ctx); err != nil {
+			Logger().Sugar().Errorf("[GIN-PLUS] [INFO] Admin server Shutdown: %v", err)
+		}
+	}
+	if l.metricsServer != nil {
+		if err := l.metricsServer.Shutdown(ctx); err != nil {
+			Logger().Sugar().Errorf("[GIN-PLUS] [INFO] Metrics server Shutdown: %v", err)
+		}
 	}
 
+	l.runStopHooks(ctx)
+	l.started.Store(false)
+
 	Logger().Sugar().Infof("[GIN-PLUS] [INFO] Server stopped")
 }
 
+// runStopHooks 按注册的逆序执行停止钩子
+func (l *GinEngine) runStopHooks(ctx context.Context) {
+	for i := len(l.stopHooks) - 1; i >= 0; i-- {
+		if err := l.stopHooks[i](ctx); err != nil {
+			Logger().Sugar().Errorf("[GIN-PLUS] [ERROR] Stop hook: %v", err)
+		}
+	}
+}
+
+// OnStart 注册启动钩子, 在监听端口之前按注册顺序执行, 任一钩子失败时Start返回错误
+func (l *GinEngine) OnStart(hooks ...LifecycleHook) *GinEngine {
+	l.startHooks = append(l.startHooks, hooks...)
+	return l
+}
+
+// OnStop 注册停止钩子, 在HTTP服务器关闭后或启动钩子执行后监听失败时按注册的逆序执行
+func (l *GinEngine) OnStop(hooks ...LifecycleHook) *GinEngine {
+	l.stopHooks = append(l.stopHooks, hooks...)
+	return l
+}
+
 func registerPing(instance *GinEngine, ping *Ping) {
 	if ping != nil {
-		instance.GET(defaultPingPath, ping.HandlerFunc)
+		instance.Admin().GET(defaultPingPath, ping.HandlerFunc)
 		return
 	}
 }
@@ -266,26 +505,68 @@ func registerMetrics(instance *GinEngine, metrics *Metrics) {
 	if metrics.Path == "" {
 		metrics.Path = defaultMetricsPath
 	}
-	instance.GET(metrics.Path, gin.WrapH(promhttp.Handler()))
-}
 
-func registerSwaggerUI(instance *GinEngine, enable bool) {
-	if !enable {
+	registerer, gatherer := metrics.registry()
+	if err := registerDefaultCollectors(instance, registerer, registerer != prometheus.DefaultRegisterer); err != nil {
+		Logger().Sugar().Errorf("[GIN-PLUS] [ERROR] register collectors: %v", err)
+	}
+
+	handlers := make([]gin.HandlerFunc, 0, 3)
+	if len(metrics.AllowIPs) > 0 {
+		handlers = append(handlers, ipAllowlist(metrics.AllowIPs))
+	}
+	if len(metrics.Accounts) > 0 {
+		handlers = append(handlers, gin.BasicAuth(metrics.Accounts))
+	}
+	handlers = append(handlers, gin.WrapH(promhttp.InstrumentMetricHandler(registerer, promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{
+		EnableOpenMetrics: metrics.OpenMetrics,
+	}))))
+
+	if metrics.Addr == "" {
+		instance.Admin().GET(metrics.Path, handlers...)
 		return
 	}
-	instance.genOpenApiYaml()
-	fp, _ := fs.Sub(swagger.Dist, "dist")
-	instance.StaticFS("/swagger-ui", http.FS(fp))
-	instance.GET("/openapi/doc/swagger", func(ctx *gin.Context) {
-		file, _ := os.ReadFile(instance.defaultOpenApiYaml)
-		ctx.Writer.Header().Set("Content-Type", "text/yaml; charset=utf-8")
-		_, _ = ctx.Writer.Write(file)
-	})
+
+	// 独立端口, 不经过主路由的中间件
+	r := gin.New()
+	r.GET(metrics.Path, handlers...)
+	instance.metricsServer = &http.Server{
+		Addr:    metrics.Addr,
+		Handler: r,
+	}
 }
 
-func registerGraphql(instance *GinEngine, config GraphqlConfig) {
-	if !config.Enable || config.Root == nil {
-		return
+// sdl 依次使用Schema、SchemaFS和Content, 都为空时根据Root生成
+func (c GraphqlConfig) sdl() (string, error) {
+	if c.Schema != "" {
+		return c.Schema, nil
+	}
+	content := fs.FS(c.Content)
+	if c.SchemaFS != nil {
+		content = c.SchemaFS
+	}
+	s, err := String(content)
+	if err != nil || strings.TrimSpace(s) != "" {
+		return s, err
+	}
+	return GenerateSchema(c.Root, c.SchemaOptions...)
+}
+
+// graphqlHandler 根据Root或controller创建graphql处理函数
+func (l *GinEngine) graphqlHandler(config GraphqlConfig, opts ...GraphqlHandlerOption) (*GraphqlHandler, error) {
+	if config.Controllers {
+		return l.newControllerHandler(config.Root, opts...)
+	}
+	sdl, err := config.sdl()
+	if err != nil {
+		return nil, fmt.Errorf("graphql schema: %w", err)
+	}
+	return NewHandler(config.Root, sdl, opts...)
+}
+
+func registerGraphql(instance *GinEngine, config GraphqlConfig) error {
+	if !config.Enable || (config.Root == nil && !config.Controllers) {
+		return nil
 	}
 	if config.HandlePath == "" {
 		config.HandlePath = DefaultHandlePath
@@ -293,8 +574,72 @@ func registerGraphql(instance *GinEngine, config GraphqlConfig) {
 	if config.ViewPath == "" {
 		config.ViewPath = DefaultViewPath
 	}
-	instance.POST(config.HandlePath, gin.WrapH(Handler(config.Root, config.Content)))
-	instance.GET(config.ViewPath, gin.WrapF(View(config.HandlePath)))
+	opts := make([]GraphqlHandlerOption, 0, 12)
+	if config.PersistedQueryStore != nil {
+		opts = append(opts, WithGraphqlPersistedQueryStore(config.PersistedQueryStore))
+	}
+	if config.MaxBatchSize > 0 {
+		opts = append(opts, WithGraphqlMaxBatchSize(config.MaxBatchSize))
+	}
+	if config.InitFunc != nil {
+		opts = append(opts, WithGraphqlInitFunc(config.InitFunc))
+	}
+	if config.KeepAlive > 0 {
+		opts = append(opts, WithGraphqlKeepAlive(config.KeepAlive))
+	}
+	if config.MaxSubscriptions > 0 {
+		opts = append(opts, WithGraphqlMaxSubscriptions(config.MaxSubscriptions))
+	}
+	if config.CheckOrigin != nil {
+		opts = append(opts, WithGraphqlCheckOrigin(config.CheckOrigin))
+	}
+	if config.MaxDepth > 0 {
+		opts = append(opts, WithGraphqlMaxDepth(config.MaxDepth))
+	}
+	if config.MaxComplexity > 0 {
+		opts = append(opts, WithGraphqlMaxComplexity(config.MaxComplexity))
+	}
+	if config.MaxParallelism > 0 {
+		opts = append(opts, WithGraphqlMaxParallelism(config.MaxParallelism))
+	}
+	if config.Timeout > 0 {
+		opts = append(opts, WithGraphqlTimeout(config.Timeout))
+	}
+	if config.DisableIntrospection {
+		opts = append(opts, WithGraphqlDisableIntrospection())
+	}
+	if config.Tracer != nil {
+		opts = append(opts, WithGraphqlTracer(config.Tracer))
+	}
+	h, err := instance.graphqlHandler(config, opts...)
+	if err != nil {
+		return err
+	}
+	handler := gin.WrapH(h)
+	instance.POST(config.HandlePath, handler)
+	// 禁用内省时也不暴露schema定义
+	if !config.DisableIntrospection {
+		instance.GET(path.Join(config.HandlePath, "schema"), gin.WrapF(h.ServeSDL))
+	}
+	if config.DisablePlayground {
+		instance.GET(config.HandlePath, handler)
+		return nil
+	}
+	view := gin.WrapF(View(config.HandlePath))
+	if config.ViewPath != config.HandlePath {
+		instance.GET(config.HandlePath, handler)
+		instance.GET(config.ViewPath, view)
+		return nil
+	}
+	// 页面和接口路径相同时, 带有查询参数的GET请求和订阅请求执行查询, 否则返回页面
+	instance.GET(config.HandlePath, func(c *gin.Context) {
+		if c.Query("query") != "" || c.Query("extensions") != "" || websocket.IsWebSocketUpgrade(c.Request) {
+			handler(c)
+			return
+		}
+		view(c)
+	})
+	return nil
 }
 
 func (l *GinEngine) RegisterPing(ping ...*Ping) *GinEngine {
@@ -314,18 +659,15 @@ func (l *GinEngine) RegisterMetrics(metrics ...*Metrics) *GinEngine {
 	return l
 }
 
-// RegisterSwaggerUI 注册swagger ui
-func (l *GinEngine) RegisterSwaggerUI() *GinEngine {
-	registerSwaggerUI(l, l.genApiEnable)
-	return l
-}
-
-// RegisterGraphql 注册graphql
+// RegisterGraphql 注册graphql, schema错误时记录错误并由Start返回
 func (l *GinEngine) RegisterGraphql(config ...*GraphqlConfig) *GinEngine {
 	if len(config) > 0 {
 		l.graphqlConfig = *config[0]
 	}
-	registerGraphql(l, l.graphqlConfig)
+	if err := registerGraphql(l, l.graphqlConfig); err != nil {
+		Logger().Sugar().Errorf("[GIN-PLUS] [ERROR] Register graphql: %v", err)
+		l.registerErrs = append(l.registerErrs, err)
+	}
 	return l
 }
 
@@ -412,7 +754,7 @@ func WithGenApiEnable(enable bool) OptionFun {
 	}
 }
 
-// WithAddr 设置启动地址
+// WithAddr 设置启动地址, 为空时不监听TCP端口, 例如只监听unix domain socket
 func WithAddr(addr string) OptionFun {
 	return func(g *GinEngine) {
 		g.addr = addr
@@ -471,3 +813,35 @@ func WithBind(bind func(c *gin.Context, params any) error) OptionFun {
 		g.defaultBind = bind
 	}
 }
+
+// WithShutdownHooks 设置服务停止时执行的钩子, 例如Middleware.Shutdown, 等同于OnStop
+func WithShutdownHooks(hooks ...LifecycleHook) OptionFun {
+	return func(g *GinEngine) {
+		g.stopHooks = append(g.stopHooks, hooks...)
+	}
+}
+
+// WithTracing 使用mid.Tracing作为最外层中间件, 并在GinEngine.Stop时关闭中间件创建的TracerProvider
+func WithTracing(mid *Middleware, url string, opts ...TracingOption) OptionFun {
+	return func(g *GinEngine) {
+		handler, shutdown := mid.newTracing(url, opts...)
+		g.Use(handler)
+		if shutdown != nil {
+			g.stopHooks = append(g.stopHooks, shutdown)
+		}
+	}
+}
+
+// WithShutdownTimeout 设置关闭HTTP服务器和执行停止钩子的超时时间
+func WithShutdownTimeout(timeout time.Duration) OptionFun {
+	return func(g *GinEngine) {
+		g.shutdownTimeout = timeout
+	}
+}
+
+// WithPreStopDelay 设置关闭HTTP服务器前的等待时间, 期间就绪探针返回503, 用于负载均衡摘除实例
+func WithPreStopDelay(delay time.Duration) OptionFun {
+	return func(g *GinEngine) {
+		g.preStopDelay = delay
+	}
+}
@@ -4,9 +4,15 @@ import (
 	"context"
 	"errors"
 	"log"
+	"net"
+	"net/http"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 type People struct {
@@ -325,3 +331,87 @@ func (p *Path2) GetInfoByID(_ context.Context, req *struct {
 func TestRouteGroup(t *testing.T) {
 	New(gin.Default(), WithControllers(&Path1{Path2: &Path2{}}))
 }
+
+func TestGinEngine_StartStop(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	if err := New(gin.New(), WithAddr(ln.Addr().String())).Start(); err == nil {
+		t.Fatal("want listen error when the address is in use")
+	}
+
+	var order []string
+	hook := func(name string) LifecycleHook {
+		return func(ctx context.Context) error {
+			order = append(order, name)
+			return nil
+		}
+	}
+	instance := New(gin.New(), WithAddr("127.0.0.1:0"), WithPreStopDelay(10*time.Millisecond), WithShutdownTimeout(time.Second))
+	instance.OnStart(hook("start1"), hook("start2")).OnStop(hook("stop1"), hook("stop2"))
+	if err := instance.Start(); err != nil {
+		t.Fatal(err)
+	}
+	instance.Stop()
+	if want := "start1,start2,stop2,stop1"; strings.Join(order, ",") != want {
+		t.Errorf("want hooks order %s, got %v", want, order)
+	}
+	if instance.started.Load() {
+		t.Error("engine still marked as started after Stop")
+	}
+	// 重复Stop不会再次执行停止钩子, Stop之后不能再次Start
+	instance.Stop()
+	if err := instance.Start(); !errors.Is(err, ErrEngineStopped) {
+		t.Errorf("want ErrEngineStopped on restart, got %v", err)
+	}
+	if want := "start1,start2,stop2,stop1"; strings.Join(order, ",") != want {
+		t.Errorf("want hooks order %s after restart attempt, got %v", want, order)
+	}
+
+	failed := New(gin.New(), WithAddr("127.0.0.1:0")).OnStart(func(ctx context.Context) error {
+		return errors.New("init failed")
+	})
+	if err := failed.Start(); err == nil {
+		t.Error("want start hook error")
+	}
+
+	// 启动钩子执行后监听失败, 按逆序执行停止钩子
+	order = nil
+	inUse := New(gin.New(), WithAddr(ln.Addr().String()))
+	inUse.OnStart(hook("start1")).OnStop(hook("stop1"), hook("stop2"))
+	if err := inUse.Start(); err == nil {
+		t.Fatal("want listen error when the address is in use")
+	}
+	if want := "start1,stop2,stop1"; strings.Join(order, ",") != want {
+		t.Errorf("want hooks order %s after listen error, got %v", want, order)
+	}
+}
+
+func Test_serveClosed(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	global := logger
+	logger = zap.New(core)
+	defer func() { logger = global }()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: http.NotFoundHandler()}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		serve(server, ln, "Server")
+	}()
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	// 正常关闭记录为info日志
+	entries := logs.FilterMessageSnippet("closed").All()
+	if len(entries) != 1 || entries[0].Level != zap.InfoLevel {
+		t.Errorf("want one info log for the closed server, got %v", logs.All())
+	}
+}
@@ -67,11 +67,16 @@ type (
 	}
 )
 
-func (l *GinEngine) genOpenApiYaml() {
-	apiYaml := defaultOpenApiYaml
+// openApiYaml 文档文件路径, 没有.yaml后缀时使用默认文件名
+func (l *GinEngine) openApiYaml() string {
 	if l.defaultOpenApiYaml != "" && strings.HasSuffix(l.defaultOpenApiYaml, ".yaml") {
-		apiYaml = l.defaultOpenApiYaml
+		return l.defaultOpenApiYaml
 	}
+	return defaultOpenApiYaml
+}
+
+func (l *GinEngine) genOpenApiYaml() {
+	apiYaml := l.openApiYaml()
 
 	// 写入之前先清空viper
 	viper.Reset()
@@ -0,0 +1,20 @@
+// Package rapidoc 内嵌RapiDoc官方发布的脚本, 通过go generate下载到dist目录
+package rapidoc
+
+import (
+	"embed"
+)
+
+//go:generate curl -fsSL -o dist/rapidoc-min.js https://cdn.jsdelivr.net/npm/rapidoc@9.3.4/dist/rapidoc-min.js
+
+const (
+	// Version 内嵌的RapiDoc版本
+	Version = "9.3.4"
+	// Bundle 官方脚本在dist目录中的文件名
+	Bundle = "rapidoc-min.js"
+)
+
+// Dist RapiDoc页面静态资源, 包含index.html模板和官方脚本Bundle
+//
+//go:embed dist/*
+var Dist embed.FS
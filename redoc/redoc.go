@@ -0,0 +1,20 @@
+// Package redoc 内嵌ReDoc官方发布的standalone脚本, 通过go generate下载到dist目录
+package redoc
+
+import (
+	"embed"
+)
+
+//go:generate curl -fsSL -o dist/redoc.standalone.js https://cdn.jsdelivr.net/npm/redoc@2.1.3/bundles/redoc.standalone.js
+
+const (
+	// Version 内嵌的ReDoc版本
+	Version = "2.1.3"
+	// Bundle 官方脚本在dist目录中的文件名
+	Bundle = "redoc.standalone.js"
+)
+
+// Dist ReDoc页面静态资源, 包含index.html模板和官方脚本Bundle
+//
+//go:embed dist/*
+var Dist embed.FS
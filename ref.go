@@ -62,33 +62,7 @@ func getTag(t reflect.Type) []FieldInfo {
 		field := tmp.Field(i)
 		fieldName := field.Name
 		fieldType := field.Type.String()
-		tagInfo := Tag{
-			Title: fieldName,
-		}
-		for _, tagKey := range tags {
-			tagVal, ok := field.Tag.Lookup(tagKey)
-			if !ok {
-				continue
-			}
-
-			switch tagKey {
-			case "form":
-				tagInfo.FormKey = tagVal
-			case "uri":
-				tagInfo.UriKey = tagVal
-			case "skip":
-				tagInfo.Skip = tagVal
-			case "title":
-				tagInfo.Title = tagVal
-			case "format":
-				tagInfo.Format = tagVal
-			case "desc":
-				tagInfo.Desc = tagVal
-			default:
-				valList := strings.Split(tagVal, ",")
-				tagInfo.JsonKey = valList[0]
-			}
-		}
+		tagInfo := fieldTag(field)
 
 		childType := field.Type
 		if childType.Kind() == reflect.Slice {
@@ -109,3 +83,35 @@ func getTag(t reflect.Type) []FieldInfo {
 
 	return fieldList
 }
+
+// fieldTag 解析结构体字段的标签, title默认为字段名
+func fieldTag(field reflect.StructField) Tag {
+	tagInfo := Tag{
+		Title: field.Name,
+	}
+	for _, tagKey := range tags {
+		tagVal, ok := field.Tag.Lookup(tagKey)
+		if !ok {
+			continue
+		}
+
+		switch tagKey {
+		case "form":
+			tagInfo.FormKey = tagVal
+		case "uri":
+			tagInfo.UriKey = tagVal
+		case "skip":
+			tagInfo.Skip = tagVal
+		case "title":
+			tagInfo.Title = tagVal
+		case "format":
+			tagInfo.Format = tagVal
+		case "desc":
+			tagInfo.Desc = tagVal
+		default:
+			valList := strings.Split(tagVal, ",")
+			tagInfo.JsonKey = valList[0]
+		}
+	}
+	return tagInfo
+}
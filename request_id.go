@@ -0,0 +1,156 @@
+package ginplus
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultRequestIDHeader = "X-Request-ID"
+	// RequestIDHeader 请求ID默认使用的请求头
+	RequestIDHeader = defaultRequestIDHeader
+	// requestIDKey gin.Context中存储请求ID的key
+	requestIDKey = "request_id"
+	// maxRequestIDLength 允许透传的请求ID最大长度
+	maxRequestIDLength = 128
+)
+
+type requestIDCtxKey struct{}
+
+type requestIDConfig struct {
+	// 请求ID所在的请求头
+	header string
+	// 生成请求ID
+	generator func() string
+	// 校验入站请求ID, 校验不通过时重新生成
+	validator func(id string) bool
+}
+
+// RequestIDOption 请求ID配置
+type RequestIDOption func(*requestIDConfig)
+
+// WithRequestIDHeader 设置请求ID所在的请求头, 默认为X-Request-ID
+func WithRequestIDHeader(header string) RequestIDOption {
+	return func(c *requestIDConfig) {
+		c.header = header
+	}
+}
+
+// WithRequestIDGenerator 设置请求ID生成函数
+func WithRequestIDGenerator(generator func() string) RequestIDOption {
+	return func(c *requestIDConfig) {
+		c.generator = generator
+	}
+}
+
+// WithRequestIDValidator 设置入站请求ID的校验函数
+func WithRequestIDValidator(validator func(id string) bool) RequestIDOption {
+	return func(c *requestIDConfig) {
+		c.validator = validator
+	}
+}
+
+// RequestID 请求ID, 透传合法的入站请求ID或生成新的请求ID, 并写入上下文和响应头
+func (l *Middleware) RequestID(opts ...RequestIDOption) gin.HandlerFunc {
+	cfg := &requestIDConfig{
+		header:    RequestIDHeader,
+		generator: newRequestID,
+		validator: validRequestID,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return func(c *gin.Context) {
+		id := c.Request.Header.Get(cfg.header)
+		if id == "" || !cfg.validator(id) {
+			id = cfg.generator()
+		}
+
+		c.Set(requestIDKey, id)
+		c.Request.Header.Set(cfg.header, id)
+		c.Request = c.Request.WithContext(ContextWithRequestID(c.Request.Context(), id))
+		c.Header(cfg.header, id)
+		c.Next()
+	}
+}
+
+// ContextWithRequestID 把请求ID写入context
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDCtxKey{}, id)
+}
+
+// RequestIDFromContext 从context中获取请求ID, 支持*gin.Context
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if c, ok := ctx.(*gin.Context); ok {
+		if id := c.GetString(requestIDKey); id != "" {
+			return id
+		}
+		if c.Request == nil {
+			return ""
+		}
+		ctx = c.Request.Context()
+	}
+	id, _ := ctx.Value(requestIDCtxKey{}).(string)
+	return id
+}
+
+// RequestIDTransport 为出站请求添加请求ID的http.RoundTripper, base为空时使用http.DefaultTransport,
+// 请求头与RequestID中间件一致, 通过WithRequestIDHeader设置, 其余配置不生效
+func RequestIDTransport(base http.RoundTripper, opts ...RequestIDOption) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	cfg := &requestIDConfig{header: RequestIDHeader}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return &requestIDTransport{base: base, header: cfg.header}
+}
+
+type requestIDTransport struct {
+	base http.RoundTripper
+	// 请求ID所在的请求头
+	header string
+}
+
+func (t *requestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	id := RequestIDFromContext(req.Context())
+	if id == "" || req.Header.Get(t.header) != "" {
+		return t.base.RoundTrip(req)
+	}
+	// RoundTripper不应修改原始请求
+	req = req.Clone(req.Context())
+	req.Header.Set(t.header, id)
+	return t.base.RoundTrip(req)
+}
+
+// newRequestID 生成16字节随机数的十六进制字符串
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validRequestID 只允许长度不超过128的字母、数字和-_.:字符
+func validRequestID(id string) bool {
+	if len(id) == 0 || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
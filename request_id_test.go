@@ -0,0 +1,87 @@
+package ginplus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMiddleware_RequestID(t *testing.T) {
+	var outbound string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		outbound = r.Header.Get(RequestIDHeader)
+	}))
+	defer upstream.Close()
+
+	client := &http.Client{Transport: RequestIDTransport(nil)}
+	r := gin.New()
+	r.Use(NewMiddleware().RequestID())
+	r.GET("/", func(c *gin.Context) {
+		req, _ := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, upstream.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		_ = resp.Body.Close()
+		NewResponse().Response(c, nil, nil)
+	})
+
+	tests := []struct {
+		name    string
+		inbound string
+		keep    bool
+	}{
+		{name: "valid", inbound: "abc-123", keep: true},
+		{name: "invalid", inbound: "bad id\n", keep: false},
+		{name: "too long", inbound: strings.Repeat("a", maxRequestIDLength+1), keep: false},
+		{name: "empty", inbound: "", keep: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(RequestIDHeader, tt.inbound)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			id := w.Header().Get(RequestIDHeader)
+			if (id == tt.inbound) != tt.keep || !validRequestID(id) {
+				t.Fatalf("unexpected request id %q for inbound %q", id, tt.inbound)
+			}
+			if outbound != id {
+				t.Errorf("outbound request id: want %q, got %q", id, outbound)
+			}
+			var body struct {
+				RequestID string `json:"request_id"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.RequestID != id {
+				t.Errorf("response request id: want %q, got %q (%v)", id, body.RequestID, err)
+			}
+		})
+	}
+}
+
+func TestRequestIDTransport_Header(t *testing.T) {
+	const header = "X-Trace-Request"
+	var outbound, fallback string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		outbound, fallback = r.Header.Get(header), r.Header.Get(RequestIDHeader)
+	}))
+	defer upstream.Close()
+
+	client := &http.Client{Transport: RequestIDTransport(nil, WithRequestIDHeader(header))}
+	req, _ := http.NewRequestWithContext(ContextWithRequestID(context.Background(), "abc-123"), http.MethodGet, upstream.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if outbound != "abc-123" || fallback != "" {
+		t.Errorf("want request id in %s only, got %q and %s=%q", header, outbound, RequestIDHeader, fallback)
+	}
+}
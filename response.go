@@ -17,8 +17,9 @@ type IValidator interface {
 }
 
 type response struct {
-	Error error `json:"error"`
-	Data  any   `json:"data"`
+	Error     error  `json:"error"`
+	Data      any    `json:"data"`
+	RequestID string `json:"request_id,omitempty"`
 }
 
 var _ IResponse = (*response)(nil)
@@ -30,8 +31,9 @@ func NewResponse() IResponse {
 func (l *response) Response(ctx *gin.Context, resp any, err error) {
 	defer ctx.Abort()
 	ctx.JSON(200, &response{
-		Error: err,
-		Data:  resp,
+		Error:     err,
+		Data:      resp,
+		RequestID: RequestIDFromContext(ctx),
 	})
 }
 
@@ -50,7 +52,7 @@ func (l *GinEngine) newDefaultHandler(controller any, t reflect.Method, req refl
 		// 绑定请求参数
 		if err := l.defaultBind(ctx, reqVal.Interface()); err != nil {
 			Logger().Info("defaultBind req err", zap.Error(err))
-			l.defaultResponse.Response(ctx, nil, err)
+			l.response(ctx, nil, err)
 			return
 		}
 
@@ -58,7 +60,7 @@ func (l *GinEngine) newDefaultHandler(controller any, t reflect.Method, req refl
 		if validate, ok := reqVal.Interface().(IValidator); ok {
 			if err := validate.Validate(); err != nil {
 				Logger().Info("Validate req err", zap.Error(err))
-				l.defaultResponse.Response(ctx, nil, err)
+				l.response(ctx, nil, err)
 				return
 			}
 		}
@@ -69,15 +71,23 @@ func (l *GinEngine) newDefaultHandler(controller any, t reflect.Method, req refl
 			err, ok := respVal[1].Interface().(error)
 			if ok {
 				Logger().Info("handleFunc Call err", zap.Error(err))
-				l.defaultResponse.Response(ctx, nil, err)
+				l.response(ctx, nil, err)
 				return
 			}
 			Logger().Info("handleFunc Call abnormal err", zap.Error(err))
-			l.defaultResponse.Response(ctx, nil, errors.New("response error"))
+			l.response(ctx, nil, errors.New("response error"))
 			return
 		}
 
 		// 返回结果
-		l.defaultResponse.Response(ctx, respVal[0].Interface(), nil)
+		l.response(ctx, respVal[0].Interface(), nil)
 	}
 }
+
+// response 通过defaultResponse输出回调方法的结果, graphql字段调用时同时记录结果作为字段的值
+func (l *GinEngine) response(ctx *gin.Context, resp any, err error) {
+	if call, ok := ctx.Request.Context().Value(controllerCallContextKey{}).(*controllerCall); ok {
+		call.done, call.resp, call.err = true, resp, err
+	}
+	l.defaultResponse.Response(ctx, resp, err)
+}
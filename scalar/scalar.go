@@ -0,0 +1,20 @@
+// Package scalar 内嵌Scalar API Reference官方发布的standalone脚本, 通过go generate下载到dist目录
+package scalar
+
+import (
+	"embed"
+)
+
+//go:generate curl -fsSL -o dist/standalone.js https://cdn.jsdelivr.net/npm/@scalar/api-reference@1.24.0/dist/browser/standalone.js
+
+const (
+	// Version 内嵌的@scalar/api-reference版本
+	Version = "1.24.0"
+	// Bundle 官方脚本在dist目录中的文件名
+	Bundle = "standalone.js"
+)
+
+// Dist Scalar页面静态资源, 包含index.html模板和官方脚本Bundle
+//
+//go:embed dist/*
+var Dist embed.FS
@@ -2,18 +2,17 @@ package ginplus
 
 import (
 	"bytes"
-	"embed"
 	"fmt"
 	"io/fs"
 	"strings"
 )
 
-// String reads the .graphql schema files from the embed.FS, concatenating the
-// files together into one string.
+// String reads the .graphql schema files from the fs.FS (for example embed.FS or
+// os.DirFS), concatenating the files together into one string.
 //
 // If this method complains about not finding functions AssetNames() or MustAsset(),
 // run `go generate` against this package to generate the functions.
-func String(content embed.FS) (string, error) {
+func String(content fs.FS) (string, error) {
 	var buf bytes.Buffer
 
 	fn := func(path string, d fs.DirEntry, err error) error {
@@ -26,7 +25,7 @@ func String(content embed.FS) (string, error) {
 			return nil
 		}
 
-		b, err := content.ReadFile(path)
+		b, err := fs.ReadFile(content, path)
 		if err != nil {
 			return fmt.Errorf("reading file %q: %w", path, err)
 		}
@@ -0,0 +1,180 @@
+package ginplus
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/aide-cloud/gin-plus/swagger"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSwaggerUIPath   = "/swagger-ui"
+	defaultSwaggerSpecPath = "/openapi/doc/swagger"
+)
+
+type (
+	// SwaggerUI swagger ui配置, 路径会加上WithBasePath设置的前缀
+	SwaggerUI struct {
+		// Path 页面路径, 默认为/swagger-ui
+		Path string
+		// SpecPath openapi文档路径, 默认为/openapi/doc/swagger
+		SpecPath string
+		// DisableDeepLinking 关闭深度链接, 默认开启
+		DisableDeepLinking bool
+		// PersistAuthorization 刷新页面后保留授权信息
+		PersistAuthorization bool
+		// DefaultModelsExpandDepth models的默认展开深度, 为空时使用swagger ui的默认值, -1为隐藏models
+		DefaultModelsExpandDepth *int
+		// OAuth2 OAuth2客户端配置, 为空时不初始化
+		OAuth2 *SwaggerOAuth2
+		// Options 其他swagger ui配置项, 会覆盖上面的配置
+		Options map[string]any
+	}
+
+	// SwaggerOAuth2 swagger ui的OAuth2客户端配置, 对应ui.initOAuth
+	SwaggerOAuth2 struct {
+		ClientID     string   `json:"clientId,omitempty"`
+		ClientSecret string   `json:"clientSecret,omitempty"`
+		Realm        string   `json:"realm,omitempty"`
+		AppName      string   `json:"appName,omitempty"`
+		Scopes       []string `json:"scopes,omitempty"`
+		// UsePKCE 授权码模式使用PKCE
+		UsePKCE bool `json:"usePkceWithAuthorizationCodeGrant,omitempty"`
+		// AdditionalQueryStringParams 授权请求附加的查询参数
+		AdditionalQueryStringParams map[string]string `json:"additionalQueryStringParams,omitempty"`
+	}
+)
+
+// swaggerInitializerTemplate 替换swagger/dist中的swagger-initializer.js, 两个参数分别为ui配置和OAuth2配置
+const swaggerInitializerTemplate = `window.onload = function() {
+  const config = %s;
+  config.oauth2RedirectUrl = window.location.origin + config.oauth2RedirectUrl;
+  window.ui = SwaggerUIBundle(Object.assign({
+    dom_id: '#swagger-ui',
+    presets: [
+      SwaggerUIBundle.presets.apis,
+      SwaggerUIStandalonePreset
+    ],
+    plugins: [
+      SwaggerUIBundle.plugins.DownloadUrl
+    ],
+    layout: "StandaloneLayout"
+  }, config));
+  const oauth = %s;
+  if (oauth) {
+    window.ui.initOAuth(oauth);
+  }
+};
+`
+
+// initializer 生成swagger-initializer.js, path和specPath为加上前缀后的路径
+func (s *SwaggerUI) initializer(uiPath, specPath string) ([]byte, error) {
+	config := map[string]any{
+		"url":                  specPath,
+		"deepLinking":          !s.DisableDeepLinking,
+		"persistAuthorization": s.PersistAuthorization,
+		"oauth2RedirectUrl":    path.Join(uiPath, "oauth2-redirect.html"),
+	}
+	if s.DefaultModelsExpandDepth != nil {
+		config["defaultModelsExpandDepth"] = *s.DefaultModelsExpandDepth
+	}
+	for k, v := range s.Options {
+		config[k] = v
+	}
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	oauthJSON, err := json.Marshal(s.OAuth2)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf(swaggerInitializerTemplate, configJSON, oauthJSON)), nil
+}
+
+// prefixPath 为路径加上WithBasePath设置的前缀
+func (l *GinEngine) prefixPath(p string) string {
+	return path.Join("/", l.basePath, p)
+}
+
+// serveOpenApiYaml 返回生成的openapi文档
+func (l *GinEngine) serveOpenApiYaml(ctx *gin.Context) {
+	file, err := os.ReadFile(l.openApiYaml())
+	if err != nil {
+		Logger().Sugar().Errorf("[GIN-PLUS] [ERROR] read openapi doc: %v", err)
+		ctx.String(http.StatusInternalServerError, "openapi doc not found")
+		return
+	}
+	ctx.Data(http.StatusOK, "text/yaml; charset=utf-8", file)
+}
+
+func registerSwaggerUI(instance *GinEngine, config *SwaggerUI) {
+	if !instance.genApiEnable {
+		return
+	}
+	if config == nil {
+		config = &SwaggerUI{}
+	}
+	if config.Path == "" {
+		config.Path = defaultSwaggerUIPath
+	}
+	if config.SpecPath == "" {
+		config.SpecPath = defaultSwaggerSpecPath
+	}
+	uiPath, specPath := instance.prefixPath(config.Path), instance.prefixPath(config.SpecPath)
+	initializer, err := config.initializer(uiPath, specPath)
+	if err != nil {
+		Logger().Sugar().Errorf("[GIN-PLUS] [ERROR] Register swagger ui: %v", err)
+		instance.registerErrs = append(instance.registerErrs, err)
+		return
+	}
+
+	instance.genOpenApiYaml()
+	r := instance.Admin()
+	r.GET(specPath, instance.serveOpenApiYaml)
+	mountDocsPage(r, uiPath, swaggerUIHandler(uiPath, initializer))
+}
+
+// swaggerUIHandler 返回挂载在uiPath下的swagger ui静态资源, swagger-initializer.js使用生成的内容
+func swaggerUIHandler(uiPath string, initializer []byte) http.Handler {
+	dist, _ := fs.Sub(swagger.Dist, "dist")
+	fileServer := http.StripPrefix(uiPath, http.FileServer(http.FS(dist)))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.TrimPrefix(r.URL.Path, uiPath) == "/swagger-initializer.js" {
+			w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+			_, _ = w.Write(initializer)
+			return
+		}
+		fileServer.ServeHTTP(w, r)
+	})
+}
+
+// mountDocsPage 把文档页面挂载到p/下, 页面使用相对路径引用静态资源, 不带/的请求重定向
+func mountDocsPage(r gin.IRoutes, p string, handler http.Handler) {
+	r.GET(p, func(ctx *gin.Context) {
+		ctx.Redirect(http.StatusMovedPermanently, p+"/")
+	})
+	r.GET(path.Join(p, "*filepath"), gin.WrapH(handler))
+}
+
+// RegisterSwaggerUI 注册swagger ui, 关闭文档生成时不注册
+func (l *GinEngine) RegisterSwaggerUI(config ...*SwaggerUI) *GinEngine {
+	if len(config) > 0 {
+		l.swaggerUI = config[0]
+	}
+	registerSwaggerUI(l, l.swaggerUI)
+	return l
+}
+
+// WithSwaggerUI 自定义swagger ui
+func WithSwaggerUI(config *SwaggerUI) OptionFun {
+	return func(g *GinEngine) {
+		g.swaggerUI = config
+	}
+}
@@ -0,0 +1,56 @@
+package ginplus
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGinEngine_RegisterSwaggerUI(t *testing.T) {
+	dir := t.TempDir()
+	depth := -1
+	engine := New(gin.New(), WithBasePath("api"), WithOpenApiYaml(dir, "openapi.yaml"), WithControllers(&Api{}))
+	engine.RegisterSwaggerUI(&SwaggerUI{
+		Path:                     "/docs",
+		SpecPath:                 "/openapi.yaml",
+		PersistAuthorization:     true,
+		DefaultModelsExpandDepth: &depth,
+		OAuth2:                   &SwaggerOAuth2{ClientID: "client", Scopes: []string{"read"}, UsePKCE: true},
+	})
+
+	tests := []struct {
+		path string
+		code int
+		want string
+	}{
+		{path: "/api/docs", code: 301},
+		{path: "/api/docs/", code: 200, want: `<div id="swagger-ui"></div>`},
+		{path: "/api/docs/swagger-ui.css", code: 200},
+		{path: "/api/docs/swagger-initializer.js", code: 200, want: `"url":"/api/openapi.yaml"`},
+		{path: "/api/docs/swagger-initializer.js", code: 200, want: `"deepLinking":true,"defaultModelsExpandDepth":-1,"oauth2RedirectUrl":"/api/docs/oauth2-redirect.html","persistAuthorization":true`},
+		{path: "/api/docs/swagger-initializer.js", code: 200, want: `{"clientId":"client","scopes":["read"],"usePkceWithAuthorizationCodeGrant":true}`},
+		{path: "/api/openapi.yaml", code: 200, want: "openapi: 3.1.3"},
+		{path: "/swagger-ui/", code: 404},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != tt.code || !strings.Contains(w.Body.String(), tt.want) {
+			t.Errorf("%s: %d %s", tt.path, w.Code, w.Body.String())
+		}
+	}
+
+	// 文档文件读取失败时返回错误
+	if err := os.Remove(filepath.Join(dir, "openapi.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/api/openapi.yaml", nil))
+	if w.Code != 500 {
+		t.Errorf("missing doc: %d", w.Code)
+	}
+}
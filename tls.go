@@ -0,0 +1,228 @@
+package ginplus
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+const (
+	// defaultCertReloadInterval 证书文件变更的检查间隔
+	defaultCertReloadInterval = 10 * time.Second
+	// clientIdentityKey gin.Context中存储客户端身份的key
+	clientIdentityKey = "client_identity"
+)
+
+type (
+	// TLSConfig TLS配置
+	TLSConfig struct {
+		// CertFile 证书文件, 文件变更后自动重新加载
+		CertFile string
+		// KeyFile 私钥文件
+		KeyFile string
+		// ClientCAFile 客户端CA证书文件, 设置后开启mTLS并要求客户端证书
+		ClientCAFile string
+		// Config 基础tls.Config, 为空时使用默认配置
+		Config *tls.Config
+		// ReloadInterval 证书文件变更的检查间隔, 默认为10s
+		ReloadInterval time.Duration
+	}
+
+	// ClientIdentity mTLS客户端身份
+	ClientIdentity struct {
+		CommonName     string
+		Organization   []string
+		DNSNames       []string
+		EmailAddresses []string
+		URIs           []string
+		SerialNumber   string
+	}
+
+	clientIdentityCtxKey struct{}
+
+	// certReloader 证书热加载, 在握手时按间隔检查文件修改时间
+	certReloader struct {
+		certFile string
+		keyFile  string
+		interval time.Duration
+
+		mtx       sync.RWMutex
+		cert      *tls.Certificate
+		modTime   time.Time
+		checkedAt time.Time
+	}
+)
+
+func newCertReloader(certFile, keyFile string, interval time.Duration) (*certReloader, error) {
+	if interval <= 0 {
+		interval = defaultCertReloadInterval
+	}
+	r := &certReloader{certFile: certFile, keyFile: keyFile, interval: interval}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// reload 重新加载证书
+func (r *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load certificate: %w", err)
+	}
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return err
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.cert = &cert
+	r.modTime = modTime
+	r.checkedAt = time.Now()
+	return nil
+}
+
+func (r *certReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, file := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return latest, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// GetCertificate 用于tls.Config.GetCertificate, 文件变更时重新加载, 加载失败时继续使用旧证书
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mtx.RLock()
+	cert, modTime, checkedAt := r.cert, r.modTime, r.checkedAt
+	r.mtx.RUnlock()
+	if time.Since(checkedAt) < r.interval {
+		return cert, nil
+	}
+
+	latest, err := r.latestModTime()
+	if err == nil && latest.After(modTime) {
+		if err = r.reload(); err == nil {
+			Logger().Sugar().Infof("[GIN-PLUS] [INFO] Certificate %s reloaded", r.certFile)
+			r.mtx.RLock()
+			defer r.mtx.RUnlock()
+			return r.cert, nil
+		}
+	}
+	if err != nil {
+		Logger().Sugar().Errorf("[GIN-PLUS] [ERROR] Reload certificate: %v", err)
+	}
+	r.mtx.Lock()
+	r.checkedAt = time.Now()
+	r.mtx.Unlock()
+	return cert, nil
+}
+
+// build 生成tls.Config
+func (c *TLSConfig) build() (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if c.Config != nil {
+		cfg = c.Config.Clone()
+	}
+	if c.CertFile != "" || c.KeyFile != "" {
+		reloader, err := newCertReloader(c.CertFile, c.KeyFile, c.ReloadInterval)
+		if err != nil {
+			return nil, err
+		}
+		cfg.GetCertificate = reloader.GetCertificate
+	}
+	if c.ClientCAFile != "" {
+		pem, err := os.ReadFile(c.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read client ca: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no valid certificate found in client ca file")
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	// 通过ALPN支持HTTP/2
+	if len(cfg.NextProtos) == 0 {
+		cfg.NextProtos = []string{"h2", "http/1.1"}
+	}
+	if len(cfg.Certificates) == 0 && cfg.GetCertificate == nil && cfg.GetConfigForClient == nil {
+		return nil, errors.New("tls: no certificate configured")
+	}
+	return cfg, nil
+}
+
+// clientIdentity 把已验证的客户端证书信息写入上下文
+func clientIdentity() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.TLS == nil || len(c.Request.TLS.VerifiedChains) == 0 {
+			c.Next()
+			return
+		}
+		cert := c.Request.TLS.VerifiedChains[0][0]
+		identity := &ClientIdentity{
+			CommonName:     cert.Subject.CommonName,
+			Organization:   cert.Subject.Organization,
+			DNSNames:       cert.DNSNames,
+			EmailAddresses: cert.EmailAddresses,
+			SerialNumber:   cert.SerialNumber.String(),
+		}
+		for _, uri := range cert.URIs {
+			identity.URIs = append(identity.URIs, uri.String())
+		}
+		c.Set(clientIdentityKey, identity)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), clientIdentityCtxKey{}, identity))
+		c.Next()
+	}
+}
+
+// ClientIdentityFromContext 获取mTLS客户端身份, 支持*gin.Context
+func ClientIdentityFromContext(ctx context.Context) (*ClientIdentity, bool) {
+	if c, ok := ctx.(*gin.Context); ok {
+		if identity, exists := c.Get(clientIdentityKey); exists {
+			id, ok := identity.(*ClientIdentity)
+			return id, ok
+		}
+		if c.Request == nil {
+			return nil, false
+		}
+		ctx = c.Request.Context()
+	}
+	identity, ok := ctx.Value(clientIdentityCtxKey{}).(*ClientIdentity)
+	return identity, ok
+}
+
+// h2cHandler 支持明文HTTP/2
+func h2cHandler(handler http.Handler) http.Handler {
+	return h2c.NewHandler(handler, &http2.Server{})
+}
+
+// WithTLS 启用TLS, 证书文件变更后自动重新加载
+func WithTLS(config TLSConfig) OptionFun {
+	return func(g *GinEngine) {
+		g.tlsConfig = &config
+	}
+}
+
+// WithH2C 启用明文HTTP/2(h2c)
+func WithH2C() OptionFun {
+	return func(g *GinEngine) {
+		g.h2c = true
+	}
+}
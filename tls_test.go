@@ -0,0 +1,172 @@
+package ginplus
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testCert 生成由parent签发的证书, parent为空时生成自签名CA
+func testCert(t *testing.T, serial int64, cn string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, []byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn, Organization: []string{"gin-plus"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{cn},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		tpl.IsCA = true
+		tpl.BasicConstraintsValid = true
+		parent, parentKey = tpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+func TestTLSConfig_Build(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	ca, caKey, caPEM, _ := testCert(t, 1, "ca", nil, nil)
+	_, _, serverPEM, serverKeyPEM := testCert(t, 2, "server", ca, caKey)
+	_, _, clientPEM, clientKeyPEM := testCert(t, 3, "client", ca, caKey)
+	certFile, keyFile := write("server.pem", serverPEM), write("server.key", serverKeyPEM)
+
+	config := TLSConfig{
+		CertFile:       certFile,
+		KeyFile:        keyFile,
+		ClientCAFile:   write("ca.pem", caPEM),
+		ReloadInterval: time.Nanosecond,
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	instance := New(gin.New(), WithListeners(ln), WithTLS(config))
+	instance.GET("/whoami", func(c *gin.Context) {
+		identity, ok := ClientIdentityFromContext(c)
+		if !ok {
+			c.Status(http.StatusUnauthorized)
+			return
+		}
+		c.String(http.StatusOK, identity.CommonName)
+	})
+	if err := instance.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer instance.Stop()
+
+	clientCert, err := tls.X509KeyPair(clientPEM, clientKeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	get := func() (*http.Response, error) {
+		client := &http.Client{Transport: &http.Transport{
+			ForceAttemptHTTP2: true,
+			TLSClientConfig:   &tls.Config{RootCAs: pool, Certificates: []tls.Certificate{clientCert}},
+		}}
+		return client.Get("https://" + ln.Addr().String() + "/whoami")
+	}
+
+	resp, err := get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := make([]byte, 16)
+	n, _ := resp.Body.Read(body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body[:n]) != "client" || resp.ProtoMajor != 2 {
+		t.Errorf("want client identity over HTTP/2, got %d %q %s", resp.StatusCode, body[:n], resp.Proto)
+	}
+	if serial := resp.TLS.PeerCertificates[0].SerialNumber.Int64(); serial != 2 {
+		t.Errorf("want serial 2, got %d", serial)
+	}
+
+	// 更新证书文件后自动重新加载
+	_, _, newPEM, newKeyPEM := testCert(t, 4, "server", ca, caKey)
+	write("server.pem", newPEM)
+	write("server.key", newKeyPEM)
+	future := time.Now().Add(time.Minute)
+	for _, file := range []string{certFile, keyFile} {
+		if err := os.Chtimes(file, future, future); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resp, err = get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if serial := resp.TLS.PeerCertificates[0].SerialNumber.Int64(); serial != 4 {
+		t.Errorf("want reloaded certificate serial 4, got %d", serial)
+	}
+
+	if _, err := (&TLSConfig{}).build(); err == nil {
+		t.Error("want error without certificate")
+	}
+}
+
+func TestGinEngine_StartH2COnce(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	instance := New(gin.New(), WithAddr(ln.Addr().String()), WithH2C(), WithShutdownTimeout(time.Second))
+	// 监听失败后重试启动
+	if err := instance.Start(); err == nil {
+		t.Fatal("want listen error when the address is in use")
+	}
+	handler := instance.server.Handler
+	ln.Close()
+	if err := instance.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer instance.Stop()
+	// 再次启动时不重复包装h2c handler
+	if instance.server.Handler != handler {
+		t.Error("h2c handler wrapped again on restart")
+	}
+}
@@ -1,28 +1,80 @@
 package ginplus
 
 import (
-	"time"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/propagators/b3"
+	jaegerprop "go.opentelemetry.io/contrib/propagators/jaeger"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/exporters/jaeger"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	tracesdk "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
 	oteltrace "go.opentelemetry.io/otel/trace"
-	"gorm.io/gorm"
 )
 
-// 告诉编译器这个结构体实现了gorm.Plugin接口
-var _ gorm.Plugin = (*OpentracingPlugin)(nil)
-
 type tracingConfig struct {
 	// URL 上报地址
 	URL      string
 	KeyValue func(c *gin.Context) []attribute.KeyValue
+	// Provider 自定义TracerProvider, 设置后忽略exporter相关配置
+	Provider oteltrace.TracerProvider
+	// Exporter 上报方式, 默认为jaeger
+	Exporter TracingExporter
+	// Insecure OTLP上报是否不使用TLS
+	Insecure bool
+	// Headers OTLP上报时携带的请求头
+	Headers map[string]string
+	// Sampler 采样器, 默认为ParentBased(AlwaysSample)
+	Sampler tracesdk.Sampler
+	// Attributes 额外的resource属性
+	Attributes []attribute.KeyValue
+	// DisableGlobal 不把TracerProvider和传播方式设置为otel全局配置
+	DisableGlobal bool
+	// Propagator 上下文传播方式, 默认为W3C TraceContext + Baggage
+	Propagator propagation.TextMapPropagator
+	// InjectResponse 是否把链路上下文写入响应头
+	InjectResponse bool
 }
 
+// TracingExporter 链路追踪上报方式
+type TracingExporter string
+
+const (
+	// TracingExporterJaeger jaeger collector, URL形如http://localhost:14268/api/traces
+	TracingExporterJaeger TracingExporter = "jaeger"
+	// TracingExporterOTLPHTTP OTLP/HTTP, URL形如http://localhost:4318/v1/traces
+	TracingExporterOTLPHTTP TracingExporter = "otlphttp"
+	// TracingExporterOTLPGRPC OTLP/gRPC, URL形如localhost:4317
+	TracingExporterOTLPGRPC TracingExporter = "otlpgrpc"
+	// TracingExporterStdout 输出到标准输出, 用于本地调试
+	TracingExporterStdout TracingExporter = "stdout"
+)
+
+// TracingPropagator 链路上下文传播格式
+type TracingPropagator string
+
+const (
+	// TracingPropagatorW3C W3C traceparent/tracestate
+	TracingPropagatorW3C TracingPropagator = "tracecontext"
+	// TracingPropagatorBaggage W3C baggage
+	TracingPropagatorBaggage TracingPropagator = "baggage"
+	// TracingPropagatorB3 B3 single header和multi header
+	TracingPropagatorB3 TracingPropagator = "b3"
+	// TracingPropagatorJaeger uber-trace-id
+	TracingPropagatorJaeger TracingPropagator = "jaeger"
+)
+
 type TracingOption func(*tracingConfig)
 
 // WithTracingURL 设置上报地址
@@ -39,139 +91,264 @@ func WithTracingKeyValueFunc(keyValue func(c *gin.Context) []attribute.KeyValue)
 	}
 }
 
-func defaultKeyValueFunc(c *gin.Context) []attribute.KeyValue {
-	return []attribute.KeyValue{
-		attribute.String("http.method", c.Request.Method),
-		attribute.String("http.host", c.Request.Host),
-		attribute.String("http.user_agent", c.Request.UserAgent()),
-		attribute.String("http.client_ip", c.ClientIP()),
+// WithTracerProvider 使用自定义的TracerProvider, 此时不会创建exporter
+func WithTracerProvider(tp oteltrace.TracerProvider) TracingOption {
+	return func(c *tracingConfig) {
+		c.Provider = tp
 	}
 }
 
-func tracerProvider(url, serviceName, environment, id string) *tracesdk.TracerProvider {
-	// Create the Jaeger exporter
-	exp, err := jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(url)))
-	if err != nil {
-		panic(err)
+// WithTracingExporter 设置上报方式
+func WithTracingExporter(exporter TracingExporter) TracingOption {
+	return func(c *tracingConfig) {
+		c.Exporter = exporter
 	}
-	tp := tracesdk.NewTracerProvider(
-		// Always be sure to batch in production.
-		tracesdk.WithBatcher(exp),
-		// Record information about this application in a Resource.
-		tracesdk.WithResource(resource.NewWithAttributes(
-			semconv.SchemaURL,
-			semconv.ServiceNameKey.String(serviceName),
-			attribute.String("environment", environment),
-			attribute.String("ID", id),
-		)),
-	)
-	otel.SetTracerProvider(tp)
-	return tp
 }
 
-const gormSpanKey = "__gorm_span"
-const gormTime = "__gorm_time"
+// WithTracingInsecure OTLP上报不使用TLS
+func WithTracingInsecure() TracingOption {
+	return func(c *tracingConfig) {
+		c.Insecure = true
+	}
+}
 
-func before(db *gorm.DB) {
-	// 先从父级spans生成子span ---> 这里命名为gorm，但实际上可以自定义
-	// 自己喜欢的operationName
-	ctx, span := otel.Tracer("gorm").Start(db.Statement.Context, "gorm")
-	// 利用db实例去传递span
-	db.InstanceSet(gormSpanKey, span)
-	db.InstanceSet(gormTime, time.Now())
-	// 设置context
-	db.WithContext(ctx)
+// WithTracingHeaders 设置OTLP上报时携带的请求头
+func WithTracingHeaders(headers map[string]string) TracingOption {
+	return func(c *tracingConfig) {
+		c.Headers = headers
+	}
 }
 
-func after(db *gorm.DB) {
-	// 从GORM的DB实例中取出span
-	_span, isExist := db.InstanceGet(gormSpanKey)
-	if !isExist {
-		// 不存在就直接抛弃掉
-		return
+// WithTracingSampler 设置采样器
+func WithTracingSampler(sampler tracesdk.Sampler) TracingOption {
+	return func(c *tracingConfig) {
+		c.Sampler = sampler
 	}
+}
 
-	// 断言进行类型转换
-	span, ok := _span.(oteltrace.Span)
-	if !ok {
-		return
+// WithTracingSampleRatio 设置采样率, 使用ParentBased(TraceIDRatioBased(ratio))
+func WithTracingSampleRatio(ratio float64) TracingOption {
+	return func(c *tracingConfig) {
+		c.Sampler = tracesdk.ParentBased(tracesdk.TraceIDRatioBased(ratio))
 	}
-	defer span.End()
+}
 
-	// Error
-	if db.Error != nil {
-		span.SetAttributes(attribute.String("error", db.Error.Error()))
+// WithTracingAttributes 设置额外的resource属性
+func WithTracingAttributes(attrs ...attribute.KeyValue) TracingOption {
+	return func(c *tracingConfig) {
+		c.Attributes = append(c.Attributes, attrs...)
 	}
-	// sql --> 写法来源GORM V2的日志
-	span.SetAttributes(attribute.String("sql", db.Dialector.Explain(db.Statement.SQL.String(), db.Statement.Vars...)))
-	// rows
-	span.SetAttributes(attribute.Int64("rows", db.RowsAffected))
-	// elapsed
-	_time, isExist := db.InstanceGet(gormTime)
-	if !isExist {
-		return
+}
+
+// WithTracingGlobal 把TracerProvider和传播方式设置为otel全局配置
+//
+// Deprecated: 默认即设置为全局配置, 使用WithTracingDisableGlobal关闭
+func WithTracingGlobal() TracingOption {
+	return func(c *tracingConfig) {
+		c.DisableGlobal = false
 	}
-	startTime, ok := _time.(time.Time)
-	if ok {
-		span.SetAttributes(attribute.String("elapsed", time.Since(startTime).String()))
+}
+
+// WithTracingDisableGlobal 不把TracerProvider和传播方式设置为otel全局配置, 多个GinEngine使用不同的TracerProvider时使用
+func WithTracingDisableGlobal() TracingOption {
+	return func(c *tracingConfig) {
+		c.DisableGlobal = true
 	}
 }
 
-const (
-	callBackBeforeName = "opentracing:before"
-	callBackAfterName  = "opentracing:after"
-)
+// WithTracingPropagators 设置上下文传播格式, 多个格式会组合使用
+func WithTracingPropagators(propagators ...TracingPropagator) TracingOption {
+	return func(c *tracingConfig) {
+		c.Propagator = NewPropagator(propagators...)
+	}
+}
 
-type OpentracingPlugin struct{}
+// WithTracingTextMapPropagator 设置自定义的上下文传播方式
+func WithTracingTextMapPropagator(propagator propagation.TextMapPropagator) TracingOption {
+	return func(c *tracingConfig) {
+		c.Propagator = propagator
+	}
+}
 
-// NewOpentracingPlugin 创建一个opentracing插件
-func NewOpentracingPlugin() *OpentracingPlugin {
-	return &OpentracingPlugin{}
+// WithTracingInjectResponse 把链路上下文写入响应头
+func WithTracingInjectResponse() TracingOption {
+	return func(c *tracingConfig) {
+		c.InjectResponse = true
+	}
 }
 
-func (op *OpentracingPlugin) Name() string {
-	return "opentracingPlugin"
+// NewPropagator 根据传播格式组合生成propagation.TextMapPropagator, 为空时使用W3C TraceContext + Baggage
+func NewPropagator(propagators ...TracingPropagator) propagation.TextMapPropagator {
+	if len(propagators) == 0 {
+		propagators = []TracingPropagator{TracingPropagatorW3C, TracingPropagatorBaggage}
+	}
+	list := make([]propagation.TextMapPropagator, 0, len(propagators))
+	for _, p := range propagators {
+		switch p {
+		case TracingPropagatorW3C:
+			list = append(list, propagation.TraceContext{})
+		case TracingPropagatorBaggage:
+			list = append(list, propagation.Baggage{})
+		case TracingPropagatorB3:
+			list = append(list, b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader|b3.B3SingleHeader)))
+		case TracingPropagatorJaeger:
+			list = append(list, jaegerprop.Jaeger{})
+		}
+	}
+	return propagation.NewCompositeTextMapPropagator(list...)
 }
 
-func (op *OpentracingPlugin) Initialize(db *gorm.DB) (err error) {
-	// 开始前 - 并不是都用相同的方法，可以自己自定义
-	if err = db.Callback().Create().Before("gorm:before_create").Register(callBackBeforeName, before); err != nil {
-		return err
+// TracingTransport 为出站请求创建client span并注入链路上下文的http.RoundTripper, base为空时使用http.DefaultTransport
+//
+// 需要在Tracing之后调用, 以使用相同的TracerProvider和传播格式
+func (l *Middleware) TracingTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	propagator := l.propagator
+	if propagator == nil {
+		propagator = NewPropagator()
+	}
+	return &tracingTransport{base: base, provider: l.tracing, propagator: propagator}
+}
+
+type tracingTransport struct {
+	base       http.RoundTripper
+	provider   oteltrace.TracerProvider
+	propagator propagation.TextMapPropagator
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	ctx, span := spanProvider(ctx, t.provider).Tracer("IMiddleware.TracingTransport").Start(ctx, "HTTP "+req.Method,
+		oteltrace.WithSpanKind(oteltrace.SpanKindClient),
+		oteltrace.WithAttributes(
+			semconv.HTTPMethod(req.Method),
+			attribute.String("http.url", req.URL.Redacted()),
+			semconv.NetPeerName(req.URL.Hostname()),
+		),
+	)
+	defer span.End()
+
+	// RoundTripper不应修改原始请求
+	req = req.Clone(ctx)
+	t.propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return resp, err
+	}
+	span.SetAttributes(semconv.HTTPStatusCode(resp.StatusCode))
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
 	}
-	if err = db.Callback().Query().Before("gorm:query").Register(callBackBeforeName, before); err != nil {
-		return err
+	return resp, nil
+}
+
+// httpServerAttributes 生成服务端span的http属性
+func httpServerAttributes(serverName, route string, req *http.Request) []attribute.KeyValue {
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
 	}
-	if err = db.Callback().Delete().Before("gorm:before_delete").Register(callBackBeforeName, before); err != nil {
-		return err
+	attrs := []attribute.KeyValue{
+		semconv.HTTPMethod(req.Method),
+		semconv.HTTPScheme(scheme),
+		semconv.HTTPTarget(req.URL.RequestURI()),
+		semconv.NetProtocolVersion(fmt.Sprintf("%d.%d", req.ProtoMajor, req.ProtoMinor)),
 	}
-	if err = db.Callback().Update().Before("gorm:setup_reflect_value").Register(callBackBeforeName, before); err != nil {
-		return err
+	if serverName != "" {
+		attrs = append(attrs, semconv.NetHostName(serverName))
 	}
-	if err = db.Callback().Row().Before("gorm:row").Register(callBackBeforeName, before); err != nil {
-		return err
+	if route != "" {
+		attrs = append(attrs, semconv.HTTPRoute(route))
 	}
-	if err = db.Callback().Raw().Before("gorm:raw").Register(callBackBeforeName, before); err != nil {
-		return err
+	if ua := req.UserAgent(); ua != "" {
+		attrs = append(attrs, semconv.UserAgentOriginal(ua))
 	}
+	return attrs
+}
 
-	// 结束后 - 并不是都用相同的方法，可以自己自定义
-	if err = db.Callback().Create().After("gorm:after_create").Register(callBackAfterName, after); err != nil {
-		return err
+func defaultKeyValueFunc(c *gin.Context) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("http.method", c.Request.Method),
+		attribute.String("http.host", c.Request.Host),
+		attribute.String("http.user_agent", c.Request.UserAgent()),
+		attribute.String("http.client_ip", c.ClientIP()),
 	}
-	if err = db.Callback().Query().After("gorm:after_query").Register(callBackAfterName, after); err != nil {
-		return err
+}
+
+// newExporter 根据配置创建exporter
+func newExporter(cfg *tracingConfig) (tracesdk.SpanExporter, error) {
+	switch cfg.Exporter {
+	case TracingExporterOTLPHTTP:
+		opts := []otlptracehttp.Option{otlptracehttp.WithHeaders(cfg.Headers)}
+		if u, err := url.Parse(cfg.URL); err == nil && u.Host != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(u.Host))
+			if u.Path != "" {
+				opts = append(opts, otlptracehttp.WithURLPath(u.Path))
+			}
+			if u.Scheme == "http" {
+				opts = append(opts, otlptracehttp.WithInsecure())
+			}
+		} else if cfg.URL != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.URL))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(context.Background(), opts...)
+	case TracingExporterOTLPGRPC:
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithHeaders(cfg.Headers)}
+		if cfg.URL != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(cfg.URL))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		return otlptracegrpc.New(context.Background(), opts...)
+	case TracingExporterStdout:
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case TracingExporterJaeger, "":
+		return jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(cfg.URL)))
+	default:
+		return nil, fmt.Errorf("unsupported tracing exporter: %s", cfg.Exporter)
 	}
-	if err = db.Callback().Delete().After("gorm:after_delete").Register(callBackAfterName, after); err != nil {
-		return err
+}
+
+func tracerProvider(cfg *tracingConfig, serviceName, environment, id string) (*tracesdk.TracerProvider, error) {
+	exp, err := newExporter(cfg)
+	if err != nil {
+		return nil, err
 	}
-	if err = db.Callback().Update().After("gorm:after_update").Register(callBackAfterName, after); err != nil {
-		return err
+	sampler := cfg.Sampler
+	if sampler == nil {
+		sampler = tracesdk.ParentBased(tracesdk.AlwaysSample())
 	}
-	if err = db.Callback().Row().After("gorm:row").Register(callBackAfterName, after); err != nil {
-		return err
+	attrs := append([]attribute.KeyValue{
+		semconv.ServiceNameKey.String(serviceName),
+		attribute.String("environment", environment),
+		attribute.String("ID", id),
+	}, cfg.Attributes...)
+	tp := tracesdk.NewTracerProvider(
+		// Always be sure to batch in production.
+		tracesdk.WithBatcher(exp),
+		tracesdk.WithSampler(sampler),
+		// Record information about this application in a Resource.
+		tracesdk.WithResource(resource.NewWithAttributes(semconv.SchemaURL, attrs...)),
+	)
+	return tp, nil
+}
+
+// spanProvider 返回创建span使用的TracerProvider: 优先使用配置的provider, 其次使用父span所属的provider,
+// 没有父span时使用otel全局TracerProvider
+func spanProvider(ctx context.Context, provider oteltrace.TracerProvider) oteltrace.TracerProvider {
+	if provider != nil {
+		return provider
 	}
-	if err = db.Callback().Raw().After("gorm:raw").Register(callBackAfterName, after); err != nil {
-		return err
+	if span := oteltrace.SpanFromContext(ctx); span.SpanContext().IsValid() {
+		return span.TracerProvider()
 	}
-	return
+	return otel.GetTracerProvider()
 }
@@ -0,0 +1,325 @@
+package ginplus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// envUpgradeListeners 继承的listener, 格式为"network:addr", 以逗号分隔, 文件描述符从3开始依次对应
+	envUpgradeListeners = "GINPLUS_UPGRADE_LISTENERS"
+	// systemdUpgradeKeyPrefix systemd listener传递给子进程时的key前缀, 格式为"systemd:名称:序号"
+	systemdUpgradeKeyPrefix = "systemd:"
+	// envUpgradeReadyFd 子进程就绪后写入的管道文件描述符
+	envUpgradeReadyFd = "GINPLUS_UPGRADE_READY_FD"
+
+	defaultUpgradeReadyTimeout = time.Minute
+)
+
+type (
+	// Upgrader 零停机重启
+	//
+	// 父进程收到升级信号后启动新的子进程并通过文件描述符传递正在监听的socket,
+	// 子进程所有服务就绪后通知父进程, 父进程再停止服务并排空连接
+	Upgrader struct {
+		// 升级时执行的命令, 默认为当前程序和参数
+		command []string
+		// 等待子进程就绪的超时时间
+		readyTimeout time.Duration
+
+		mtx sync.Mutex
+		// 从父进程继承的listener
+		inherited map[string]net.Listener
+		// 当前使用的listener, 升级时传递给子进程
+		listeners map[string]net.Listener
+		keys      []string
+		// 就绪后通知父进程
+		readyFile *os.File
+		upgrading bool
+	}
+
+	// UpgraderOption Upgrader配置函数
+	UpgraderOption func(*Upgrader)
+
+	filer interface {
+		File() (*os.File, error)
+	}
+)
+
+// NewUpgrader 创建Upgrader, 如果当前进程是升级启动的子进程, 则接管父进程传递的listener
+func NewUpgrader(opts ...UpgraderOption) (*Upgrader, error) {
+	u := &Upgrader{
+		command:      os.Args,
+		readyTimeout: defaultUpgradeReadyTimeout,
+		inherited:    make(map[string]net.Listener),
+		listeners:    make(map[string]net.Listener),
+	}
+	for _, opt := range opts {
+		opt(u)
+	}
+	if err := u.inherit(); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// WithUpgradeCommand 设置升级时执行的命令, 默认为当前程序和参数
+func WithUpgradeCommand(name string, args ...string) UpgraderOption {
+	return func(u *Upgrader) {
+		u.command = append([]string{name}, args...)
+	}
+}
+
+// WithUpgradeReadyTimeout 设置等待子进程就绪的超时时间, 默认为1分钟
+func WithUpgradeReadyTimeout(timeout time.Duration) UpgraderOption {
+	return func(u *Upgrader) {
+		u.readyTimeout = timeout
+	}
+}
+
+// inherit 接管父进程传递的listener和就绪通知管道
+func (u *Upgrader) inherit() error {
+	keys := os.Getenv(envUpgradeListeners)
+	readyFd := os.Getenv(envUpgradeReadyFd)
+	// 避免再次启动的子进程重复使用
+	_ = os.Unsetenv(envUpgradeListeners)
+	_ = os.Unsetenv(envUpgradeReadyFd)
+
+	if keys != "" {
+		for i, key := range strings.Split(keys, ",") {
+			file := os.NewFile(uintptr(listenFdsStart+i), key)
+			ln, err := net.FileListener(file)
+			_ = file.Close()
+			if err != nil {
+				return fmt.Errorf("inherit listener %s: %w", key, err)
+			}
+			u.inherited[key] = ln
+		}
+	}
+	if readyFd != "" {
+		fd, err := strconv.Atoi(readyFd)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", envUpgradeReadyFd, err)
+		}
+		u.readyFile = os.NewFile(uintptr(fd), "ready")
+	}
+	return nil
+}
+
+// IsChild 是否为升级启动的子进程
+func (u *Upgrader) IsChild() bool {
+	return u.readyFile != nil
+}
+
+// Listen 监听地址, 优先使用从父进程继承的listener, 返回的listener会在升级时传递给子进程
+func (u *Upgrader) Listen(network, addr string) (net.Listener, error) {
+	return u.listen(network, addr, func() (net.Listener, error) {
+		return net.Listen(network, addr)
+	})
+}
+
+func (u *Upgrader) listen(network, addr string, listen func() (net.Listener, error)) (net.Listener, error) {
+	key := network + ":" + addr
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+	if ln, ok := u.listeners[key]; ok {
+		return ln, nil
+	}
+	ln, ok := u.inherited[key]
+	if ok {
+		delete(u.inherited, key)
+	} else {
+		var err error
+		if ln, err = listen(); err != nil {
+			return nil, err
+		}
+	}
+	u.listeners[key] = ln
+	u.keys = append(u.keys, key)
+	return ln, nil
+}
+
+// adopt 把外部创建的listener交给Upgrader管理, 升级时以key传递给子进程; 已由Upgrader管理的listener不重复添加
+func (u *Upgrader) adopt(key string, ln net.Listener) {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+	u.adoptLocked(key, ln)
+}
+
+func (u *Upgrader) adoptLocked(key string, ln net.Listener) {
+	for _, managed := range u.listeners {
+		if managed == ln {
+			return
+		}
+	}
+	if _, ok := u.listeners[key]; ok {
+		return
+	}
+	u.listeners[key] = ln
+	u.keys = append(u.keys, key)
+}
+
+// systemd 管理systemd socket activation传入的listener, 升级时传递给子进程;
+// 子进程中没有LISTEN_FDS时使用父进程传递的systemd listener
+func (u *Upgrader) systemd(listeners map[string][]net.Listener) map[string][]net.Listener {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+	if len(listeners) == 0 {
+		keys := make([]string, 0, len(u.inherited))
+		for key := range u.inherited {
+			if strings.HasPrefix(key, systemdUpgradeKeyPrefix) {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			name := strings.TrimPrefix(key, systemdUpgradeKeyPrefix)
+			name = name[:strings.LastIndex(name, ":")]
+			if listeners == nil {
+				listeners = make(map[string][]net.Listener)
+			}
+			listeners[name] = append(listeners[name], u.inherited[key])
+			delete(u.inherited, key)
+		}
+	}
+	for name, lns := range listeners {
+		for i, ln := range lns {
+			u.adoptLocked(systemdUpgradeKeyPrefix+name+":"+strconv.Itoa(i), ln)
+		}
+	}
+	return listeners
+}
+
+// Ready 通知父进程子进程已就绪, 并关闭未使用的继承listener, 非子进程时不做任何操作
+func (u *Upgrader) Ready() error {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+	for key, ln := range u.inherited {
+		_ = ln.Close()
+		delete(u.inherited, key)
+	}
+	if u.readyFile == nil {
+		return nil
+	}
+	defer func() {
+		_ = u.readyFile.Close()
+		u.readyFile = nil
+	}()
+	_, err := u.readyFile.Write([]byte{1})
+	return err
+}
+
+// Upgrade 启动子进程并传递listener, 子进程就绪后返回nil, 此时调用方应停止服务并退出
+func (u *Upgrader) Upgrade(ctx context.Context) error {
+	u.mtx.Lock()
+	if u.upgrading {
+		u.mtx.Unlock()
+		return errors.New("upgrade in progress")
+	}
+	u.upgrading = true
+	keys := append([]string(nil), u.keys...)
+	listeners := make([]net.Listener, 0, len(keys))
+	for _, key := range keys {
+		listeners = append(listeners, u.listeners[key])
+	}
+	u.mtx.Unlock()
+	defer func() {
+		u.mtx.Lock()
+		u.upgrading = false
+		u.mtx.Unlock()
+	}()
+
+	files := make([]*os.File, 0, len(listeners)+1)
+	defer func() {
+		for _, file := range files {
+			_ = file.Close()
+		}
+	}()
+	for i, ln := range listeners {
+		f, ok := ln.(filer)
+		if !ok {
+			return fmt.Errorf("listener %s does not support file descriptor", keys[i])
+		}
+		file, err := f.File()
+		if err != nil {
+			return fmt.Errorf("listener %s: %w", keys[i], err)
+		}
+		files = append(files, file)
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	files = append(files, w)
+
+	cmd := exec.Command(u.command[0], u.command[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.ExtraFiles = files
+	cmd.Env = append(os.Environ(),
+		envUpgradeListeners+"="+strings.Join(keys, ","),
+		envUpgradeReadyFd+"="+strconv.Itoa(listenFdsStart+len(keys)),
+	)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("start child: %w", err)
+	}
+	// 关闭父进程中的写端, 子进程退出时读端会返回EOF
+	_ = w.Close()
+	files = files[:len(files)-1]
+
+	readyCh := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 1)
+		if _, err := r.Read(buf); err != nil {
+			readyCh <- fmt.Errorf("child exited before ready: %w", err)
+			return
+		}
+		readyCh <- nil
+	}()
+	go func() {
+		_ = cmd.Wait()
+	}()
+
+	timer := time.NewTimer(u.readyTimeout)
+	defer timer.Stop()
+	select {
+	case err = <-readyCh:
+	case <-timer.C:
+		err = fmt.Errorf("child not ready after %s", u.readyTimeout)
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err != nil {
+		_ = cmd.Process.Signal(syscall.SIGTERM)
+		return err
+	}
+
+	// 子进程已接管socket, 父进程关闭listener时不删除unix socket文件
+	for _, ln := range listeners {
+		if unixLn, ok := ln.(*net.UnixListener); ok {
+			unixLn.SetUnlinkOnClose(false)
+		}
+	}
+	Logger().Sugar().Infof("[GIN-PLUS] [INFO] Upgraded to child process %d", cmd.Process.Pid)
+	return nil
+}
+
+// WithUpgrader 使用Upgrader监听启动地址和unix socket, 以便在升级时传递给子进程
+//
+// systemd listener会一并传递, 子进程自动使用; WithListeners和WithAdminListener传入的listener以"network:地址"传递,
+// 子进程需要使用Upgrader.Listen(network, ln.Addr().String())取得同一个listener后再传入
+func WithUpgrader(upgrader *Upgrader) OptionFun {
+	return func(g *GinEngine) {
+		g.upgrader = upgrader
+	}
+}
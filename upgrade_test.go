@@ -0,0 +1,200 @@
+package ginplus
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const envUpgradeTestChild = "GINPLUS_TEST_UPGRADE_CHILD"
+
+// upgradeTestServer 在继承或新建的listener上返回name
+func upgradeTestServer(t *testing.T, u *Upgrader, name string) (*http.Server, string) {
+	ln, err := u.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, name)
+	})}
+	go func() { _ = server.Serve(ln) }()
+	return server, ln.Addr().String()
+}
+
+func TestUpgrader_Upgrade(t *testing.T) {
+	if os.Getenv(envUpgradeTestChild) != "" {
+		// 子进程: 接管父进程的listener, 就绪后继续服务一段时间
+		u, err := NewUpgrader()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !u.IsChild() {
+			t.Fatal("want child process")
+		}
+		server, _ := upgradeTestServer(t, u, "child")
+		if err := u.Ready(); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Second)
+		_ = server.Close()
+		return
+	}
+
+	t.Setenv(envUpgradeTestChild, "1")
+	u, err := NewUpgrader(WithUpgradeCommand(os.Args[0], "-test.run=^TestUpgrader_Upgrade$"), WithUpgradeReadyTimeout(10*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, addr := upgradeTestServer(t, u, "parent")
+	get := func() string {
+		resp, err := http.Get("http://" + addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+	if got := get(); got != "parent" {
+		t.Fatalf("want parent, got %s", got)
+	}
+
+	if err := u.Upgrade(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// 父进程停止服务后, 同一地址由子进程继续提供服务
+	_ = server.Close()
+	http.DefaultClient.CloseIdleConnections()
+	if got := get(); got != "child" {
+		t.Errorf("want child after upgrade, got %s", got)
+	}
+
+	failed, err := NewUpgrader(WithUpgradeCommand(os.Args[0], "-test.list=^$"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := failed.Upgrade(context.Background()); err == nil {
+		t.Error("want error when child exits before ready")
+	}
+}
+
+func TestUpgrader_AdoptListeners(t *testing.T) {
+	u, err := NewUpgrader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	// WithListeners传入的listener由Upgrader管理, 升级时传递给子进程
+	if _, err := New(gin.New(), WithListeners(ln), WithUpgrader(u)).listen(); err != nil {
+		t.Fatal(err)
+	}
+	if key := "tcp:" + ln.Addr().String(); u.listeners[key] != ln {
+		t.Errorf("want injected listener adopted as %s, got %v", key, u.keys)
+	}
+	// 已由Upgrader管理的listener不重复添加
+	u.adopt("tcp:other", ln)
+	if len(u.keys) != 1 {
+		t.Errorf("want listener adopted once, got %v", u.keys)
+	}
+
+	admin, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+	u.systemd(map[string][]net.Listener{"": {ln}, SystemdAdminListener: {admin}})
+	if u.listeners["systemd:admin:0"] != admin {
+		t.Errorf("want systemd listener adopted, got %v", u.keys)
+	}
+
+	// 子进程没有LISTEN_FDS时使用父进程传递的systemd listener
+	child := &Upgrader{
+		inherited: map[string]net.Listener{"systemd::0": ln, "systemd:admin:0": admin, "tcp:127.0.0.1:1": ln},
+		listeners: make(map[string]net.Listener),
+	}
+	systemd := child.systemd(nil)
+	if len(systemd) != 2 || systemd[""][0] != ln || systemd[SystemdAdminListener][0] != admin {
+		t.Errorf("want inherited systemd listeners, got %v", systemd)
+	}
+	if len(child.inherited) != 1 || len(child.keys) != 2 {
+		t.Errorf("want systemd listeners moved to managed listeners, inherited %v, keys %v", child.inherited, child.keys)
+	}
+}
+
+func TestUpgrader_UpgradeInjectedListener(t *testing.T) {
+	engine := func(u *Upgrader, ln net.Listener, name string) *GinEngine {
+		r := gin.New()
+		r.GET("/", func(c *gin.Context) {
+			c.String(http.StatusOK, name)
+		})
+		return New(r, WithListeners(ln), WithUpgrader(u), WithShutdownTimeout(time.Second))
+	}
+	if addr := os.Getenv(envUpgradeTestChild); addr != "" {
+		// 子进程: 通过Upgrader.Listen取得父进程传入WithListeners的listener
+		u, err := NewUpgrader()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ln, err := u.Listen("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		child := engine(u, ln, "child")
+		if err := child.Start(); err != nil {
+			t.Fatal(err)
+		}
+		if err := u.Ready(); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Second)
+		child.Stop()
+		return
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	t.Setenv(envUpgradeTestChild, addr)
+	u, err := NewUpgrader(WithUpgradeCommand(os.Args[0], "-test.run=^TestUpgrader_UpgradeInjectedListener$"), WithUpgradeReadyTimeout(10*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent := engine(u, ln, "parent")
+	if err := parent.Start(); err != nil {
+		t.Fatal(err)
+	}
+	get := func() string {
+		resp, err := http.Get("http://" + addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+	if got := get(); got != "parent" {
+		t.Fatalf("want parent, got %s", got)
+	}
+
+	if err := u.Upgrade(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// 父进程停止后, 注入的listener由子进程继续提供服务
+	parent.Stop()
+	http.DefaultClient.CloseIdleConnections()
+	if got := get(); got != "child" {
+		t.Errorf("want child after upgrade, got %s", got)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package ginplus
+
+import (
+	"os"
+	"syscall"
+)
+
+// defaultUpgradeSignals 默认的升级信号
+var defaultUpgradeSignals = []os.Signal{syscall.SIGUSR2}
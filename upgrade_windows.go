@@ -0,0 +1,8 @@
+//go:build windows
+
+package ginplus
+
+import "os"
+
+// defaultUpgradeSignals windows不支持传递listener, 默认不监听升级信号
+var defaultUpgradeSignals []os.Signal
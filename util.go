@@ -149,6 +149,9 @@ func (l *GinEngine) genRoute(parentGroup *gin.RouterGroup, controller any, skipA
 				// 注册路由回调函数
 				handleFunc := l.defaultHandler(controller, t.Method(i), req)
 				l.registerCallHandler(route, routeGroup, handleFunc)
+				if l.onCallback != nil {
+					l.onCallback(controller, t.Method(i), req, resp, strings.ToUpper(route.HttpMethod), path.Join(routeGroup.BasePath(), route.Path))
+				}
 				continue
 			}
 		}
@@ -177,12 +180,8 @@ func (l *GinEngine) genOpenAPI(group *gin.RouterGroup, req, resp reflect.Type, r
 	}
 
 	// 处理Uri参数
-	for _, tagInfo := range reqTagInfo {
-		uriKey := tagInfo.Tags.UriKey
-		skip := tagInfo.Tags.Skip
-		if uriKey != "" && uriKey != "-" && skip != "true" {
-			route.Path = path.Join(route.Path, fmt.Sprintf(":%s", uriKey))
-		}
+	for _, uriKey := range uriParams(req) {
+		route.Path = path.Join(route.Path, fmt.Sprintf(":%s", uriKey))
 	}
 
 	apiPath := path.Join(group.BasePath(), route.Path)
@@ -194,6 +193,24 @@ func (l *GinEngine) genOpenAPI(group *gin.RouterGroup, req, resp reflect.Type, r
 	l.apiRoutes[apiPath] = append(l.apiRoutes[apiPath], apiRoute)
 }
 
+// uriParams 请求结构体中uri标签对应的路由参数, 不使用getTag, 同一个请求结构体用于多个方法时也能生成参数
+func uriParams(req reflect.Type) []string {
+	for req.Kind() == reflect.Ptr {
+		req = req.Elem()
+	}
+	if req.Kind() != reflect.Struct {
+		return nil
+	}
+	var params []string
+	for i := 0; i < req.NumField(); i++ {
+		tag := fieldTag(req.Field(i))
+		if tag.UriKey != "" && tag.UriKey != "-" && tag.Skip != "true" {
+			params = append(params, tag.UriKey)
+		}
+	}
+	return params
+}
+
 // registerCallHandler 注册回调函数
 func (l *GinEngine) registerCallHandler(route *Route, routeGroup *gin.RouterGroup, handleFunc gin.HandlerFunc) {
 	// 具体的action